@@ -1,62 +1,202 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/metadata"
 	"github.com/hazadus/go-snatcher/internal/s3"
 	"github.com/hazadus/go-snatcher/internal/uploader"
 )
 
+// Действия при найденном дубликате
+const (
+	duplicateAsk    = "ask"    // Спросить пользователя
+	duplicateSkip   = "skip"   // Не загружать файл
+	duplicateLink   = "link"   // Добавить запись со ссылкой на уже загруженный файл
+	duplicateUpload = "upload" // Загрузить файл еще раз
+)
+
+// addOptions содержит параметры команды add
+type addOptions struct {
+	duplicate string // Действие при найденном дубликате
+	force     bool   // Загрузить файл без проверки на дубликаты
+}
+
 // createAddCommand создает команду add с привязкой к экземпляру приложения
 func (app *Application) createAddCommand(ctx context.Context) *cobra.Command {
-	return &cobra.Command{
+	opts := &addOptions{}
+
+	cmd := &cobra.Command{
 		Use:   "add [file path]",
-		Short: "Upload an mp3 file to S3 storage",
-		Long:  `Upload an mp3 file to S3 storage with progress tracking.`,
-		Args:  cobra.ExactArgs(1),
+		Short: "Upload an audio file to S3 storage",
+		Long: `Upload an audio file (MP3, FLAC, Ogg Vorbis or WAV) to S3 storage with progress tracking.
+Before uploading, the file is checked against the library: if a track with the same content
+(SHA-256) or the same source URL exists, the upload is skipped, linked to the existing file
+or confirmed, depending on --duplicate. Use --force to upload without the check.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
+			switch opts.duplicate {
+			case duplicateAsk, duplicateSkip, duplicateLink:
+			default:
+				return fmt.Errorf("неизвестное действие с дубликатами '%s': используйте ask, skip или link", opts.duplicate)
+			}
 			// Создаем контекст с таймаутом для загрузки (10 минут)
 			uploadCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 			defer cancel()
-			return app.uploadToS3(uploadCtx, args[0])
+			return app.uploadToS3(uploadCtx, args[0], opts)
 		},
 	}
+
+	cmd.Flags().StringVar(&opts.duplicate, "duplicate", duplicateAsk,
+		"what to do if the file is already in the library: ask, skip or link")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "upload the file even if it is already in the library")
+
+	return cmd
 }
 
 // uploadToS3 загружает файл в S3 с отображением прогресса
-func (app *Application) uploadToS3(ctx context.Context, filePath string) error {
+func (app *Application) uploadToS3(ctx context.Context, filePath string, opts *addOptions) error {
 	// Создаем S3 uploader
-	s3Config := &s3.Config{
-		Region:     app.Config.AwsRegion,
-		AccessKey:  app.Config.AwsAccessKey,
-		SecretKey:  app.Config.AwsSecretKey,
-		Endpoint:   app.Config.AwsEndpoint,
-		BucketName: app.Config.AwsBucketName,
+	s3Uploader, err := app.newS3Uploader()
+	if err != nil {
+		return err
 	}
 
-	s3Uploader, err := s3.NewUploader(s3Config)
+	// Создаем сервис загрузки
+	uploadService := uploader.NewService(s3Uploader, metadata.NewExtractor(), app.Library)
+
+	action := duplicateUpload
+	var duplicates []data.TrackMetadata
+	var sum string // SHA-256 файла, посчитанный при проверке на дубликаты
+	if !opts.force {
+		if duplicates, sum, err = app.findDuplicateUploads(filePath); err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			action = resolveDuplicate(opts.duplicate)
+		}
+	}
+
+	var result *uploader.UploadResult
+	switch action {
+	case duplicateSkip:
+		fmt.Println("⏭️  Загрузка пропущена. Используйте --force, чтобы загрузить файл еще раз")
+		return nil
+	case duplicateLink:
+		original := duplicates[0]
+		if original.URL == "" {
+			return fmt.Errorf("у трека с ID %d отсутствует URL", original.ID)
+		}
+		result = uploadService.LinkResult(filePath, original)
+		fmt.Printf("🔗 Новая запись ссылается на файл трека #%d: %s\n", original.ID, original.URL)
+	default:
+		if result, err = app.uploadWithProgress(ctx, uploadService, filePath, sum); err != nil {
+			return err
+		}
+	}
+
+	// Обновляем данные приложения
+	if err := uploadService.UpdateApplicationData(result); err != nil {
+		return fmt.Errorf("ошибка обновления данных приложения: %w", err)
+	}
+
+	// Сохраняем данные
+	if err := app.SaveData(); err != nil {
+		return fmt.Errorf("ошибка сохранения данных: %w", err)
+	}
+
+	fmt.Printf("\n📦 Данные трека добавлены в %s\n", defaultDataFilePath)
+	return nil
+}
+
+// findDuplicateUploads ищет в библиотеке треки с тем же содержимым или тем
+// же источником, что и файл, который собираются загрузить. Возвращает
+// найденные треки и SHA-256 файла, чтобы не считать его еще раз при загрузке.
+func (app *Application) findDuplicateUploads(filePath string) ([]data.TrackMetadata, string, error) {
+	fileInfo, err := metadata.NewExtractor().GetFileInfo(filePath)
 	if err != nil {
-		return fmt.Errorf("ошибка создания S3 uploader: %w", err)
+		return nil, "", fmt.Errorf("ошибка получения информации о файле: %w", err)
 	}
 
-	// Создаем сервис загрузки
-	uploadService := uploader.NewService(s3Uploader, app.Data)
+	sum, err := uploader.HashFile(filePath, func(bytesRead int64) {
+		if fileInfo.Size > 0 {
+			fmt.Printf("\r🔍 Проверяем, нет ли файла в библиотеке: %.1f%%", float64(bytesRead)/float64(fileInfo.Size)*100)
+		}
+	})
+	fmt.Println()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sourceURL := metadata.NewExtractor().ExtractFromFile(filePath).SourceURL
+	duplicates, err := data.FindDuplicatesOf(app.Library, sum, sourceURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(duplicates) == 0 {
+		return nil, sum, nil
+	}
+
+	fmt.Println("⚠️  Этот файл уже есть в библиотеке:")
+	for _, track := range duplicates {
+		reason := "тот же источник"
+		if strings.EqualFold(track.SHA256, sum) {
+			reason = "то же содержимое"
+		}
+		fmt.Printf("   #%d %s - %s (%s)\n", track.ID, track.Artist, track.Title, reason)
+	}
+	return duplicates, sum, nil
+}
+
+// resolveDuplicate возвращает действие с найденным дубликатом. Для ask
+// спрашивает пользователя; если ответа нет (ввод закрыт), файл пропускается.
+func resolveDuplicate(action string) string {
+	if action != duplicateAsk {
+		return action
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("❓ Что сделать? [s] пропустить, [l] добавить ссылку на загруженный файл, [u] загрузить еще раз: ")
+		answer, err := input.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "s":
+			return duplicateSkip
+		case "l":
+			return duplicateLink
+		case "u":
+			return duplicateUpload
+		}
+		if err != nil {
+			fmt.Println()
+			return duplicateSkip
+		}
+	}
+}
 
+// uploadWithProgress загружает файл через сервис загрузки, выводя сведения
+// о файле и прогресс загрузки. sum - уже посчитанный SHA-256 файла или
+// пустая строка.
+func (app *Application) uploadWithProgress(ctx context.Context, uploadService *uploader.Service, filePath, sum string) (*uploader.UploadResult, error) {
 	// Получаем информацию о файле для отображения
 	metadataExtractor := metadata.NewExtractor()
 	fileInfo, err := metadataExtractor.GetFileInfo(filePath)
 	if err != nil {
-		return fmt.Errorf("ошибка получения информации о файле: %w", err)
+		return nil, fmt.Errorf("ошибка получения информации о файле: %w", err)
 	}
 
 	// Отображаем информацию о загрузке
 	fmt.Printf("📤 Загружаем файл в S3:\n")
 	fmt.Printf("   Файл: %s\n", filePath)
+	fmt.Printf("   Формат: %s\n", strings.ToUpper(string(fileInfo.Format)))
 	fmt.Printf("   Размер: %s\n", uploader.FormatFileSize(fileInfo.Size))
 	fmt.Printf("   Бакет: %s\n", app.Config.AwsBucketName)
 	fmt.Println()
@@ -103,7 +243,7 @@ func (app *Application) uploadToS3(ctx context.Context, filePath string) error {
 	}()
 
 	// Выполняем загрузку с контекстом
-	result, err := uploadService.UploadFile(ctx, filePath, func(bytesRead int64) {
+	result, err := uploadService.UploadFile(ctx, filePath, sum, func(bytesRead int64) {
 		progressChan <- bytesRead
 	})
 
@@ -111,27 +251,37 @@ func (app *Application) uploadToS3(ctx context.Context, filePath string) error {
 	close(progressChan)
 
 	if err != nil {
-		return fmt.Errorf("ошибка загрузки файла: %w", err)
+		return nil, fmt.Errorf("ошибка загрузки файла: %w", err)
 	}
 
 	// Проверяем, не была ли операция отменена
 	if ctx.Err() != nil {
-		return fmt.Errorf("операция отменена: %w", ctx.Err())
+		return nil, fmt.Errorf("операция отменена: %w", ctx.Err())
 	}
 
 	fmt.Printf("\n✅ Файл успешно загружен в S3!\n")
 	fmt.Printf("   URL: %s\n", result.URL)
-
-	// Обновляем данные приложения
-	if err := uploadService.UpdateApplicationData(result); err != nil {
-		return fmt.Errorf("ошибка обновления данных приложения: %w", err)
+	if result.ArtworkErr != nil {
+		fmt.Printf("⚠️  Предупреждение: не удалось загрузить обложку: %v\n", result.ArtworkErr)
+	} else if result.ArtworkURL != "" {
+		fmt.Printf("   Обложка: %s\n", result.ArtworkURL)
 	}
+	return result, nil
+}
 
-	// Сохраняем данные
-	if err := app.SaveData(); err != nil {
-		return fmt.Errorf("ошибка сохранения данных: %w", err)
+// newS3Uploader создает S3 uploader по настройкам из конфигурации
+func (app *Application) newS3Uploader() (*s3.Uploader, error) {
+	s3Config := &s3.Config{
+		Region:     app.Config.AwsRegion,
+		AccessKey:  app.Config.AwsAccessKey,
+		SecretKey:  app.Config.AwsSecretKey,
+		Endpoint:   app.Config.AwsEndpoint,
+		BucketName: app.Config.AwsBucketName,
 	}
 
-	fmt.Printf("\n📦 Данные трека добавлены в %s\n", defaultDataFilePath)
-	return nil
+	s3Uploader, err := s3.NewUploader(s3Config)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания S3 uploader: %w", err)
+	}
+	return s3Uploader, nil
 }
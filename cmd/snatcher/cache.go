@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazadus/go-snatcher/internal/cache"
+	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/player/streaming"
+	"github.com/hazadus/go-snatcher/internal/uploader"
+)
+
+// createCacheCommand создает команду cache с подкомандами управления локальным кэшем
+func (app *Application) createCacheCommand(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local track cache",
+		Long: `Manage the local on-disk cache of played tracks.
+Cached tracks are played from disk and are available in --offline mode.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "pin [trackid...]",
+		Short: "Download tracks to the cache and keep them from eviction",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.pinTracks(ctx, args)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "unpin [trackid...]",
+		Short: "Allow pinned tracks to be evicted from the cache",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.unpinTracks(args)
+		},
+	})
+
+	var all bool
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove cached tracks",
+		Long:  `Remove cached tracks. Pinned tracks are kept unless --all is given.`,
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.clearCache(all)
+		},
+	}
+	clearCmd.Flags().BoolVar(&all, "all", false, "remove pinned tracks too")
+	cmd.AddCommand(clearCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show cache usage and cached tracks",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.cacheStatus()
+		},
+	})
+
+	return cmd
+}
+
+// openCache открывает локальный кэш треков из конфигурации
+func (app *Application) openCache() (*cache.Cache, error) {
+	c, err := cache.New(app.Config.CacheDir, app.Config.CacheSizeMB*1024*1024)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия кэша: %w", err)
+	}
+	return c, nil
+}
+
+// tracksByIDs находит треки по списку числовых ID или UID из аргументов команды
+func (app *Application) tracksByIDs(args []string) ([]data.TrackMetadata, error) {
+	tracks := make([]data.TrackMetadata, 0, len(args))
+	for _, arg := range args {
+		track, err := data.FindTrack(app.Library, arg)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка поиска трека: %w", err)
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// cachedTracks возвращает треки, которые полностью сохранены в кэше
+func cachedTracks(c *cache.Cache, tracks []data.TrackMetadata) []data.TrackMetadata {
+	var cached []data.TrackMetadata
+	for _, track := range tracks {
+		if track.URL != "" && c.Has(track.URL) {
+			cached = append(cached, track)
+		}
+	}
+	return cached
+}
+
+func (app *Application) pinTracks(ctx context.Context, args []string) error {
+	tracks, err := app.tracksByIDs(args)
+	if err != nil {
+		return err
+	}
+
+	c, err := app.openCache()
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		if track.URL == "" {
+			return fmt.Errorf("у трека с ID %d отсутствует URL", track.ID)
+		}
+
+		// Закрепляем до скачивания, чтобы трек не вытеснил сам себя
+		if err := c.Pin(track.URL); err != nil {
+			return err
+		}
+
+		if c.Has(track.URL) {
+			fmt.Printf("📌 %s - %s уже в кэше, трек закреплен\n", track.Artist, track.Title)
+			continue
+		}
+
+		fmt.Printf("📥 Скачиваем в кэш: %s - %s\n", track.Artist, track.Title)
+		if err := downloadToCache(ctx, c, track); err != nil {
+			return err
+		}
+		fmt.Printf("\n📌 Трек сохранен в кэше и закреплен\n")
+	}
+
+	return nil
+}
+
+// downloadToCache скачивает трек в кэш целиком, показывая прогресс
+func downloadToCache(ctx context.Context, c *cache.Cache, track data.TrackMetadata) error {
+	const bufferSize = 256 * 1024 // 256KB буфер
+	reader, err := streaming.NewReader(ctx, track.URL, bufferSize)
+	if err != nil {
+		return fmt.Errorf("ошибка создания потокового ридера: %w", err)
+	}
+	defer reader.Close()
+
+	progressReader := &uploader.ProgressReader{
+		Reader: reader,
+		Size:   reader.Size(),
+		OnProgress: func(bytesRead int64) {
+			if reader.Size() > 0 {
+				percentage := float64(bytesRead) / float64(reader.Size()) * 100
+				fmt.Printf("\r📊 Прогресс: %.1f%% (%s)", percentage, uploader.FormatFileSize(bytesRead))
+			}
+		},
+	}
+
+	if err := c.Store(track.URL, progressReader); err != nil {
+		return fmt.Errorf("ошибка сохранения трека %d в кэш: %w", track.ID, err)
+	}
+	return nil
+}
+
+func (app *Application) unpinTracks(args []string) error {
+	tracks, err := app.tracksByIDs(args)
+	if err != nil {
+		return err
+	}
+
+	c, err := app.openCache()
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		if err := c.Unpin(track.URL); err != nil {
+			return err
+		}
+		fmt.Printf("📍 Закрепление снято: %s - %s\n", track.Artist, track.Title)
+	}
+	return nil
+}
+
+func (app *Application) clearCache(all bool) error {
+	c, err := app.openCache()
+	if err != nil {
+		return err
+	}
+
+	removed, err := c.Clear(!all)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🧹 Удалено файлов из кэша: %d\n", removed)
+	if !all {
+		fmt.Println("💡 Закрепленные треки сохранены. Используйте --all, чтобы удалить и их")
+	}
+	return nil
+}
+
+func (app *Application) cacheStatus() error {
+	c, err := app.openCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := c.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("💾 Кэш: %s\n", c.Dir())
+	fmt.Printf("   Занято: %s из %s\n", uploader.FormatFileSize(stats.Size), uploader.FormatFileSize(stats.MaxSize))
+	fmt.Printf("   Файлов: %d (закреплено: %d)\n", stats.Files, stats.Pinned)
+
+	library, err := app.Library.List()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения библиотеки: %w", err)
+	}
+	tracks := cachedTracks(c, library)
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n%-4s %-3s %-30s %-30s\n", "ID", "", "Исполнитель", "Название")
+	for _, track := range tracks {
+		pin := ""
+		if c.IsPinned(track.URL) {
+			pin = "📌"
+		}
+		fmt.Printf("%-4d %-3s %-30s %-30s\n",
+			track.ID, pin, truncateString(track.Artist, 28), truncateString(track.Title, 28))
+	}
+	return nil
+}
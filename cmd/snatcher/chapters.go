@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/metadata"
+	"github.com/hazadus/go-snatcher/internal/uploader"
+	"github.com/hazadus/go-snatcher/internal/utils"
+)
+
+// createChaptersCommand создает команду chapters с привязкой к экземпляру приложения
+func (app *Application) createChaptersCommand() *cobra.Command {
+	var clearChapters bool
+
+	cmd := &cobra.Command{
+		Use:   "chapters [id] [tracklist file|-]",
+		Short: "Show or set the chapters of a DJ mix",
+		Long: `Show the chapters of a track, or replace them with chapters parsed from a .cue sheet
+or a text tracklist with lines like "00:00 Artist - Title". Use "-" to paste the tracklist into stdin.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("неверный ID '%s': ID должен быть числом", args[0])
+			}
+
+			switch {
+			case clearChapters:
+				return app.setChapters(id, nil)
+			case len(args) == 2:
+				chapters, err := readChapters(args[1])
+				if err != nil {
+					return err
+				}
+				return app.setChapters(id, chapters)
+			}
+			return app.showChapters(id)
+		},
+	}
+
+	cmd.Flags().BoolVar(&clearChapters, "clear", false, "remove all chapters from the track")
+
+	return cmd
+}
+
+// readChapters читает главы из CUE-листа или текстового треклиста. Путь
+// "-" означает стандартный ввод.
+func readChapters(path string) ([]data.Chapter, error) {
+	var text []byte
+	var err error
+	if path == "-" {
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения треклиста: %w", err)
+	}
+
+	chapters, err := metadata.ParseChapters(string(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("в треклисте не найдено ни одной главы")
+	}
+	return uploader.LibraryChapters(chapters), nil
+}
+
+// setChapters заменяет главы трека и сохраняет данные
+func (app *Application) setChapters(id int, chapters []data.Chapter) error {
+	track, err := app.Library.Get(id)
+	if err != nil {
+		return err
+	}
+	track.Chapters = chapters
+	if err := app.Library.Put(&track); err != nil {
+		return fmt.Errorf("ошибка обновления трека: %w", err)
+	}
+
+	if err := app.SaveData(); err != nil {
+		return fmt.Errorf("ошибка сохранения данных: %w", err)
+	}
+
+	if len(chapters) == 0 {
+		fmt.Printf("✅ Главы трека %s - %s удалены\n", track.Artist, track.Title)
+		return nil
+	}
+	fmt.Printf("✅ Трек %s - %s: сохранено глав: %d\n", track.Artist, track.Title, len(chapters))
+	printChapters(chapters)
+	return nil
+}
+
+// showChapters выводит главы трека
+func (app *Application) showChapters(id int) error {
+	track, err := app.Library.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if len(track.Chapters) == 0 {
+		fmt.Printf("📑 У трека %s - %s нет глав\n", track.Artist, track.Title)
+		return nil
+	}
+	fmt.Printf("📑 Главы трека %s - %s:\n", track.Artist, track.Title)
+	printChapters(track.Chapters)
+	return nil
+}
+
+// printChapters выводит список глав с временем начала
+func printChapters(chapters []data.Chapter) {
+	for i, chapter := range chapters {
+		start := utils.FormatDuration(time.Duration(chapter.Start) * time.Second)
+		fmt.Printf("   %2d. %s %s\n", i+1, start, chapter)
+	}
+}
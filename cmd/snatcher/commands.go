@@ -14,13 +14,22 @@ func (app *Application) createRootCommand(ctx context.Context) *cobra.Command {
 		Long:  `A simple command line tool to manage and play mp3 files from local path or URL.`,
 	}
 
+	// Режим без сети: доступны только треки из локального кэша
+	rootCmd.PersistentFlags().BoolVar(&app.Offline, "offline", false, "use only tracks from the local cache")
+
 	// Добавляем команды, передавая в них экземпляр приложения и контекст
 	rootCmd.AddCommand(app.createAddCommand(ctx))
 	rootCmd.AddCommand(app.createListCommand())
 	rootCmd.AddCommand(app.createPlayCommand(ctx))
 	rootCmd.AddCommand(app.createDownloadCommand(ctx))
+	rootCmd.AddCommand(app.createSnatchCommand(ctx))
 	rootCmd.AddCommand(app.createDeleteCommand(ctx))
+	rootCmd.AddCommand(app.createDedupeCommand(ctx))
+	rootCmd.AddCommand(app.createRetagCommand(ctx))
+	rootCmd.AddCommand(app.createChaptersCommand())
 	rootCmd.AddCommand(app.createTUICommand())
+	rootCmd.AddCommand(app.createCacheCommand(ctx))
+	rootCmd.AddCommand(app.createDBCommand())
 
 	return rootCmd
 }
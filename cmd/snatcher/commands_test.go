@@ -4,12 +4,21 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/dhowden/tag"
 	"github.com/hazadus/go-snatcher/internal/config"
 	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/metadata"
+	"github.com/hazadus/go-snatcher/internal/player"
+	"github.com/hazadus/go-snatcher/internal/uploader"
+	"github.com/kkdai/youtube/v2"
 )
 
 // captureOutput перехватывает stdout и stderr во время выполнения функции
@@ -93,6 +102,45 @@ func TestCmdList(t *testing.T) {
 	}
 }
 
+// TestCmdListLong проверяет, что `list --long` выводит расширенные теги трека
+func TestCmdListLong(t *testing.T) {
+	tempDir := t.TempDir()
+	app := createTestApplication(t, tempDir)
+
+	app.Data.AddTrack(data.TrackMetadata{
+		Artist:      "Test Artist",
+		Title:       "Test Title",
+		Year:        2021,
+		Genre:       "Deep House",
+		TrackNumber: 3,
+		BPM:         124,
+		Key:         "8A",
+		Comment:     "Recorded live",
+		Length:      180,
+		Format:      "flac",
+	})
+
+	listCmd := app.createListCommand()
+	output := captureOutput(t, func() {
+		listCmd.SetArgs([]string{"--long"})
+		if err := listCmd.Execute(); err != nil {
+			t.Errorf("Ошибка выполнения команды list: %v", err)
+		}
+	})
+
+	expectedStrings := []string{
+		"#1 Test Artist - Test Title",
+		"2021 • Deep House • трек 3 • 124 BPM • 8A",
+		"Recorded live",
+		"FLAC",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Вывод команды list --long не содержит ожидаемую строку '%s': %s", expected, output)
+		}
+	}
+}
+
 // TestCmdListEmpty проверяет, что команда `list` корректно обрабатывает пустую библиотеку
 func TestCmdListEmpty(t *testing.T) {
 	// Создаем временную директорию для тестов
@@ -283,6 +331,8 @@ func createTestApplication(t *testing.T, tempDir string) *Application {
 		AwsEndpoint:   "http://localhost:9000",
 		AwsBucketName: "test-bucket",
 		DownloadDir:   tempDir,
+		CacheDir:      filepath.Join(tempDir, "cache"),
+		CacheSizeMB:   config.DefaultCacheSizeMB,
 	}
 
 	// Создаем тестовые данные
@@ -290,8 +340,9 @@ func createTestApplication(t *testing.T, tempDir string) *Application {
 
 	// Создаем приложение
 	app := &Application{
-		Config: testConfig,
-		Data:   testData,
+		Config:  testConfig,
+		Data:    testData,
+		Library: testData,
 	}
 
 	return app
@@ -328,3 +379,650 @@ func TestCmdAddInvalidArgs(t *testing.T) {
 		t.Errorf("Команда add не отобразила ошибку о неверных аргументах: %s", output)
 	}
 }
+
+// TestResolvePlayTracks проверяет выбор треков для команды play
+func TestResolvePlayTracks(t *testing.T) {
+	app := createTestApplication(t, t.TempDir())
+
+	for i := 1; i <= 3; i++ {
+		app.Data.AddTrack(data.TrackMetadata{
+			Artist: "Artist",
+			Title:  "Title",
+			URL:    "https://s3.example.com/test.mp3",
+		})
+	}
+
+	// Треки воспроизводятся в порядке, указанном пользователем
+	tracks, err := app.resolvePlayTracks([]string{"3", "1"}, false)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(tracks) != 2 || tracks[0].ID != 3 || tracks[1].ID != 1 {
+		t.Errorf("Ожидались треки 3 и 1, получено %v", tracks)
+	}
+
+	// Вместо числового ID можно указать UID трека в любом регистре
+	uid := strings.ToLower(app.Data.Tracks[1].UID)
+	tracks, err = app.resolvePlayTracks([]string{uid}, false)
+	if err != nil || len(tracks) != 1 || tracks[0].ID != 2 {
+		t.Errorf("Ожидался трек 2 по UID %s, получено %v, %v", uid, tracks, err)
+	}
+
+	// Флаг --all добавляет всю библиотеку
+	tracks, err = app.resolvePlayTracks(nil, true)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(tracks) != 3 {
+		t.Errorf("Ожидалось 3 трека, получено %d", len(tracks))
+	}
+
+	// Неверные и несуществующие ID приводят к ошибке
+	if _, err := app.resolvePlayTracks([]string{"abc"}, false); err == nil {
+		t.Error("Ожидалась ошибка для неверного ID")
+	}
+	if _, err := app.resolvePlayTracks([]string{"42"}, false); err == nil {
+		t.Error("Ожидалась ошибка для несуществующего трека")
+	}
+}
+
+// TestRememberPosition проверяет сохранение позиции для возобновления воспроизведения
+func TestRememberPosition(t *testing.T) {
+	tempDir := t.TempDir()
+	app := createTestApplication(t, tempDir)
+
+	// Файл данных сохраняется в домашней директории, подменяем ее
+	t.Setenv("HOME", tempDir)
+
+	app.Data.AddTrack(data.TrackMetadata{Artist: "Artist", Title: "Mix", Length: 5400})
+	track := app.Data.Tracks[0]
+	total := 90 * time.Minute
+
+	// Позиция в середине трека запоминается в памяти
+	if !app.rememberPosition(track, 47*time.Minute+12*time.Second, total) {
+		t.Error("Ожидалось изменение позиции")
+	}
+	if position := app.Data.Position(track.ID); position != 47*60+12 {
+		t.Errorf("Ожидалась позиция 2832 с, получено %d", position)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, ".snatcher_data")); !os.IsNotExist(err) {
+		t.Error("Позиция не должна записываться в файл данных во время воспроизведения")
+	}
+
+	// Позицию сохраняет главный цикл плеера
+	app.savePositions()
+	loaded := data.NewAppData()
+	if err := loaded.LoadData(defaultDataFilePath); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	if position := loaded.Position(track.ID); position != 47*60+12 {
+		t.Errorf("Ожидалась сохраненная позиция 2832 с, получено %d", position)
+	}
+
+	// Несохраненная позиция сохраняется при выходе из плеера
+	app.rememberPosition(track, 50*time.Minute, total)
+	app.savePlayerState(player.DefaultVolume, true)
+	if err := loaded.LoadData(defaultDataFilePath); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	if position := loaded.Position(track.ID); position != 50*60 {
+		t.Errorf("Ожидалась сохраненная позиция 3000 с, получено %d", position)
+	}
+
+	// Дослушанный трек забывает позицию
+	app.rememberPosition(track, total, total)
+	if position := app.Data.Position(track.ID); position != 0 {
+		t.Errorf("Позиция дослушанного трека должна быть удалена, получено %d", position)
+	}
+
+	// Удаление трека удаляет и его позицию
+	app.rememberPosition(track, 10*time.Minute, total)
+	if err := app.Data.DeleteTrackByID(track.ID); err != nil {
+		t.Fatalf("Ошибка удаления трека: %v", err)
+	}
+	if position := app.Data.Position(track.ID); position != 0 {
+		t.Errorf("Позиция удаленного трека должна быть удалена, получено %d", position)
+	}
+}
+
+// TestResolvePlayTracksOffline проверяет, что офлайн доступны только треки из кэша
+func TestResolvePlayTracksOffline(t *testing.T) {
+	tempDir := t.TempDir()
+	app := createTestApplication(t, tempDir)
+	app.Offline = true
+
+	app.Data.AddTrack(data.TrackMetadata{Artist: "A", Title: "Cached", URL: "https://s3.example.com/cached.mp3"})
+	app.Data.AddTrack(data.TrackMetadata{Artist: "B", Title: "Remote", URL: "https://s3.example.com/remote.mp3"})
+
+	c, err := app.openCache()
+	if err != nil {
+		t.Fatalf("Ошибка открытия кэша: %v", err)
+	}
+	if err := c.Store("https://s3.example.com/cached.mp3", strings.NewReader("audio")); err != nil {
+		t.Fatalf("Ошибка сохранения в кэш: %v", err)
+	}
+
+	// Из всей библиотеки остаются только треки из кэша
+	tracks, err := app.resolvePlayTracks(nil, true)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(tracks) != 1 || tracks[0].Title != "Cached" {
+		t.Errorf("Ожидался только трек из кэша, получено %v", tracks)
+	}
+
+	// Явно указанный трек не из кэша - ошибка
+	if _, err := app.resolvePlayTracks([]string{"2"}, false); err == nil {
+		t.Error("Ожидалась ошибка для трека, которого нет в кэше")
+	}
+}
+
+// TestDownloadThumbnail проверяет, что самое большое превью в JPEG
+// сохраняется рядом с аудиофайлом
+func TestDownloadThumbnail(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/maxres.jpg" {
+			t.Errorf("Скачано не то превью: %s", r.URL.Path)
+		}
+		_, _ = w.Write(jpeg)
+	}))
+	defer server.Close()
+
+	thumbnails := youtube.Thumbnails{
+		{URL: server.URL + "/default.jpg", Width: 120, Height: 90},
+		{URL: server.URL + "/maxres.webp", Width: 1920, Height: 1080},
+		{URL: server.URL + "/maxres.jpg?v=1", Width: 1280, Height: 720},
+	}
+	audioPath := filepath.Join(t.TempDir(), "mix.mp3")
+	coverPath, err := downloadThumbnail(context.Background(), thumbnails, audioPath)
+	if err != nil {
+		t.Fatalf("Ошибка скачивания превью: %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(audioPath), "mix.jpg"); coverPath != want {
+		t.Errorf("Ожидался путь %s, получено %s", want, coverPath)
+	}
+	if saved, err := os.ReadFile(coverPath); err != nil || !bytes.Equal(saved, jpeg) {
+		t.Errorf("Превью сохранено неверно: %v", err)
+	}
+
+	if _, err := downloadThumbnail(context.Background(), nil, audioPath); err == nil {
+		t.Error("Ожидалась ошибка для видео без превью")
+	}
+}
+
+// TestRetagTrack проверяет, что команда retag перезаписывает теги файла
+// в хранилище, не меняя аудиоданные
+func TestRetagTrack(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	audioData := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 256)
+	original := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x11TIT2\x00\x00\x00\x07\x00\x00\x00Old Tag"), audioData...)
+
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/test-bucket/song.mp3" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write(original)
+		case http.MethodPut:
+			uploaded, _ = io.ReadAll(r.Body)
+		}
+	}))
+	defer server.Close()
+
+	app := createTestApplication(t, tempDir)
+	app.Config.AwsEndpoint = server.URL
+	app.Data.AddTrack(data.TrackMetadata{
+		Artist:   "New Artist",
+		Title:    "New Title",
+		BPM:      126,
+		FileSize: int64(len(original)),
+		URL:      server.URL + "/test-bucket/song.mp3",
+	})
+
+	output := captureOutput(t, func() {
+		if err := app.retagTrack(context.Background(), 1); err != nil {
+			t.Errorf("Ошибка записи тегов: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Теги записаны") {
+		t.Errorf("Ожидалось сообщение об успехе, получено:\n%s", output)
+	}
+
+	tags, err := tag.ReadFrom(bytes.NewReader(uploaded))
+	if err != nil {
+		t.Fatalf("Ошибка чтения тегов загруженного файла: %v", err)
+	}
+	if tags.Artist() != "New Artist" || tags.Title() != "New Title" {
+		t.Errorf("Теги не перезаписаны: %q - %q", tags.Artist(), tags.Title())
+	}
+	if !bytes.HasSuffix(uploaded, audioData) {
+		t.Error("Аудиоданные изменились")
+	}
+	if track, _ := app.Data.TrackByID(1); track.FileSize != int64(len(uploaded)) {
+		t.Errorf("Размер файла в библиотеке не обновлен: %d", track.FileSize)
+	}
+	if sum, _ := uploader.HashReader(bytes.NewReader(uploaded), nil); app.Data.Tracks[0].SHA256 != sum {
+		t.Errorf("Хеш файла в библиотеке не обновлен: %q", app.Data.Tracks[0].SHA256)
+	}
+
+	// Команда принимает и UID трека
+	uploaded = nil
+	retagCmd := app.createRetagCommand(context.Background())
+	retagCmd.SetArgs([]string{app.Data.Tracks[0].UID})
+	captureOutput(t, func() {
+		if err := retagCmd.Execute(); err != nil {
+			t.Errorf("Ошибка записи тегов по UID: %v", err)
+		}
+	})
+	if uploaded == nil {
+		t.Error("Файл не загружен при указании трека по UID")
+	}
+
+	// Файл, на который ссылается другой трек, не перезаписывается
+	app.Data.AddTrack(data.TrackMetadata{Title: "Linked", URL: app.Data.Tracks[0].URL})
+	uploaded = nil
+	captureOutput(t, func() {
+		if err := app.retagTrack(context.Background(), 1); err == nil || uploaded != nil {
+			t.Errorf("Общий файл не должен перезаписываться: %v", err)
+		}
+	})
+
+	// Теги пишутся только в MP3
+	app.Data.AddTrack(data.TrackMetadata{Title: "Set", Format: "flac", URL: server.URL + "/test-bucket/set.flac"})
+	captureOutput(t, func() {
+		if err := app.retagTrack(context.Background(), 2); err == nil {
+			t.Error("Ожидалась ошибка для трека в формате FLAC")
+		}
+	})
+}
+
+// TestCmdChapters проверяет, что команда `chapters` сохраняет главы из треклиста
+func TestCmdChapters(t *testing.T) {
+	tempDir := t.TempDir()
+	app := createTestApplication(t, tempDir)
+	t.Setenv("HOME", tempDir)
+
+	app.Data.AddTrack(data.TrackMetadata{Artist: "DJ", Title: "Mix", Length: 3600})
+	tracklist := filepath.Join(tempDir, "tracklist.txt")
+	if err := os.WriteFile(tracklist, []byte("00:00 A - Intro\n12:30 B - Peak\n"), 0644); err != nil {
+		t.Fatalf("Ошибка записи треклиста: %v", err)
+	}
+
+	cmd := app.createChaptersCommand()
+	cmd.SetArgs([]string{"1", tracklist})
+	output := captureOutput(t, func() {
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("Ошибка команды chapters: %v", err)
+		}
+	})
+	if !strings.Contains(output, "00:12:30 B - Peak") {
+		t.Errorf("Вывод должен содержать главы, получено:\n%s", output)
+	}
+
+	loaded := data.NewAppData()
+	if err := loaded.LoadData(defaultDataFilePath); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	chapters := loaded.Tracks[0].Chapters
+	if len(chapters) != 2 || chapters[1] != (data.Chapter{Start: 750, Artist: "B", Title: "Peak"}) {
+		t.Errorf("Неверные сохраненные главы: %+v", chapters)
+	}
+
+	// Флаг --clear удаляет главы
+	cmd = app.createChaptersCommand()
+	cmd.SetArgs([]string{"1", "--clear"})
+	captureOutput(t, func() {
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("Ошибка команды chapters --clear: %v", err)
+		}
+	})
+	if len(app.Data.Tracks[0].Chapters) != 0 {
+		t.Errorf("Главы должны быть удалены: %+v", app.Data.Tracks[0].Chapters)
+	}
+}
+
+// TestVideoSourceInfo проверяет, что сведения о видео и главы из описания
+// сохраняются для последующего добавления в библиотеку
+func TestVideoSourceInfo(t *testing.T) {
+	video := &youtube.Video{
+		ID:          "dQw4w9WgXcQ",
+		Title:       "Live Set",
+		Author:      "Some Channel",
+		Description: "Tracklist:\n00:00 Intro\n03:30 Artist - Track\n",
+		PublishDate: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+	}
+
+	info := videoSourceInfo(video)
+	if info.URL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || info.Uploader != "Some Channel" || info.UploadDate != "2021-03-04" {
+		t.Errorf("Неверные сведения об источнике: %+v", info)
+	}
+	if len(info.Chapters) != 2 || info.Chapters[1].Artist != "Artist" {
+		t.Errorf("Главы из описания не разобраны: %+v", info.Chapters)
+	}
+}
+
+// TestSnatchResume проверяет, что команда `snatch` продолжает прерванную
+// операцию с уже скачанным файлом и не повторяет ее для добавленного трека
+func TestSnatchResume(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	var uploads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			uploads++
+			_, _ = io.Copy(io.Discard, r.Body)
+		}
+	}))
+	defer server.Close()
+
+	app := createTestApplication(t, tempDir)
+	app.Config.AwsEndpoint = server.URL
+
+	// Прошлый запуск прервался после скачивания
+	audioPath := filepath.Join(tempDir, "Artist_-_Song.mp3")
+	if err := os.WriteFile(audioPath, bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 256), 0644); err != nil {
+		t.Fatalf("Ошибка записи аудиофайла: %v", err)
+	}
+	if err := metadata.WriteSourceInfo(audioPath, metadata.SourceInfo{
+		URL:   youtubeVideoURL("dQw4w9WgXcQ"),
+		Title: "Artist - Song",
+	}); err != nil {
+		t.Fatalf("Ошибка записи сведений об источнике: %v", err)
+	}
+	statePath := snatchStatePath(tempDir, "dQw4w9WgXcQ")
+	if err := saveSnatchState(statePath, &snatchState{FilePath: audioPath}); err != nil {
+		t.Fatalf("Ошибка записи состояния: %v", err)
+	}
+
+	url := "https://youtu.be/dQw4w9WgXcQ"
+	output := captureOutput(t, func() {
+		if err := app.snatch(context.Background(), url, &snatchOptions{deleteLocal: true}); err != nil {
+			t.Errorf("Ошибка выполнения snatch: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Файл уже скачан") {
+		t.Errorf("Ожидалось продолжение с уже скачанным файлом, получено:\n%s", output)
+	}
+	if uploads != 1 {
+		t.Errorf("Ожидалась одна загрузка в S3, выполнено: %d", uploads)
+	}
+
+	found, err := app.Library.Query(data.Query{SourceURL: youtubeVideoURL("dQw4w9WgXcQ")})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Трек не добавлен в библиотеку: %+v, %v", found, err)
+	}
+	track := found[0]
+	if track.Artist != "Artist" || track.Title != "Song" {
+		t.Errorf("Неверные метаданные трека: %q - %q", track.Artist, track.Title)
+	}
+	for _, path := range []string{audioPath, metadata.SourceInfoPath(audioPath), statePath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Файл %s не удален", path)
+		}
+	}
+
+	// Повторный запуск не загружает трек заново
+	output = captureOutput(t, func() {
+		if err := app.snatch(context.Background(), url, &snatchOptions{}); err != nil {
+			t.Errorf("Ошибка повторного выполнения snatch: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Трек уже в библиотеке") || uploads != 1 || len(app.Data.Tracks) != 1 {
+		t.Errorf("Трек добавлен повторно, вывод:\n%s", output)
+	}
+}
+
+func TestFindBestAudioFormat(t *testing.T) {
+	formats := youtube.FormatList{
+		{ItagNo: 18, MimeType: `video/mp4; codecs="avc1.42001E, mp4a.40.2"`, Bitrate: 500000, AudioChannels: 2},
+		{ItagNo: 140, MimeType: `audio/mp4; codecs="mp4a.40.2"`, Bitrate: 130000, AudioChannels: 2},
+		{ItagNo: 251, MimeType: `audio/webm; codecs="opus"`, Bitrate: 160000, AudioChannels: 2},
+	}
+	best := findBestAudioFormat(formats)
+	if best == nil || best.ItagNo != 251 {
+		t.Fatalf("Ожидался аудиоформат с наибольшим битрейтом (251), получено %+v", best)
+	}
+	if ext := downloadExt(best.MimeType); ext != ".webm" {
+		t.Errorf("Ожидалось расширение .webm, получено %s", ext)
+	}
+
+	// Формат, который плеер декодирует сам, важнее битрейта
+	formats = append(formats, youtube.Format{ItagNo: 1, MimeType: "audio/mpeg", Bitrate: 64000, AudioChannels: 2})
+	if best := findBestAudioFormat(formats); best == nil || best.ItagNo != 1 || downloadExt(best.MimeType) != ".mp3" {
+		t.Errorf("Ожидался формат MP3, получено %+v", best)
+	}
+}
+
+// TestSnatchTranscodes проверяет, что скачанное аудио в MP4 перекодируется в
+// MP3 перед загрузкой, а без ffmpeg трек не добавляется в библиотеку
+func TestSnatchTranscodes(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	var uploadKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			uploadKeys = append(uploadKeys, r.URL.Path)
+			_, _ = io.Copy(io.Discard, r.Body)
+		}
+	}))
+	defer server.Close()
+
+	app := createTestApplication(t, tempDir)
+	app.Config.AwsEndpoint = server.URL
+
+	// Прошлый запуск прервался после скачивания AAC в MP4
+	audioPath := filepath.Join(tempDir, "Artist_-_Song.m4a")
+	if err := os.WriteFile(audioPath, []byte("\x00\x00\x00\x18ftypdash\x00\x00\x00\x00"), 0644); err != nil {
+		t.Fatalf("Ошибка записи аудиофайла: %v", err)
+	}
+	statePath := snatchStatePath(tempDir, "dQw4w9WgXcQ")
+	if err := saveSnatchState(statePath, &snatchState{FilePath: audioPath}); err != nil {
+		t.Fatalf("Ошибка записи состояния: %v", err)
+	}
+	url := "https://youtu.be/dQw4w9WgXcQ"
+
+	// ffmpeg не установлен
+	binDir := filepath.Join(tempDir, "bin")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatalf("Ошибка создания папки: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+	captureOutput(t, func() {
+		err := app.snatch(context.Background(), url, &snatchOptions{})
+		if err == nil || !strings.Contains(err.Error(), "ffmpeg") {
+			t.Errorf("Ожидалась ошибка об отсутствии ffmpeg, получено: %v", err)
+		}
+	})
+	if len(uploadKeys) != 0 || len(app.Data.Tracks) != 0 {
+		t.Fatalf("Файл MP4 не должен загружаться: загрузки %v, треков %d", uploadKeys, len(app.Data.Tracks))
+	}
+
+	// ffmpeg записывает MP3 в последний аргумент
+	script := "#!/bin/sh\nfor out; do :; done\nprintf '\\377\\373\\220\\000\\377\\373\\220\\000' > \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatalf("Ошибка записи ffmpeg: %v", err)
+	}
+	output := captureOutput(t, func() {
+		if err := app.snatch(context.Background(), url, &snatchOptions{}); err != nil {
+			t.Errorf("Ошибка выполнения snatch: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Перекодируем MP4 в MP3") {
+		t.Errorf("Ожидалось перекодирование, получено:\n%s", output)
+	}
+	if len(uploadKeys) != 1 || !strings.HasSuffix(uploadKeys[0], "/Artist_-_Song.mp3") {
+		t.Errorf("Ожидалась загрузка MP3, загружено: %v", uploadKeys)
+	}
+	if _, err := os.Stat(audioPath); !os.IsNotExist(err) {
+		t.Errorf("Исходный файл MP4 не удален")
+	}
+	if len(app.Data.Tracks) != 1 || app.Data.Tracks[0].Format != "mp3" {
+		t.Errorf("Неверный трек в библиотеке: %+v", app.Data.Tracks)
+	}
+}
+
+// TestAddDuplicate проверяет, что команда add не загружает файл, который уже есть в библиотеке
+func TestAddDuplicate(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	var uploads, deletes int
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			uploads++
+			contentType = r.Header.Get("Content-Type")
+			_, _ = io.Copy(io.Discard, r.Body)
+		case http.MethodDelete:
+			deletes++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	app := createTestApplication(t, tempDir)
+	app.Config.AwsEndpoint = server.URL
+
+	audioData := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 256)
+	original := filepath.Join(tempDir, "mix.mp3")
+	renamed := filepath.Join(tempDir, "mix (copy).mp3")
+	for _, path := range []string{original, renamed} {
+		if err := os.WriteFile(path, audioData, 0644); err != nil {
+			t.Fatalf("Ошибка записи аудиофайла: %v", err)
+		}
+	}
+
+	add := func(path string, opts *addOptions) string {
+		return captureOutput(t, func() {
+			if err := app.uploadToS3(context.Background(), path, opts); err != nil {
+				t.Errorf("Ошибка выполнения add: %v", err)
+			}
+		})
+	}
+
+	add(original, &addOptions{duplicate: duplicateSkip})
+	if uploads != 1 || len(app.Data.Tracks) != 1 || app.Data.Tracks[0].SHA256 == "" {
+		t.Fatalf("Файл не загружен или хеш не сохранен: загрузок %d, треки %+v", uploads, app.Data.Tracks)
+	}
+	if contentType != "audio/mpeg" {
+		t.Errorf("Ожидался Content-Type audio/mpeg, получено: %q", contentType)
+	}
+
+	// Тот же файл под другим именем не загружается
+	output := add(renamed, &addOptions{duplicate: duplicateSkip})
+	if !strings.Contains(output, "#1") || !strings.Contains(output, "то же содержимое") {
+		t.Errorf("Дубликат не найден, вывод:\n%s", output)
+	}
+	if uploads != 1 || len(app.Data.Tracks) != 1 {
+		t.Errorf("Дубликат загружен: загрузок %d, треков %d", uploads, len(app.Data.Tracks))
+	}
+
+	// Ссылка добавляет запись без загрузки
+	add(renamed, &addOptions{duplicate: duplicateLink})
+	if uploads != 1 || len(app.Data.Tracks) != 2 || app.Data.Tracks[1].URL != app.Data.Tracks[0].URL {
+		t.Errorf("Неверная ссылка на загруженный файл: загрузок %d, треки %+v", uploads, app.Data.Tracks)
+	}
+
+	output = captureOutput(t, func() {
+		if err := app.dedupe(); err != nil {
+			t.Errorf("Ошибка выполнения dedupe: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Одинаковое содержимое") || !strings.Contains(output, "#2") {
+		t.Errorf("dedupe не нашла дубликаты, вывод:\n%s", output)
+	}
+
+	// Файл, на который ссылается другой трек, остается в S3
+	captureOutput(t, func() { app.deleteTrack(context.Background(), 1) })
+	if deletes != 0 || len(app.Data.Tracks) != 1 {
+		t.Errorf("Общий файл удален из S3: удалений %d, треков %d", deletes, len(app.Data.Tracks))
+	}
+
+	// С --force файл загружается без проверки
+	add(renamed, &addOptions{force: true})
+	if uploads != 2 || len(app.Data.Tracks) != 2 {
+		t.Errorf("Файл не загружен с --force: загрузок %d, треков %d", uploads, len(app.Data.Tracks))
+	}
+}
+
+// TestDedupeHashesCachedTracks проверяет вычисление хешей треков из кэша командой dedupe --hash
+func TestDedupeHashesCachedTracks(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	app := createTestApplication(t, tempDir)
+	app.Offline = true
+	app.Data.AddTrack(data.TrackMetadata{Title: "Cached", URL: "https://s3.example.com/bucket/cached.mp3"})
+	app.Data.AddTrack(data.TrackMetadata{Title: "Remote", URL: "https://s3.example.com/bucket/remote.mp3"})
+
+	c, err := app.openCache()
+	if err != nil {
+		t.Fatalf("Ошибка открытия кэша: %v", err)
+	}
+	if err := c.Store("https://s3.example.com/bucket/cached.mp3", strings.NewReader("audio")); err != nil {
+		t.Fatalf("Ошибка сохранения в кэш: %v", err)
+	}
+
+	output := captureOutput(t, func() {
+		if err := app.hashTracks(context.Background()); err != nil {
+			t.Errorf("Ошибка вычисления хешей: %v", err)
+		}
+	})
+
+	if sum, _ := uploader.HashReader(strings.NewReader("audio"), nil); sum != app.Data.Tracks[0].SHA256 {
+		t.Errorf("Неверный хеш трека из кэша: %s", app.Data.Tracks[0].SHA256)
+	}
+	if app.Data.Tracks[1].SHA256 != "" || !strings.Contains(output, "отсутствует в кэше") {
+		t.Errorf("Трек вне кэша не должен хешироваться в режиме offline, вывод:\n%s", output)
+	}
+}
+
+// TestMigrateLibrary проверяет перенос библиотеки командой `db migrate`
+func TestMigrateLibrary(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	app := createTestApplication(t, tempDir)
+	app.Config.LibraryBackend = config.BackendYAML
+	app.Data.AddTrack(data.TrackMetadata{Artist: "A", Title: "One", SourceURL: "https://youtu.be/one"})
+	app.Data.AddTrack(data.TrackMetadata{Artist: "B", Title: "Two"})
+	if err := app.Data.DeleteTrackByID(1); err != nil {
+		t.Fatalf("Ошибка удаления трека: %v", err)
+	}
+
+	output := captureOutput(t, func() {
+		if err := app.migrateLibrary(config.BackendDB); err != nil {
+			t.Errorf("Ошибка переноса библиотеки: %v", err)
+		}
+	})
+	if !strings.Contains(output, "library_backend: db") {
+		t.Errorf("Ожидалась подсказка о настройке хранилища, получено:\n%s", output)
+	}
+
+	db, err := app.openLibrary(config.BackendDB)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer db.Close()
+	tracks, err := db.List()
+	if err != nil || len(tracks) != 1 || tracks[0].ID != 2 || tracks[0].Title != "Two" {
+		t.Errorf("Неверные треки в базе данных: %+v, %v", tracks, err)
+	}
+
+	// Перенос в текущее хранилище не имеет смысла
+	if err := app.migrateLibrary(config.BackendYAML); err == nil {
+		t.Error("Ожидалась ошибка переноса в текущее хранилище")
+	}
+	if err := app.migrateLibrary("sqlite"); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного хранилища")
+	}
+}
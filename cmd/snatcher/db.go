@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazadus/go-snatcher/internal/config"
+	"github.com/hazadus/go-snatcher/internal/data"
+)
+
+// createDBCommand создает команду db с подкомандами управления хранилищем библиотеки
+func (app *Application) createDBCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the library storage",
+		Long: `Manage the storage of the track library. The backend is chosen by library_backend in the config:
+yaml keeps tracks in the data file, db keeps them in an embedded database with indexes.`,
+	}
+
+	var to string
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Copy the library to another storage backend",
+		Long: `Copy all tracks from the current storage backend to another one, keeping track IDs.
+Tracks in the target backend are replaced; the current backend is left untouched.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.migrateLibrary(to)
+		},
+	}
+	migrateCmd.Flags().StringVar(&to, "to", "", "target backend: yaml or db")
+	_ = migrateCmd.MarkFlagRequired("to")
+	cmd.AddCommand(migrateCmd)
+
+	return cmd
+}
+
+// migrateLibrary копирует треки из текущего хранилища в хранилище backend
+func (app *Application) migrateLibrary(backend string) error {
+	if err := config.ValidateBackend(backend); err != nil {
+		return err
+	}
+	if backend == app.Config.LibraryBackend {
+		return fmt.Errorf("библиотека уже хранится в %s", backend)
+	}
+
+	tracks, err := app.Library.List()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения библиотеки: %w", err)
+	}
+
+	target, err := app.openLibrary(backend)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия хранилища %s: %w", backend, err)
+	}
+	defer target.Close()
+
+	if err := replaceTracks(target, tracks); err != nil {
+		return fmt.Errorf("ошибка записи в хранилище %s: %w", backend, err)
+	}
+	// Треки хранилища yaml записываются вместе с файлом данных
+	if backend == config.BackendYAML {
+		if err := app.SaveData(); err != nil {
+			return fmt.Errorf("ошибка сохранения данных: %w", err)
+		}
+	}
+
+	fmt.Printf("✅ Перенесено треков: %d (%s → %s)\n", len(tracks), app.Config.LibraryBackend, backend)
+	fmt.Printf("💡 Чтобы использовать новое хранилище, укажите в %s:\n", defaultConfigPath)
+	fmt.Printf("   library_backend: %s\n", backend)
+	return nil
+}
+
+// replaceTracks заменяет треки хранилища одной транзакцией. Треки с теми же
+// ID перезаписываются, а не удаляются: так у них сохраняются позиции
+// воспроизведения.
+func replaceTracks(store data.Store, tracks []data.TrackMetadata) error {
+	keep := make(map[int]bool, len(tracks))
+	for _, track := range tracks {
+		keep[track.ID] = true
+	}
+
+	return store.Update(func(tx data.Tx) error {
+		existing, err := tx.List()
+		if err != nil {
+			return err
+		}
+		for _, track := range existing {
+			if !keep[track.ID] {
+				if err := tx.Delete(track.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range tracks {
+			if err := tx.Put(&tracks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
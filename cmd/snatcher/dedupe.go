@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazadus/go-snatcher/internal/cache"
+	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/player/streaming"
+	"github.com/hazadus/go-snatcher/internal/uploader"
+)
+
+// createDedupeCommand создает команду dedupe с привязкой к экземпляру приложения
+func (app *Application) createDedupeCommand(ctx context.Context) *cobra.Command {
+	var hashMissing bool
+
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Find duplicate tracks in the library",
+		Long: `Find tracks with the same content (SHA-256) or the same source URL.
+Tracks added before content hashes were stored have no hash: use --hash to read them
+from the local cache or S3 and store their hashes before searching.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if hashMissing {
+				if err := app.hashTracks(ctx); err != nil {
+					return err
+				}
+			}
+			return app.dedupe()
+		},
+	}
+
+	cmd.Flags().BoolVar(&hashMissing, "hash", false, "compute missing content hashes before searching")
+
+	return cmd
+}
+
+// dedupe выводит группы дубликатов в библиотеке
+func (app *Application) dedupe() error {
+	tracks, err := app.Library.List()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения библиотеки: %w", err)
+	}
+
+	groups := data.FindDuplicates(tracks)
+	if len(groups) == 0 {
+		fmt.Println("✅ Дубликатов не найдено")
+	} else {
+		fmt.Printf("🔁 Найдено групп дубликатов: %d\n", len(groups))
+	}
+	for _, group := range groups {
+		switch group.Reason {
+		case data.DuplicateContent:
+			fmt.Printf("\n🧬 Одинаковое содержимое (sha256 %s):\n", truncateString(group.Value, 16))
+		case data.DuplicateSource:
+			fmt.Printf("\n🔗 Один источник (%s):\n", group.Value)
+		}
+		for _, track := range group.Tracks {
+			fmt.Printf("   #%-4d %s - %s  %s\n", track.ID, track.Artist, track.Title, track.URL)
+		}
+	}
+	if len(groups) > 0 {
+		fmt.Println("\n💡 Лишние треки можно удалить командой 'snatcher delete [ID]'")
+	}
+
+	var unhashed int
+	for _, track := range tracks {
+		if track.SHA256 == "" {
+			unhashed++
+		}
+	}
+	if unhashed > 0 {
+		fmt.Printf("ℹ️  Треков без хеша содержимого: %d (используйте --hash, чтобы вычислить его)\n", unhashed)
+	}
+	return nil
+}
+
+// trackReader - источник содержимого трека: файл из кэша или поток из S3
+type trackReader interface {
+	io.ReadCloser
+	Size() int64
+}
+
+// hashTracks вычисляет и сохраняет хеш содержимого треков, у которых его
+// нет. Треки читаются из кэша, а если их там нет - из S3.
+func (app *Application) hashTracks(ctx context.Context) error {
+	tracks, err := app.Library.List()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения библиотеки: %w", err)
+	}
+
+	c, err := app.openCache()
+	if err != nil {
+		return err
+	}
+
+	// Треки со ссылкой на один файл хешируются один раз
+	sums := make(map[string]string)
+	var hashed int
+	for _, track := range tracks {
+		if track.SHA256 != "" || track.URL == "" {
+			continue
+		}
+
+		sum, ok := sums[track.URL]
+		if !ok {
+			fmt.Printf("🔐 Вычисляем хеш: #%d %s - %s\n", track.ID, track.Artist, track.Title)
+			if sum, err = app.hashTrack(ctx, c, track); err != nil {
+				fmt.Printf("\n⚠️  Предупреждение: %v\n", err)
+				continue
+			}
+			fmt.Println()
+			sums[track.URL] = sum
+		}
+
+		track.SHA256 = sum
+		if err := app.Library.Put(&track); err != nil {
+			return fmt.Errorf("ошибка сохранения трека %d: %w", track.ID, err)
+		}
+		hashed++
+	}
+
+	if hashed == 0 {
+		return nil
+	}
+	if err := app.SaveData(); err != nil {
+		return fmt.Errorf("ошибка сохранения данных: %w", err)
+	}
+	fmt.Printf("✅ Хеш сохранен для треков: %d\n\n", hashed)
+	return nil
+}
+
+// hashTrack читает содержимое трека из кэша или из S3 и возвращает его SHA-256
+func (app *Application) hashTrack(ctx context.Context, c *cache.Cache, track data.TrackMetadata) (string, error) {
+	var reader trackReader
+	var err error
+	switch {
+	case c.Has(track.URL):
+		reader, err = c.Open(track.URL)
+	case app.Offline:
+		return "", fmt.Errorf("трек с ID %d отсутствует в кэше", track.ID)
+	default:
+		const bufferSize = 256 * 1024 // 256KB буфер
+		reader, err = streaming.NewReader(ctx, track.URL, bufferSize)
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения трека %d: %w", track.ID, err)
+	}
+	defer reader.Close()
+
+	return uploader.HashReader(reader, func(bytesRead int64) {
+		if reader.Size() > 0 {
+			percentage := float64(bytesRead) / float64(reader.Size()) * 100
+			fmt.Printf("\r📊 Прогресс: %.1f%% (%s)", percentage, uploader.FormatFileSize(bytesRead))
+		}
+	})
+}
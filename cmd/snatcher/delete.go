@@ -4,12 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/hazadus/go-snatcher/internal/s3"
+	"github.com/hazadus/go-snatcher/internal/data"
 )
 
 // createDeleteCommand создает команду delete с привязкой к экземпляру приложения
@@ -17,22 +16,22 @@ func (app *Application) createDeleteCommand(ctx context.Context) *cobra.Command
 	return &cobra.Command{
 		Use:   "delete [id]",
 		Short: "Delete a track by ID",
-		Long:  `Delete a track from both S3 storage and local data by its ID.`,
+		Long:  `Delete a track from both S3 storage and local data by its numeric ID or UID.`,
 		Args:  cobra.ExactArgs(1),
 		Run: func(_ *cobra.Command, args []string) {
-			id, err := strconv.Atoi(args[0])
+			track, err := data.FindTrack(app.Library, args[0])
 			if err != nil {
-				fmt.Printf("❌ Ошибка: неверный ID '%s'. ID должен быть числом.\n", args[0])
+				fmt.Printf("❌ Ошибка: %v\n", err)
 				return
 			}
-			app.deleteTrack(ctx, id)
+			app.deleteTrack(ctx, track.ID)
 		},
 	}
 }
 
 func (app *Application) deleteTrack(ctx context.Context, id int) {
 	// Находим трек по ID
-	track, err := app.Data.TrackByID(id)
+	track, err := app.Library.Get(id)
 	if err != nil {
 		fmt.Printf("❌ Ошибка: %v\n", err)
 		return
@@ -40,6 +39,21 @@ func (app *Application) deleteTrack(ctx context.Context, id int) {
 
 	fmt.Printf("🗑️  Удаляем трек: %s - %s\n", track.Artist, track.Title)
 
+	// Файлы, на которые ссылаются другие треки (см. add --duplicate link),
+	// остаются в S3 и в кэше
+	shared, err := app.sharedFiles(track)
+	if err != nil {
+		fmt.Printf("❌ Ошибка: %v\n", err)
+		return
+	}
+	if shared[track.URL] {
+		fmt.Println("🔗 Файл используется другими треками и остается в S3")
+		track.URL = ""
+	}
+	if shared[track.ArtworkURL] {
+		track.ArtworkURL = ""
+	}
+
 	// Удаляем файл из S3, если есть URL
 	if track.URL != "" {
 		if err := app.deleteFromS3(ctx, track.URL); err != nil {
@@ -50,11 +64,31 @@ func (app *Application) deleteTrack(ctx context.Context, id int) {
 		}
 	}
 
+	// Удаляем обложку из S3
+	if track.ArtworkURL != "" {
+		if err := app.deleteFromS3(ctx, track.ArtworkURL); err != nil {
+			fmt.Printf("⚠️  Предупреждение: не удалось удалить обложку из S3: %v\n", err)
+		} else {
+			fmt.Println("✅ Обложка успешно удалена из S3")
+		}
+	}
+
+	// Удаляем трек из локального кэша
+	if track.URL != "" {
+		if c, err := app.openCache(); err == nil {
+			if err := c.Remove(track.URL); err != nil {
+				fmt.Printf("⚠️  Предупреждение: %v\n", err)
+			}
+		}
+	}
+
 	// Удаляем трек из локальных данных
-	if err := app.Data.DeleteTrackByID(id); err != nil {
+	if err := app.Library.Delete(id); err != nil {
 		fmt.Printf("❌ Ошибка удаления трека из данных: %v\n", err)
 		return
 	}
+	// Позиция не должна достаться новому треку с тем же ID
+	app.Data.SetPosition(id, 0)
 
 	// Сохраняем обновленные данные
 	if err := app.SaveData(); err != nil {
@@ -65,19 +99,32 @@ func (app *Application) deleteTrack(ctx context.Context, id int) {
 	fmt.Println("✅ Трек успешно удален из библиотеки")
 }
 
-func (app *Application) deleteFromS3(ctx context.Context, fileURL string) error {
-	// Создаем S3 uploader
-	s3Config := &s3.Config{
-		Region:     app.Config.AwsRegion,
-		AccessKey:  app.Config.AwsAccessKey,
-		SecretKey:  app.Config.AwsSecretKey,
-		Endpoint:   app.Config.AwsEndpoint,
-		BucketName: app.Config.AwsBucketName,
+// sharedFiles возвращает URL файлов трека, на которые ссылаются и другие
+// треки библиотеки
+func (app *Application) sharedFiles(track data.TrackMetadata) (map[string]bool, error) {
+	tracks, err := app.Library.List()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения библиотеки: %w", err)
 	}
+	shared := make(map[string]bool)
+	for _, other := range tracks {
+		if other.ID == track.ID {
+			continue
+		}
+		for _, url := range []string{other.URL, other.ArtworkURL} {
+			if url != "" && (url == track.URL || url == track.ArtworkURL) {
+				shared[url] = true
+			}
+		}
+	}
+	return shared, nil
+}
 
-	uploader, err := s3.NewUploader(s3Config)
+func (app *Application) deleteFromS3(ctx context.Context, fileURL string) error {
+	// Создаем S3 uploader
+	uploader, err := app.newS3Uploader()
 	if err != nil {
-		return fmt.Errorf("ошибка создания S3 клиента: %w", err)
+		return err
 	}
 
 	// Извлекаем ключ из URL
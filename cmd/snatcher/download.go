@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/hazadus/go-snatcher/internal/audio"
+	"github.com/hazadus/go-snatcher/internal/metadata"
 	"github.com/kkdai/youtube/v2"
 	"github.com/spf13/cobra"
 )
@@ -19,23 +24,26 @@ func (app *Application) createDownloadCommand(ctx context.Context) *cobra.Comman
 	return &cobra.Command{
 		Use:   "download [YouTube URL]",
 		Short: "Download audio from YouTube video as MP3",
-		Long:  `Download audio from YouTube video and save it as MP3 file to the configured download directory.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Download audio from YouTube video and save it as MP3 file to the configured download directory.
+Audio formats the player cannot decode (AAC in MP4, Opus in WebM) are converted to MP3 with ffmpeg.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
 			// Создаем контекст с таймаутом для скачивания (15 минут)
 			downloadCtx, cancel := context.WithTimeout(ctx, 15*time.Minute)
 			defer cancel()
-			return app.downloadYouTubeAudio(downloadCtx, args[0])
+			_, err := app.downloadYouTubeAudio(downloadCtx, args[0])
+			return err
 		},
 	}
 }
 
-// downloadYouTubeAudio скачивает аудио из YouTube видео
-func (app *Application) downloadYouTubeAudio(ctx context.Context, url string) error {
+// downloadYouTubeAudio скачивает аудио из YouTube видео и возвращает путь к
+// скачанному файлу
+func (app *Application) downloadYouTubeAudio(ctx context.Context, url string) (string, error) {
 	// Извлекаем ID видео из URL
 	videoID, err := extractVideoID(url)
 	if err != nil {
-		return fmt.Errorf("ошибка извлечения ID видео: %w", err)
+		return "", fmt.Errorf("ошибка извлечения ID видео: %w", err)
 	}
 
 	fmt.Printf("Скачиваем аудио для видео ID: %s\n", videoID)
@@ -46,7 +54,7 @@ func (app *Application) downloadYouTubeAudio(ctx context.Context, url string) er
 	// Получаем информацию о видео с контекстом
 	video, err := client.GetVideoContext(ctx, videoID)
 	if err != nil {
-		return fmt.Errorf("ошибка получения информации о видео: %w", err)
+		return "", fmt.Errorf("ошибка получения информации о видео: %w", err)
 	}
 
 	fmt.Printf("Название: %s\n", video.Title)
@@ -55,7 +63,7 @@ func (app *Application) downloadYouTubeAudio(ctx context.Context, url string) er
 	// Находим лучший аудио формат
 	audioFormat := findBestAudioFormat(video.Formats)
 	if audioFormat == nil {
-		return fmt.Errorf("аудио формат не найден")
+		return "", fmt.Errorf("аудио формат не найден")
 	}
 
 	fmt.Printf("Используем формат: itag=%d, качество=%s\n", audioFormat.ItagNo, audioFormat.Quality)
@@ -63,23 +71,23 @@ func (app *Application) downloadYouTubeAudio(ctx context.Context, url string) er
 	// Получаем поток для скачивания с контекстом
 	stream, _, err := client.GetStreamContext(ctx, video, audioFormat)
 	if err != nil {
-		return fmt.Errorf("ошибка получения потока: %w", err)
+		return "", fmt.Errorf("ошибка получения потока: %w", err)
 	}
 	defer stream.Close()
 
-	// Создаем имя файла
-	fileName := sanitizeFileName(video.Title) + ".mp3"
+	// Создаем имя файла с расширением скачиваемого формата
+	fileName := sanitizeFileName(video.Title) + downloadExt(audioFormat.MimeType)
 	filePath := filepath.Join(app.Config.DownloadDir, fileName)
 
 	// Создаем директорию если она не существует
 	if err := os.MkdirAll(app.Config.DownloadDir, 0755); err != nil {
-		return fmt.Errorf("ошибка создания директории: %w", err)
+		return "", fmt.Errorf("ошибка создания директории: %w", err)
 	}
 
 	// Создаем файл
 	file, err := os.Create(filePath)
 	if err != nil {
-		return fmt.Errorf("ошибка создания файла: %w", err)
+		return "", fmt.Errorf("ошибка создания файла: %w", err)
 	}
 	defer file.Close()
 
@@ -96,14 +104,130 @@ func (app *Application) downloadYouTubeAudio(ctx context.Context, url string) er
 	select {
 	case err := <-done:
 		if err != nil {
-			return fmt.Errorf("ошибка скачивания: %w", err)
+			return "", fmt.Errorf("ошибка скачивания: %w", err)
 		}
 	case <-ctx.Done():
-		return fmt.Errorf("скачивание отменено: %w", ctx.Err())
+		return "", fmt.Errorf("скачивание отменено: %w", ctx.Err())
+	}
+
+	file.Close()
+
+	// Плеер декодирует не все форматы YouTube: остальные перекодируем в MP3
+	if filePath, err = ensurePlayable(ctx, filePath); err != nil {
+		return "", err
 	}
 
 	fmt.Printf("Аудио успешно скачано: %s\n", filePath)
-	return nil
+
+	// Сведения о видео сохраняем рядом с аудио: при загрузке они попадут в библиотеку
+	source := videoSourceInfo(video)
+	if err := metadata.WriteSourceInfo(filePath, source); err != nil {
+		fmt.Printf("⚠️  Предупреждение: %v\n", err)
+	} else if len(source.Chapters) > 0 {
+		fmt.Printf("Найдено глав в описании видео: %d\n", len(source.Chapters))
+	}
+
+	// Превью видео сохраняем рядом с аудио: при загрузке оно станет обложкой
+	if coverPath, err := downloadThumbnail(ctx, video.Thumbnails, filePath); err != nil {
+		fmt.Printf("⚠️  Предупреждение: не удалось скачать обложку: %v\n", err)
+	} else {
+		fmt.Printf("Обложка сохранена: %s\n", coverPath)
+	}
+	return filePath, nil
+}
+
+// videoSourceInfo собирает сведения об источнике из информации о видео.
+// Главы берутся из строк описания с временем в начале: по ним же YouTube
+// размечает главы видео.
+func videoSourceInfo(video *youtube.Video) metadata.SourceInfo {
+	return metadata.SourceInfo{
+		URL:        youtubeVideoURL(video.ID),
+		Title:      video.Title,
+		Uploader:   video.Author,
+		UploadDate: metadata.FormatUploadDate(video.PublishDate),
+		Chapters:   metadata.ParseTracklist(video.Description),
+	}
+}
+
+// youtubeVideoURL возвращает канонический URL видео по его ID
+func youtubeVideoURL(videoID string) string {
+	return "https://www.youtube.com/watch?v=" + videoID
+}
+
+// downloadThumbnail скачивает самое большое превью видео в формате JPEG или
+// PNG и сохраняет его рядом с аудиофайлом. Возвращает путь к обложке.
+func downloadThumbnail(ctx context.Context, thumbnails youtube.Thumbnails, audioPath string) (string, error) {
+	best := bestThumbnail(thumbnails)
+	if best == nil {
+		return "", fmt.Errorf("у видео нет превью")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, best.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("сервер вернул статус: %s", resp.Status)
+	}
+
+	picture, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailSize))
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения превью: %w", err)
+	}
+
+	// Превью бывают и в WebP, который не поддерживается как обложка
+	var ext string
+	switch http.DetectContentType(picture) {
+	case "image/jpeg":
+		ext = "jpg"
+	case "image/png":
+		ext = "png"
+	default:
+		return "", fmt.Errorf("неподдерживаемый формат превью: %s", http.DetectContentType(picture))
+	}
+
+	coverPath := metadata.ArtworkPath(audioPath, ext)
+	if err := os.WriteFile(coverPath, picture, 0644); err != nil {
+		return "", fmt.Errorf("ошибка сохранения обложки: %w", err)
+	}
+	return coverPath, nil
+}
+
+// maxThumbnailSize ограничивает размер скачиваемого превью
+const maxThumbnailSize = 10 << 20
+
+// bestThumbnail выбирает превью с наибольшим разрешением, предпочитая JPEG
+func bestThumbnail(thumbnails youtube.Thumbnails) *youtube.Thumbnail {
+	var best *youtube.Thumbnail
+	for i := range thumbnails {
+		thumbnail := &thumbnails[i]
+		if best == nil {
+			best = thumbnail
+			continue
+		}
+		isJPEG := isJPEGThumbnail(thumbnail.URL)
+		if isJPEG != isJPEGThumbnail(best.URL) {
+			if isJPEG {
+				best = thumbnail
+			}
+			continue
+		}
+		if thumbnail.Width*thumbnail.Height > best.Width*best.Height {
+			best = thumbnail
+		}
+	}
+	return best
+}
+
+// isJPEGThumbnail проверяет по URL, что превью в формате JPEG
+func isJPEGThumbnail(url string) bool {
+	path, _, _ := strings.Cut(url, "?")
+	return strings.HasSuffix(path, ".jpg")
 }
 
 // extractVideoID извлекает ID видео из различных форматов YouTube URL
@@ -131,7 +255,9 @@ func extractVideoID(url string) (string, error) {
 	return "", fmt.Errorf("не удалось извлечь ID видео из URL: %s", url)
 }
 
-// findBestAudioFormat находит лучший аудио формат для скачивания
+// findBestAudioFormat находит лучший аудио формат для скачивания. Сначала
+// выбираются форматы, которые плеер декодирует без перекодирования, затем
+// форматы только с аудио, а среди них - с наибольшим битрейтом.
 func findBestAudioFormat(formats youtube.FormatList) *youtube.Format {
 	// Сначала ищем форматы только с аудио
 	audioFormats := formats.WithAudioChannels()
@@ -147,28 +273,99 @@ func findBestAudioFormat(formats youtube.FormatList) *youtube.Format {
 		return nil
 	}
 
-	// Ищем форматы с лучшим качеством аудио
 	bestFormat := &audioFormats[0]
-
 	for i := range audioFormats {
 		format := &audioFormats[i]
 
-		// Предпочитаем форматы с более высоким битрейтом
-		if format.Bitrate > bestFormat.Bitrate {
-			bestFormat = format
+		_, playable := audio.FromContentType(format.MimeType)
+		_, bestPlayable := audio.FromContentType(bestFormat.MimeType)
+		if playable != bestPlayable {
+			if playable {
+				bestFormat = format
+			}
+			continue
 		}
 
-		// Предпочитаем MP4/M4A форматы для лучшей совместимости
-		if strings.Contains(format.MimeType, "mp4") || strings.Contains(format.MimeType, "m4a") {
-			if !strings.Contains(bestFormat.MimeType, "mp4") && !strings.Contains(bestFormat.MimeType, "m4a") {
+		audioOnly := strings.HasPrefix(format.MimeType, "audio/")
+		if audioOnly != strings.HasPrefix(bestFormat.MimeType, "audio/") {
+			if audioOnly {
 				bestFormat = format
 			}
+			continue
+		}
+
+		// Предпочитаем форматы с более высоким битрейтом
+		if format.Bitrate > bestFormat.Bitrate {
+			bestFormat = format
 		}
 	}
 
 	return bestFormat
 }
 
+// downloadExt возвращает расширение файла (с точкой) для MIME-типа
+// скачиваемого формата
+func downloadExt(mimeType string) string {
+	if format, ok := audio.FromContentType(mimeType); ok {
+		return format.Ext()
+	}
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	switch mediaType {
+	case "audio/mp4":
+		return ".m4a"
+	case "audio/webm", "video/webm":
+		return ".webm"
+	}
+	return ".mp4"
+}
+
+// ensurePlayable проверяет по сигнатуре, что плеер может декодировать
+// скачанный файл, и иначе перекодирует его в MP3. Возвращает путь к файлу,
+// который можно загружать в библиотеку.
+func ensurePlayable(ctx context.Context, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	header, err := audio.ReadHeader(file)
+	file.Close()
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения заголовка файла: %w", err)
+	}
+
+	if _, ok := audio.Sniff(header); ok {
+		return filePath, nil
+	}
+	container, ok := audio.SniffUnsupported(header)
+	if !ok {
+		container = strings.ToUpper(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	}
+	fmt.Printf("Перекодируем %s в MP3...\n", container)
+	return transcodeToMP3(ctx, filePath)
+}
+
+// transcodeToMP3 перекодирует аудиофайл в MP3 рядом с исходным с помощью
+// ffmpeg и удаляет исходный файл. Возвращает путь к MP3-файлу.
+func transcodeToMP3(ctx context.Context, filePath string) (string, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", fmt.Errorf("для перекодирования в MP3 нужен ffmpeg: %w", err)
+	}
+
+	mp3Path := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + audio.FormatMP3.Ext()
+	cmd := exec.CommandContext(ctx, ffmpeg, "-y", "-loglevel", "error",
+		"-i", filePath, "-vn", "-codec:a", "libmp3lame", "-q:a", "2", mp3Path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(mp3Path)
+		return "", fmt.Errorf("ошибка перекодирования в MP3: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		fmt.Printf("⚠️  Предупреждение: не удалось удалить исходный файл: %v\n", err)
+	}
+	return mp3Path, nil
+}
+
 // sanitizeFileName очищает имя файла от недопустимых символов
 func sanitizeFileName(name string) string {
 	// Заменяем недопустимые символы
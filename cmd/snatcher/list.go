@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/track"
 	"github.com/hazadus/go-snatcher/internal/uploader"
 	"github.com/hazadus/go-snatcher/internal/utils"
@@ -13,20 +14,44 @@ import (
 
 // createListCommand создает команду list с привязкой к экземпляру приложения
 func (app *Application) createListCommand() *cobra.Command {
-	return &cobra.Command{
+	var long bool
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all tracks from the library",
 		Long:  `Display a list of all tracks stored in the application data.`,
 		Run: func(_ *cobra.Command, _ []string) {
-			app.listTracks()
+			app.listTracks(long)
 		},
 	}
+
+	cmd.Flags().BoolVarP(&long, "long", "l", false, "Show all tags of every track (year, genre, BPM, key, comment...)")
+
+	return cmd
 }
 
-func (app *Application) listTracks() {
+func (app *Application) listTracks(long bool) {
 	// Создаем менеджер треков
-	trackManager := track.NewManager(app.Data)
-	tracks := trackManager.ListTracks()
+	trackManager := track.NewManager(app.Library)
+	tracks, err := trackManager.ListTracks()
+	if err != nil {
+		fmt.Printf("❌ Ошибка: %v\n", err)
+		return
+	}
+
+	// Офлайн показываем только треки из кэша
+	if app.Offline {
+		c, err := app.openCache()
+		if err != nil {
+			fmt.Printf("❌ Ошибка: %v\n", err)
+			return
+		}
+		tracks = cachedTracks(c, tracks)
+		if len(tracks) == 0 {
+			fmt.Println("💾 В кэше нет треков. Сохраните их с помощью команды 'cache pin'.")
+			return
+		}
+	}
 
 	if len(tracks) == 0 {
 		fmt.Println("📚 Библиотека пуста. Добавьте треки с помощью команды 'add'.")
@@ -35,6 +60,14 @@ func (app *Application) listTracks() {
 
 	fmt.Printf("📚 Найдено треков: %d\n\n", len(tracks))
 
+	if long {
+		for _, track := range tracks {
+			printTrackDetails(track)
+		}
+		fmt.Println("💡 Используйте 'snatcher play [ID]' для воспроизведения трека")
+		return
+	}
+
 	// Выводим заголовок таблицы
 	fmt.Printf("%-4s %-30s %-30s %-20s %-10s %-12s\n",
 		"ID", "Исполнитель", "Название", "Альбом", "Длительность", "Размер")
@@ -64,6 +97,37 @@ func (app *Application) listTracks() {
 	fmt.Println("💡 Используйте 'snatcher play [ID]' для воспроизведения трека")
 }
 
+// printTrackDetails выводит трек со всеми заполненными тегами
+func printTrackDetails(track data.TrackMetadata) {
+	fmt.Printf("#%d %s - %s\n", track.ID, track.Artist, track.Title)
+
+	printField := func(label, value string) {
+		if value != "" {
+			fmt.Printf("   %-14s %s\n", label+":", value)
+		}
+	}
+
+	duration := "N/A"
+	if track.Length > 0 {
+		duration = utils.FormatDurationFromSeconds(track.Length)
+	}
+	format := "MP3"
+	if track.Format != "" {
+		format = strings.ToUpper(track.Format)
+	}
+
+	printField("Альбом", track.Album)
+	printField("Теги", track.TagSummary())
+	printField("Комментарий", track.Comment)
+	printField("Обложка", track.ArtworkURL)
+	printField("Длительность", duration)
+	printField("Размер", uploader.FormatFileSize(track.FileSize))
+	printField("Формат", format)
+	printField("Источник", track.SourceURL)
+	printField("UID", track.UID)
+	fmt.Println()
+}
+
 // Функция для обрезки строки до указанной длины
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -13,14 +13,17 @@ import (
 )
 
 const (
-	defaultConfigPath   = "~/.snatcher"
-	defaultDataFilePath = "~/.snatcher_data"
+	defaultConfigPath    = "~/.snatcher"
+	defaultDataFilePath  = "~/.snatcher_data"
+	defaultLibraryDBPath = "~/.snatcher_library.db"
 )
 
 // Application содержит все зависимости приложения
 type Application struct {
-	Config *config.Config
-	Data   *data.AppData
+	Config  *config.Config
+	Data    *data.AppData // Файл данных: настройки плеера и треки при хранилище yaml
+	Library data.Store    // Хранилище библиотеки треков
+	Offline bool          // Работать только с треками из локального кэша
 }
 
 func main() {
@@ -42,6 +45,7 @@ func run() error {
 	if err := app.Initialize(); err != nil {
 		return err
 	}
+	defer app.Library.Close()
 
 	// Создаем корневую команду
 	rootCmd := app.createRootCommand(ctx)
@@ -69,9 +73,22 @@ func (app *Application) Initialize() error {
 		return fmt.Errorf("ошибка загрузки данных приложения: %w", err)
 	}
 
+	if app.Library, err = app.openLibrary(app.Config.LibraryBackend); err != nil {
+		return fmt.Errorf("ошибка открытия библиотеки: %w", err)
+	}
+
 	return nil
 }
 
+// openLibrary открывает хранилище библиотеки треков. Хранилище yaml - это
+// сам файл данных, поэтому его треки записываются вместе с SaveData.
+func (app *Application) openLibrary(backend string) (data.Store, error) {
+	if backend == config.BackendDB {
+		return data.OpenDB(defaultLibraryDBPath)
+	}
+	return app.Data, nil
+}
+
 // SaveData сохраняет данные приложения
 func (app *Application) SaveData() error {
 	return app.Data.SaveData(defaultDataFilePath)
@@ -6,32 +6,126 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
-	"strconv"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/player"
 	"github.com/hazadus/go-snatcher/internal/player/streaming"
 	"github.com/hazadus/go-snatcher/internal/utils"
 )
 
+// playOptions содержит параметры команды play
+type playOptions struct {
+	all     bool
+	shuffle bool
+	seed    int64
+	repeat  string
+	resume  bool
+	output  string
+}
+
 // createPlayCommand создает команду play с привязкой к экземпляру приложения
 func (app *Application) createPlayCommand(ctx context.Context) *cobra.Command {
-	return &cobra.Command{
-		Use:   "play [trackid]",
-		Short: "Play a track by its ID",
-		Long:  `Play an mp3 file by its track ID from the app data.`,
-		Args:  cobra.ExactArgs(1),
-		RunE: func(_ *cobra.Command, args []string) error {
-			trackID, err := strconv.Atoi(args[0])
+	opts := &playOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "play [trackid...]",
+		Short: "Play tracks by their IDs",
+		Long: `Play one or more tracks by their numeric IDs or UIDs from the app data.
+Tracks are played back to back as a queue; use --all to queue the whole library.`,
+		Args: func(_ *cobra.Command, args []string) error {
+			if len(args) == 0 && !opts.all {
+				return fmt.Errorf("укажите ID треков или флаг --all")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tracks, err := app.resolvePlayTracks(args, opts.all)
 			if err != nil {
-				return fmt.Errorf("неверный ID трека: %s", args[0])
+				return err
+			}
+			if opts.shuffle && !cmd.Flags().Changed("seed") {
+				opts.seed = time.Now().UnixNano()
 			}
-			return app.playByID(ctx, trackID)
+			return app.playTracks(ctx, tracks, opts)
 		},
 	}
+
+	cmd.Flags().BoolVar(&opts.all, "all", false, "play all tracks from the library")
+	cmd.Flags().BoolVar(&opts.shuffle, "shuffle", false, "shuffle the play queue")
+	cmd.Flags().Int64Var(&opts.seed, "seed", 0, "seed for reproducible shuffle order")
+	cmd.Flags().StringVar(&opts.repeat, "repeat", "off", "repeat mode: off, one or all")
+	cmd.Flags().BoolVar(&opts.resume, "resume", false, "resume tracks from saved positions without asking")
+	cmd.Flags().StringVar(&opts.output, "output", "speaker", "audio output: speaker, null, null:fast or wav:PATH")
+
+	return cmd
+}
+
+// resolvePlayTracks находит треки для воспроизведения по ID или берет всю библиотеку
+func (app *Application) resolvePlayTracks(args []string, all bool) ([]data.TrackMetadata, error) {
+	var tracks []data.TrackMetadata
+
+	if all {
+		library, err := app.Library.List()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения библиотеки: %w", err)
+		}
+		tracks = append(tracks, library...)
+	}
+
+	selected, err := app.tracksByIDs(args)
+	if err != nil {
+		return nil, err
+	}
+	tracks = append(tracks, selected...)
+
+	// Офлайн доступны только треки из кэша
+	if app.Offline {
+		if tracks, err = app.offlineTracks(tracks, all); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(tracks) == 0 {
+		if app.Offline {
+			return nil, fmt.Errorf("в кэше нет треков")
+		}
+		return nil, fmt.Errorf("библиотека пуста")
+	}
+
+	// Проверяем, что у треков есть URL
+	for _, track := range tracks {
+		if track.URL == "" {
+			return nil, fmt.Errorf("у трека с ID %d отсутствует URL", track.ID)
+		}
+	}
+
+	return tracks, nil
+}
+
+// offlineTracks оставляет только треки из кэша. Явно указанный трек,
+// которого нет в кэше, считается ошибкой; из всей библиотеки (--all)
+// такие треки просто пропускаются.
+func (app *Application) offlineTracks(tracks []data.TrackMetadata, all bool) ([]data.TrackMetadata, error) {
+	c, err := app.openCache()
+	if err != nil {
+		return nil, err
+	}
+
+	cached := cachedTracks(c, tracks)
+	if !all && len(cached) != len(tracks) {
+		for _, track := range tracks {
+			if track.URL == "" || !c.Has(track.URL) {
+				return nil, fmt.Errorf("трек с ID %d отсутствует в кэше (используйте 'snatcher cache pin %d')", track.ID, track.ID)
+			}
+		}
+	}
+	return cached, nil
 }
 
 // enableRawMode включает режим raw для терминала (без буферизации и echo)
@@ -56,24 +150,14 @@ func readSingleChar() (byte, error) {
 	return buffer[0], err
 }
 
-func (app *Application) playByID(ctx context.Context, trackID int) error {
-	// Находим трек по ID
-	track, err := app.Data.TrackByID(trackID)
-	if err != nil {
-		return fmt.Errorf("ошибка поиска трека: %w", err)
-	}
-
-	if track == nil {
-		return fmt.Errorf("трек с ID %d не найден", trackID)
-	}
-
-	// Проверяем, что у трека есть URL
-	if track.URL == "" {
-		return fmt.Errorf("у трека с ID %d отсутствует URL", trackID)
+// printTrackInfo выводит информацию о текущем треке
+func printTrackInfo(track data.TrackMetadata, position, total int) {
+	fmt.Printf("\r\033[K") // Очищаем строку прогресса
+	if total > 1 {
+		fmt.Printf("🎵 Сейчас играет (%d из %d):\n", position, total)
+	} else {
+		fmt.Printf("🎵 Сейчас играет:\n")
 	}
-
-	fmt.Printf("🎵 Воспроизводим трек ID %d: %s - %s\n", trackID, track.Artist, track.Title)
-	fmt.Printf("🎵 Сейчас играет:\n")
 	fmt.Printf("   ID: %d\n", track.ID)
 	fmt.Printf("   Исполнитель: %s\n", track.Artist)
 	fmt.Printf("   Название: %s\n", track.Title)
@@ -82,24 +166,96 @@ func (app *Application) playByID(ctx context.Context, trackID int) error {
 		duration := utils.FormatDuration(time.Duration(track.Length) * time.Second)
 		fmt.Printf("   Продолжительность: %s\n", duration)
 	}
+	if len(track.Chapters) > 0 {
+		fmt.Printf("   Главы: %d\n", len(track.Chapters))
+	}
 	fmt.Println()
+}
 
-	// Создаем плеер
-	p := player.NewPlayer()
-	defer p.Close()
+func (app *Application) playTracks(ctx context.Context, tracks []data.TrackMetadata, opts *playOptions) error {
+	repeat, err := player.ParseRepeatMode(opts.repeat)
+	if err != nil {
+		return err
+	}
 
-	// Запускаем воспроизведение
-	err = p.Play(track)
+	output, err := player.NewOutput(opts.output)
 	if err != nil {
-		return fmt.Errorf("ошибка запуска воспроизведения: %w", err)
+		return err
+	}
+	if opts.output != "speaker" {
+		fmt.Printf("🔈 Вывод звука: %s\n", opts.output)
+	}
+
+	// Создаем плеер с последней использованной громкостью
+	p := player.NewPlayer(output)
+	defer func() {
+		// Для WAV файла при закрытии дописывается заголовок
+		if err := p.Close(); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}()
+	if app.Data.Volume != nil {
+		p.SetVolume(*app.Data.Volume)
+	}
+	// Позиции треков сохраняются в главном цикле, как только изменились, а
+	// громкость и несохраненные позиции - при выходе из плеера
+	var positionsChanged atomic.Bool
+	positionChanged := make(chan struct{}, 1)
+	defer func() { app.savePlayerState(p.Volume(), positionsChanged.Load()) }()
+	p.SetCrossfade(time.Duration(app.Config.CrossfadeSeconds) * time.Second)
+
+	// Подключаем локальный кэш: без него треки просто воспроизводятся по сети
+	if c, err := app.openCache(); err == nil {
+		p.SetCache(c, app.Offline)
+	} else if app.Offline {
+		return err
+	} else {
+		fmt.Printf("⚠️  Кэш недоступен: %v\n", err)
+	}
+
+	// Запоминаем позицию каждого трека, когда он перестает играть
+	p.SetPositionHandler(func(track data.TrackMetadata, position, total time.Duration) {
+		if app.rememberPosition(track, position, total) {
+			positionsChanged.Store(true)
+			select {
+			case positionChanged <- struct{}{}:
+			default:
+			}
+		}
+	})
+	offer := &resumeOffer{}
+
+	// Формируем очередь воспроизведения
+	queue := p.Queue()
+	queue.Add(tracks...)
+	queue.SetRepeat(repeat)
+	if opts.shuffle {
+		queue.SetShuffle(true, opts.seed)
+		fmt.Printf("🔀 Очередь перемешана (зерно: %d)\n", opts.seed)
 	}
 
-	fmt.Printf("🌐 Начинаем потоковое воспроизведение...\n")
 	fmt.Printf("🎮 Управление:\n")
 	fmt.Printf("   [Пробел] - пауза/воспроизведение\n")
+	fmt.Printf("   [←/→]    - перемотка на 10 секунд\n")
+	fmt.Printf("   [ и ]    - перемотка на 1 минуту\n")
+	fmt.Printf("   [+/-]    - громкость\n")
+	fmt.Printf("   [m]      - выключить/включить звук\n")
+	if len(tracks) > 1 {
+		fmt.Printf("   [n/p]    - следующий/предыдущий трек\n")
+		fmt.Printf("   [s]      - перемешивание\n")
+	}
+	fmt.Printf("   [r]      - режим повтора\n")
+	fmt.Printf("   [< и >]  - предыдущая/следующая глава микса\n")
+	fmt.Printf("   [c]      - продолжить с сохраненной позиции\n")
 	fmt.Printf("   [Ctrl+C] - остановить и выйти\n")
 	fmt.Println()
 
+	// Запускаем воспроизведение
+	fmt.Printf("🌐 Начинаем потоковое воспроизведение...\n")
+	if err := p.PlayQueue(); err != nil {
+		return fmt.Errorf("ошибка запуска воспроизведения: %w", err)
+	}
+
 	// Включаем raw режим для чтения одиночных клавиш
 	enableRawMode()
 	defer disableRawMode()
@@ -116,8 +272,8 @@ func (app *Application) playByID(ctx context.Context, trackID int) error {
 				continue
 			}
 
-			// Проверяем на пробел (ASCII 32) или Enter (ASCII 10/13)
-			if char == 32 || char == 10 || char == 13 {
+			switch char {
+			case 32, 10, 13: // Пробел или Enter
 				p.Pause()
 				// Показываем новое состояние
 				fmt.Printf("\r\033[K") // Очищаем текущую строку
@@ -126,6 +282,59 @@ func (app *Application) playByID(ctx context.Context, trackID int) error {
 				} else {
 					fmt.Printf("⏸️  Пауза\n")
 				}
+			case 27: // Escape-последовательность стрелок: ESC [ C / ESC [ D
+				if next, err := readSingleChar(); err != nil || next != '[' {
+					continue
+				}
+				arrow, err := readSingleChar()
+				if err != nil {
+					continue
+				}
+				switch arrow {
+				case 'C':
+					skipAndReport(p, player.SkipStep)
+				case 'D':
+					skipAndReport(p, -player.SkipStep)
+				}
+			case '+', '=':
+				p.SetVolume(p.Volume() + player.VolumeStep)
+				reportVolume(p)
+			case '-':
+				p.SetVolume(p.Volume() - player.VolumeStep)
+				reportVolume(p)
+			case 'm':
+				p.ToggleMute()
+				reportVolume(p)
+			case 'n':
+				reportQueueError(p.Next())
+			case 'p':
+				reportQueueError(p.Previous())
+			case 'r':
+				mode := queue.CycleRepeat()
+				fmt.Printf("\r\033[K🔁 Повтор: %s\n", mode)
+			case 's':
+				shuffle := !queue.Shuffle()
+				if opts.seed == 0 {
+					opts.seed = time.Now().UnixNano()
+				}
+				queue.SetShuffle(shuffle, opts.seed)
+				if shuffle {
+					fmt.Printf("\r\033[K🔀 Перемешивание включено (зерно: %d)\n", opts.seed)
+				} else {
+					fmt.Printf("\r\033[K➡️  Перемешивание выключено\n")
+				}
+			case 'c':
+				if position, ok := offer.take(); ok {
+					resumeAndReport(p, position)
+				}
+			case '[':
+				skipAndReport(p, -player.LongSkipStep)
+			case ']':
+				skipAndReport(p, player.LongSkipStep)
+			case '<':
+				chapterAndReport(p.PreviousChapter())
+			case '>':
+				chapterAndReport(p.NextChapter())
 			}
 		}
 	}()
@@ -135,7 +344,14 @@ func (app *Application) playByID(ctx context.Context, trackID int) error {
 		select {
 		case status := <-p.Progress():
 			// Обновляем прогресс
-			displayProgress(status)
+			displayProgress(status, p.CurrentTrack())
+		case track := <-p.TrackChanged():
+			printTrackInfo(track, queue.Position(), queue.Len())
+			app.offerResume(p, offer, track, opts.resume)
+		case <-positionChanged:
+			if positionsChanged.Swap(false) {
+				app.savePositions()
+			}
 		case <-p.Done():
 			fmt.Println("\n✅ Потоковое воспроизведение завершено")
 			return nil
@@ -151,8 +367,146 @@ func (app *Application) playByID(ctx context.Context, trackID int) error {
 	}
 }
 
-// displayProgress отображает прогресс воспроизведения
-func displayProgress(status player.Status) {
+// resumeOffer хранит предложение продолжить текущий трек с сохраненной
+// позиции. Предложение принимается клавишей в отдельной горутине.
+type resumeOffer struct {
+	mutex    sync.Mutex
+	position time.Duration
+}
+
+// set заменяет предложение; нулевая позиция отменяет его
+func (o *resumeOffer) set(position time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.position = position
+}
+
+// take возвращает позицию из предложения и отменяет его
+func (o *resumeOffer) take() (time.Duration, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	position := o.position
+	o.position = 0
+	return position, position > 0
+}
+
+// offerResume предлагает продолжить трек с сохраненной позиции или,
+// если указан флаг --resume, сразу перематывает на нее
+func (app *Application) offerResume(p *player.Player, offer *resumeOffer, track data.TrackMetadata, auto bool) {
+	position := time.Duration(app.Data.Position(track.ID)) * time.Second
+	if position == 0 {
+		offer.set(0)
+		return
+	}
+
+	if auto {
+		offer.set(0)
+		resumeAndReport(p, position)
+		return
+	}
+
+	offer.set(position)
+	fmt.Printf("⏯️  Продолжить с %s? Нажмите [c]\n", utils.FormatDuration(position))
+}
+
+// resumeAndReport перематывает трек на сохраненную позицию и сообщает о результате
+func resumeAndReport(p *player.Player, position time.Duration) {
+	fmt.Printf("\r\033[K") // Очищаем текущую строку
+	if err := p.Seek(position); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return
+	}
+	fmt.Printf("⏯️  Продолжаем с %s\n", utils.FormatDuration(position))
+}
+
+// rememberPosition запоминает в памяти позицию остановленного трека и
+// возвращает true, если она изменилась. Слишком ранние позиции и
+// дослушанные треки не запоминаются. Плеер вызывает обработчик под своим
+// мьютексом, поэтому файл данных записывается не здесь, а в главном цикле
+// playTracks.
+func (app *Application) rememberPosition(track data.TrackMetadata, position, total time.Duration) bool {
+	seconds := int(player.ResumePosition(position, total) / time.Second)
+	return app.Data.SetPosition(track.ID, seconds)
+}
+
+// skipAndReport перематывает трек и сообщает о результате
+func skipAndReport(p *player.Player, delta time.Duration) {
+	fmt.Printf("\r\033[K") // Очищаем текущую строку
+	if err := p.Skip(delta); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return
+	}
+	if delta > 0 {
+		fmt.Printf("⏩ Вперед на %s\n", utils.FormatDuration(delta))
+	} else {
+		fmt.Printf("⏪ Назад на %s\n", utils.FormatDuration(-delta))
+	}
+}
+
+// chapterAndReport сообщает о переходе к главе микса
+func chapterAndReport(chapter data.Chapter, err error) {
+	fmt.Printf("\r\033[K") // Очищаем текущую строку
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return
+	}
+	fmt.Printf("📑 %s %s\n", utils.FormatDuration(time.Duration(chapter.Start)*time.Second), chapter)
+}
+
+// reportQueueError сообщает об ошибке перехода по очереди
+func reportQueueError(err error) {
+	if err != nil {
+		fmt.Printf("\r\033[K⚠️  %v\n", err)
+	}
+}
+
+// reportVolume сообщает о текущей громкости
+func reportVolume(p *player.Player) {
+	fmt.Printf("\r\033[K") // Очищаем текущую строку
+	fmt.Printf("%s\n", formatVolume(p.Volume(), p.IsMuted()))
+}
+
+// formatVolume форматирует громкость для вывода
+func formatVolume(level int, muted bool) string {
+	if muted {
+		return fmt.Sprintf("🔇 %d%% (звук выключен)", level)
+	}
+	return fmt.Sprintf("🔊 %d%%", level)
+}
+
+// savePlayerState сохраняет громкость плеера и позиции треков в файл
+// данных, если они изменились
+func (app *Application) savePlayerState(volume int, positionsChanged bool) {
+	if !app.Data.SetVolume(volume) && !positionsChanged {
+		return
+	}
+	if err := app.SaveData(); err != nil {
+		fmt.Printf("\n⚠️  Не удалось сохранить громкость и позиции треков: %v\n", err)
+	}
+}
+
+// savePositions сохраняет позиции треков в файл данных
+func (app *Application) savePositions() {
+	if err := app.SaveData(); err != nil {
+		fmt.Printf("\r\033[K⚠️  Не удалось сохранить позицию трека: %v\n", err)
+	}
+}
+
+// formatChapter возвращает текущую главу микса для строки прогресса
+// (например, " | 📑 3/12 Artist - Title") или пустую строку
+func formatChapter(track *data.TrackMetadata, position time.Duration) string {
+	if track == nil {
+		return ""
+	}
+	index := track.ChapterAt(position)
+	if index < 0 {
+		return ""
+	}
+	return fmt.Sprintf(" | 📑 %d/%d %s", index+1, len(track.Chapters), utils.TruncateString(track.Chapters[index].String(), 40))
+}
+
+// displayProgress отображает прогресс воспроизведения и текущую главу микса
+func displayProgress(status player.Status, track *data.TrackMetadata) {
 	// Определяем процент завершения
 	var progress string
 	if status.Total > 0 {
@@ -166,7 +520,10 @@ func displayProgress(status player.Status) {
 	statusIcon := "⏱️"
 	statusText := streaming.GetStreamStatus(status.StuckCount)
 
-	if !status.IsPlaying {
+	if status.Reconnecting() {
+		statusIcon = "🔄"
+		statusText = streaming.GetReconnectStatus(status.ReconnectAttempt)
+	} else if !status.IsPlaying {
 		statusIcon = "⏸️"
 		statusText = "На паузе"
 	} else if status.StuckCount > 3 {
@@ -175,32 +532,45 @@ func displayProgress(status player.Status) {
 		statusIcon = "✅"
 	}
 
+	volume := formatVolume(status.Volume, status.Muted)
+	chapter := formatChapter(track, status.Current)
+
 	// Отображаем прогресс
 	if status.Total > 0 {
 		if !status.IsPlaying {
-			fmt.Printf("\r%s  %s | %s / %s | Статус: %s",
+			fmt.Printf("\r%s  %s | %s / %s | %s | Статус: %s%s",
 				statusIcon,
 				progress,
 				utils.FormatDuration(status.Current),
 				utils.FormatDuration(status.Total),
-				statusText)
+				volume,
+				statusText,
+				chapter)
 		} else {
-			fmt.Printf("\r%s  %s | %s / %s | Скорость: %.2fx | Статус: %s",
+			fmt.Printf("\r%s  %s | %s / %s | %s | Скорость: %.2fx | Статус: %s%s",
 				statusIcon,
 				progress,
 				utils.FormatDuration(status.Current),
 				utils.FormatDuration(status.Total),
+				volume,
 				status.Speed,
-				statusText)
+				statusText,
+				chapter)
 		}
 	} else {
 		if !status.IsPlaying {
-			fmt.Printf("\r⏸️  %s | Статус: На паузе | Потоковое воспроизведение",
-				utils.FormatDuration(status.Current))
+			fmt.Printf("\r⏸️  %s | %s | Статус: На паузе | Потоковое воспроизведение%s",
+				utils.FormatDuration(status.Current),
+				volume,
+				chapter)
 		} else {
-			fmt.Printf("\r⏱️  %s | Скорость: %.2fx | Потоковое воспроизведение",
+			fmt.Printf("\r%s  %s | %s | Скорость: %.2fx | %s%s",
+				statusIcon,
 				utils.FormatDuration(status.Current),
-				status.Speed)
+				volume,
+				status.Speed,
+				statusText,
+				chapter)
 		}
 	}
 }
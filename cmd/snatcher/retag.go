@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazadus/go-snatcher/internal/audio"
+	"github.com/hazadus/go-snatcher/internal/cache"
+	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/metadata/id3"
+	"github.com/hazadus/go-snatcher/internal/uploader"
+)
+
+// createRetagCommand создает команду retag с привязкой к экземпляру приложения
+func (app *Application) createRetagCommand(ctx context.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "retag [id]",
+		Short: "Write library metadata into the track's ID3 tags",
+		Long: `Download the track from S3, rewrite its ID3v2.4 tags with the metadata from the library
+and upload it back. The track is given by its numeric ID or UID. Audio frames are preserved byte-for-byte. Only MP3 files are supported.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			track, err := data.FindTrack(app.Library, args[0])
+			if err != nil {
+				return err
+			}
+
+			// Трек скачивается и загружается целиком, как при команде add
+			retagCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+			defer cancel()
+			return app.retagTrack(retagCtx, track.ID)
+		},
+	}
+}
+
+// retagTrack записывает метаданные трека из библиотеки в его файл в S3
+func (app *Application) retagTrack(ctx context.Context, id int) error {
+	track, err := app.Library.Get(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🏷️  Записываем теги в файл: %s - %s\n", track.Artist, track.Title)
+	if err := app.writeTrackTags(ctx, track); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Теги записаны, файл загружен в S3")
+	return nil
+}
+
+// writeTrackTags скачивает файл трека (или берет его из кэша), записывает в
+// него теги ID3v2.4 со значениями из track и загружает файл обратно под
+// прежним ключом. Копия в кэше заменяется новой, размер и хеш файла в
+// библиотеке обновляются. Файл, на который ссылаются и другие треки (см.
+// add --duplicate link), не перезаписывается: теги изменились бы у всех.
+func (app *Application) writeTrackTags(ctx context.Context, track data.TrackMetadata) error {
+	format, err := audio.Parse(track.Format)
+	if err != nil {
+		return err
+	}
+	if format != audio.FormatMP3 {
+		return fmt.Errorf("запись тегов поддерживается только для MP3, формат трека: %s", format)
+	}
+	if track.URL == "" {
+		return fmt.Errorf("у трека нет URL в хранилище")
+	}
+	shared, err := app.sharedFiles(track)
+	if err != nil {
+		return err
+	}
+	if shared[track.URL] {
+		return fmt.Errorf("файл трека используется и другими треками библиотеки, теги не записаны")
+	}
+
+	key, err := extractKeyFromURL(track.URL)
+	if err != nil {
+		return fmt.Errorf("ошибка извлечения ключа из URL: %w", err)
+	}
+
+	// Кэш не обязателен: без него файл скачивается из хранилища
+	c, err := app.openCache()
+	if err != nil {
+		c = nil
+	}
+
+	tagged, err := retagFile(ctx, track, c)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tagged.Name())
+	defer tagged.Close()
+
+	s3Uploader, err := app.newS3Uploader()
+	if err != nil {
+		return err
+	}
+	if _, err := s3Uploader.UploadFile(ctx, tagged, key, format.ContentType()); err != nil {
+		return fmt.Errorf("ошибка загрузки в S3: %w", err)
+	}
+
+	if c != nil && c.Has(track.URL) {
+		if _, err := tagged.Seek(0, io.SeekStart); err == nil {
+			// Не критично: старая копия лишь останется с прежними тегами
+			_ = c.Store(track.URL, tagged)
+		}
+	}
+
+	info, err := tagged.Stat()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+	sum, err := uploader.HashFile(tagged.Name(), nil)
+	if err != nil {
+		return err
+	}
+	if stored, err := app.Library.Get(track.ID); err == nil {
+		stored.FileSize, stored.SHA256 = info.Size(), sum
+		if err := app.Library.Put(&stored); err != nil {
+			return fmt.Errorf("ошибка обновления трека: %w", err)
+		}
+	}
+	if err := app.SaveData(); err != nil {
+		return fmt.Errorf("ошибка сохранения данных: %w", err)
+	}
+	return nil
+}
+
+// retagFile записывает копию файла трека с новыми тегами во временный файл
+// и возвращает его открытым на начале. Исходный файл берется из кэша, если
+// он там есть, иначе скачивается по URL.
+func retagFile(ctx context.Context, track data.TrackMetadata, c *cache.Cache) (*os.File, error) {
+	var source *os.File
+	var size int64
+	if c != nil && c.Has(track.URL) {
+		cached, err := c.Open(track.URL)
+		if err != nil {
+			return nil, err
+		}
+		source, size = cached.File, cached.Size()
+	} else {
+		downloaded, err := downloadToTemp(ctx, track.URL)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(downloaded.Name())
+		info, err := downloaded.Stat()
+		if err != nil {
+			downloaded.Close()
+			return nil, fmt.Errorf("ошибка чтения файла: %w", err)
+		}
+		source, size = downloaded, info.Size()
+	}
+	defer source.Close()
+
+	tagged, err := os.CreateTemp("", "snatcher-retag-*.mp3")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания временного файла: %w", err)
+	}
+
+	tag := id3.Tag{
+		Artist:      track.Artist,
+		Title:       track.Title,
+		Album:       track.Album,
+		Year:        track.Year,
+		Genre:       track.Genre,
+		Comment:     track.Comment,
+		TrackNumber: track.TrackNumber,
+		BPM:         track.BPM,
+		Key:         track.Key,
+	}
+	if err := id3.Rewrite(tagged, source, size, tag); err != nil {
+		tagged.Close()
+		os.Remove(tagged.Name())
+		return nil, fmt.Errorf("ошибка записи тегов: %w", err)
+	}
+	if _, err := tagged.Seek(0, io.SeekStart); err != nil {
+		tagged.Close()
+		os.Remove(tagged.Name())
+		return nil, fmt.Errorf("ошибка чтения временного файла: %w", err)
+	}
+	return tagged, nil
+}
+
+// downloadToTemp скачивает файл по URL во временный файл
+func downloadToTemp(ctx context.Context, url string) (*os.File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка скачивания трека: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка скачивания трека: сервер вернул статус %s", resp.Status)
+	}
+
+	file, err := os.CreateTemp("", "snatcher-download-*.mp3")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания временного файла: %w", err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("ошибка скачивания трека: %w", err)
+	}
+	return file, nil
+}
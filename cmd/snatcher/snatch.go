@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/metadata"
+	"github.com/hazadus/go-snatcher/internal/uploader"
+)
+
+// snatchOptions содержит параметры команды snatch
+type snatchOptions struct {
+	deleteLocal bool
+}
+
+// snatchState - состояние команды snatch. Оно сохраняется в папке загрузок
+// после каждого этапа, чтобы повторный запуск с тем же URL продолжил работу
+// с прерванного места, а не скачивал и не загружал файл заново.
+type snatchState struct {
+	SourceURL  string `yaml:"source_url"`
+	FilePath   string `yaml:"file_path,omitempty"`   // Скачанный файл (после окончания скачивания)
+	URL        string `yaml:"url,omitempty"`         // URL файла в S3 (после окончания загрузки)
+	ArtworkURL string `yaml:"artwork_url,omitempty"` // URL обложки в S3
+	TrackID    int    `yaml:"track_id,omitempty"`    // ID трека в библиотеке (после добавления)
+}
+
+// createSnatchCommand создает команду snatch с привязкой к экземпляру приложения
+func (app *Application) createSnatchCommand(ctx context.Context) *cobra.Command {
+	opts := &snatchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "snatch [YouTube URL]",
+		Short: "Download audio from YouTube and add it to the library in one step",
+		Long: `Download audio from a YouTube video, extract its metadata, upload it to S3 storage and add it
+to the library with the video URL as its source. If any stage fails, run the command again
+with the same URL to continue from where it stopped.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.snatch(ctx, args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.deleteLocal, "delete", false, "delete the downloaded file after adding it to the library")
+
+	return cmd
+}
+
+// snatch скачивает аудио из видео, загружает его в S3 и добавляет в библиотеку
+func (app *Application) snatch(ctx context.Context, url string, opts *snatchOptions) error {
+	videoID, err := extractVideoID(url)
+	if err != nil {
+		return fmt.Errorf("ошибка извлечения ID видео: %w", err)
+	}
+
+	statePath := snatchStatePath(app.Config.DownloadDir, videoID)
+	state, resumed, err := loadSnatchState(statePath)
+	if err != nil {
+		return err
+	}
+	state.SourceURL = youtubeVideoURL(videoID)
+
+	// Трек из этого источника уже в библиотеке: повторять нечего, если только
+	// прошлый запуск не прервался после добавления трека
+	existing, err := app.Library.Query(data.Query{SourceURL: state.SourceURL})
+	if err != nil {
+		return fmt.Errorf("ошибка поиска в библиотеке: %w", err)
+	}
+	if len(existing) > 0 {
+		if !resumed {
+			fmt.Printf("✅ Трек уже в библиотеке: #%d %s - %s\n", existing[0].ID, existing[0].Artist, existing[0].Title)
+			return nil
+		}
+		state.TrackID = existing[0].ID
+	}
+	if resumed {
+		fmt.Printf("⏯️  Продолжаем прерванную операцию для видео ID: %s\n", videoID)
+	}
+
+	// Этап 1: скачивание
+	if state.FilePath == "" || !fileExists(state.FilePath) {
+		downloadCtx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+		filePath, err := app.downloadYouTubeAudio(downloadCtx, url)
+		cancel()
+		if err != nil {
+			return err
+		}
+		// Новый файл нужно загрузить заново
+		state.FilePath, state.URL, state.ArtworkURL = filePath, "", ""
+		if err := saveSnatchState(statePath, state); err != nil {
+			return err
+		}
+	} else if state.TrackID == 0 {
+		fmt.Printf("⏭️  Файл уже скачан: %s\n", state.FilePath)
+	}
+
+	// Прошлый запуск мог прерваться до перекодирования скачанного файла в
+	// формат, который декодирует плеер
+	if state.URL == "" && state.TrackID == 0 {
+		filePath, err := ensurePlayable(ctx, state.FilePath)
+		if err != nil {
+			return err
+		}
+		if filePath != state.FilePath {
+			state.FilePath = filePath
+			if err := saveSnatchState(statePath, state); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Этап 2: загрузка в S3
+	if state.TrackID == 0 {
+		if err := app.snatchUpload(ctx, statePath, state); err != nil {
+			return err
+		}
+	}
+
+	// Этап 3: удаление локальных файлов
+	if opts.deleteLocal {
+		removeDownload(state.FilePath)
+		fmt.Printf("🗑️  Локальный файл удален: %s\n", state.FilePath)
+	}
+
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("⚠️  Предупреждение: не удалось удалить файл состояния: %v\n", err)
+	}
+
+	track, err := app.Library.Get(state.TrackID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n🎉 Трек добавлен в библиотеку: #%d %s - %s\n", track.ID, track.Artist, track.Title)
+	return nil
+}
+
+// snatchUpload загружает скачанный файл в S3, если это еще не сделано, и
+// добавляет трек в библиотеку, сохраняя состояние после каждого шага
+func (app *Application) snatchUpload(ctx context.Context, statePath string, state *snatchState) error {
+	s3Uploader, err := app.newS3Uploader()
+	if err != nil {
+		return err
+	}
+	uploadService := uploader.NewService(s3Uploader, metadata.NewExtractor(), app.Library)
+
+	var result *uploader.UploadResult
+	if state.URL == "" {
+		uploadCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		result, err = app.uploadWithProgress(uploadCtx, uploadService, state.FilePath, "")
+		cancel()
+		if err != nil {
+			return err
+		}
+		state.URL = result.URL
+		if result.ArtworkErr == nil {
+			state.ArtworkURL = result.ArtworkURL
+		}
+		if err := saveSnatchState(statePath, state); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("⏭️  Файл уже загружен в S3: %s\n", state.URL)
+		if result, err = uploadService.UploadedResult(state.FilePath, state.URL, state.ArtworkURL); err != nil {
+			return err
+		}
+	}
+
+	// URL источника известен, даже если сведения о видео не сохранились
+	if result.Metadata.SourceURL == "" {
+		result.Metadata.SourceURL = state.SourceURL
+	}
+	if err := uploadService.UpdateApplicationData(result); err != nil {
+		return fmt.Errorf("ошибка обновления данных приложения: %w", err)
+	}
+	if err := app.SaveData(); err != nil {
+		return fmt.Errorf("ошибка сохранения данных: %w", err)
+	}
+	added, err := app.Library.Query(data.Query{SourceURL: result.Metadata.SourceURL})
+	if err != nil {
+		return fmt.Errorf("ошибка поиска в библиотеке: %w", err)
+	}
+	if len(added) == 0 {
+		return fmt.Errorf("трек не найден в библиотеке после добавления")
+	}
+	state.TrackID = added[len(added)-1].ID
+	return saveSnatchState(statePath, state)
+}
+
+// snatchStatePath возвращает путь к файлу состояния команды snatch для видео
+func snatchStatePath(downloadDir, videoID string) string {
+	return filepath.Join(downloadDir, ".snatch-"+videoID+".yaml")
+}
+
+// loadSnatchState загружает состояние прерванной операции. Возвращает
+// пустое состояние и false, если файла состояния нет.
+func loadSnatchState(path string) (*snatchState, bool, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &snatchState{}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка чтения файла состояния: %w", err)
+	}
+	state := &snatchState{}
+	if err := yaml.Unmarshal(content, state); err != nil {
+		return nil, false, fmt.Errorf("ошибка разбора файла состояния %s: %w", path, err)
+	}
+	return state, true, nil
+}
+
+// saveSnatchState сохраняет состояние операции
+func saveSnatchState(path string, state *snatchState) error {
+	content, err := yaml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации состояния: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("ошибка записи файла состояния: %w", err)
+	}
+	return nil
+}
+
+// removeDownload удаляет скачанный аудиофайл вместе с обложкой и
+// сведениями об источнике рядом с ним
+func removeDownload(audioPath string) {
+	paths := []string{
+		audioPath,
+		metadata.SourceInfoPath(audioPath),
+		metadata.ArtworkPath(audioPath, "jpg"),
+		metadata.ArtworkPath(audioPath, "png"),
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("⚠️  Предупреждение: %v\n", err)
+		}
+	}
+}
+
+// fileExists проверяет, что файл существует
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/hazadus/go-snatcher/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -19,7 +22,20 @@ func (app *Application) createTUICommand() *cobra.Command {
 
 func (app *Application) launchTUI() {
 	// Создаем экземпляр TUI приложения
-	tuiApp := tui.NewApp(app.Data, app.SaveData)
+	tuiApp := tui.NewApp(app.Data, app.Library, app.SaveData)
+	tuiApp.SetCrossfade(time.Duration(app.Config.CrossfadeSeconds) * time.Second)
+	tuiApp.SetRetagFunc(app.writeTrackTags)
+
+	// Подключаем локальный кэш; офлайн без него работать нельзя
+	c, err := app.openCache()
+	if err != nil {
+		if app.Offline {
+			panic(err)
+		}
+		fmt.Printf("⚠️  Кэш недоступен: %v\n", err)
+	} else {
+		tuiApp.SetCache(c, app.Offline)
+	}
 
 	// Запускаем TUI
 	if err := tuiApp.Run(); err != nil {
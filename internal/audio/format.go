@@ -0,0 +1,195 @@
+// Package audio определяет формат аудиофайлов и выбирает для них декодер
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/flac"
+	"github.com/gopxl/beep/mp3"
+	"github.com/gopxl/beep/vorbis"
+	"github.com/gopxl/beep/wav"
+)
+
+// Format представляет контейнер аудиофайла
+type Format string
+
+// Поддерживаемые форматы
+const (
+	FormatMP3  Format = "mp3"
+	FormatFLAC Format = "flac"
+	FormatOgg  Format = "ogg"
+	FormatWAV  Format = "wav"
+)
+
+// HeaderSize - количество байтов начала файла, достаточное для Sniff
+const HeaderSize = 12
+
+// Formats возвращает все поддерживаемые форматы
+func Formats() []Format {
+	return []Format{FormatMP3, FormatFLAC, FormatOgg, FormatWAV}
+}
+
+// Ext возвращает расширение файла для формата (с точкой)
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// ContentType возвращает MIME-тип формата
+func (f Format) ContentType() string {
+	switch f {
+	case FormatFLAC:
+		return "audio/flac"
+	case FormatOgg:
+		return "audio/ogg"
+	case FormatWAV:
+		return "audio/wav"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+// Parse возвращает формат по его названию. Пустое название означает MP3:
+// так хранятся треки, добавленные до поддержки других форматов.
+func Parse(name string) (Format, error) {
+	if name == "" {
+		return FormatMP3, nil
+	}
+	for _, format := range Formats() {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("неподдерживаемый формат аудио: %s", name)
+}
+
+// FromExt определяет формат по расширению имени файла или пути URL
+func FromExt(name string) (Format, bool) {
+	// Отбрасываем параметры запроса, если передан URL
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".mp3":
+		return FormatMP3, true
+	case ".flac":
+		return FormatFLAC, true
+	case ".ogg", ".oga":
+		return FormatOgg, true
+	case ".wav", ".wave":
+		return FormatWAV, true
+	}
+	return "", false
+}
+
+// FromContentType определяет формат по заголовку Content-Type
+func FromContentType(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "audio/mpeg", "audio/mp3", "audio/mpeg3":
+		return FormatMP3, true
+	case "audio/flac", "audio/x-flac":
+		return FormatFLAC, true
+	case "audio/ogg", "audio/vorbis", "application/ogg":
+		return FormatOgg, true
+	case "audio/wav", "audio/wave", "audio/x-wav", "audio/vnd.wave":
+		return FormatWAV, true
+	}
+	return "", false
+}
+
+// Sniff определяет формат по сигнатуре в начале файла
+func Sniff(header []byte) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return FormatFLAC, true
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return FormatOgg, true
+	case len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WAVE":
+		return FormatWAV, true
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return FormatMP3, true
+	case len(header) >= 2 && header[0] == 0xff && header[1]&0xe0 == 0xe0:
+		return FormatMP3, true // Синхрослово фрейма MPEG без тега
+	}
+	return "", false
+}
+
+// SniffUnsupported определяет по сигнатуре контейнеры, которые плеер не
+// умеет декодировать (например, аудио YouTube в MP4 и WebM), и возвращает
+// название контейнера
+func SniffUnsupported(header []byte) (string, bool) {
+	switch {
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		return "MP4", true
+	case bytes.HasPrefix(header, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return "WebM", true // Заголовок EBML контейнеров Matroska и WebM
+	}
+	return "", false
+}
+
+// Detect определяет формат файла. Сигнатура надежнее всего, затем
+// проверяются Content-Type и расширение; если ничего не подошло,
+// файл считается MP3.
+func Detect(header []byte, contentType, name string) Format {
+	if format, ok := Sniff(header); ok {
+		return format
+	}
+	if format, ok := FromContentType(contentType); ok {
+		return format
+	}
+	if format, ok := FromExt(name); ok {
+		return format
+	}
+	return FormatMP3
+}
+
+// Decode декодирует поток указанного формата. Если rc реализует io.Seeker,
+// декодер поддерживает перемотку. Закрытие возвращенного потока закрывает rc,
+// при ошибке rc закрывается сразу.
+func Decode(format Format, rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	var (
+		streamer beep.StreamSeekCloser
+		decoded  beep.Format
+		err      error
+	)
+	switch format {
+	case FormatMP3:
+		streamer, decoded, err = mp3.Decode(rc)
+	case FormatFLAC:
+		streamer, decoded, err = flac.Decode(rc)
+	case FormatOgg:
+		streamer, decoded, err = vorbis.Decode(rc)
+	case FormatWAV:
+		streamer, decoded, err = wav.Decode(rc)
+	default:
+		rc.Close()
+		return nil, beep.Format{}, fmt.Errorf("неподдерживаемый формат аудио: %s", format)
+	}
+	if err != nil {
+		rc.Close()
+		return nil, beep.Format{}, fmt.Errorf("ошибка декодирования %s: %w", strings.ToUpper(string(format)), err)
+	}
+	return streamer, decoded, nil
+}
+
+// ReadHeader читает начало файла для Sniff и возвращает ридер на начало
+func ReadHeader(r io.ReadSeeker) ([]byte, error) {
+	header := make([]byte, HeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return header[:n], nil
+}
@@ -0,0 +1,154 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/generators"
+	"github.com/gopxl/beep/wav"
+)
+
+func TestSniff(t *testing.T) {
+	testCases := []struct {
+		header []byte
+		format Format
+	}{
+		{[]byte("fLaC\x00\x00\x00\x22"), FormatFLAC},
+		{[]byte("OggS\x00\x02"), FormatOgg},
+		{[]byte("RIFF\x24\x08\x00\x00WAVEfmt "), FormatWAV},
+		{[]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), FormatMP3},
+		{[]byte{0xff, 0xfb, 0x90, 0x64}, FormatMP3},
+	}
+	for _, tc := range testCases {
+		format, ok := Sniff(tc.header)
+		if !ok || format != tc.format {
+			t.Errorf("%q: ожидался формат %s, получено %s (%v)", tc.header, tc.format, format, ok)
+		}
+	}
+
+	for _, header := range [][]byte{nil, []byte("RIFF\x24\x08\x00\x00AVI "), []byte("<html>")} {
+		if format, ok := Sniff(header); ok {
+			t.Errorf("%q: формат не должен определяться, получено %s", header, format)
+		}
+	}
+}
+
+func TestSniffUnsupported(t *testing.T) {
+	testCases := []struct {
+		header    []byte
+		container string
+	}{
+		{[]byte("\x00\x00\x00\x18ftypdash"), "MP4"},
+		{[]byte("\x00\x00\x00\x20ftypM4A "), "MP4"},
+		{[]byte{0x1a, 0x45, 0xdf, 0xa3, 0x9f}, "WebM"},
+	}
+	for _, tc := range testCases {
+		container, ok := SniffUnsupported(tc.header)
+		if !ok || container != tc.container {
+			t.Errorf("%q: ожидался контейнер %s, получено %s (%v)", tc.header, tc.container, container, ok)
+		}
+		if format, ok := Sniff(tc.header); ok {
+			t.Errorf("%q: формат не должен определяться, получено %s", tc.header, format)
+		}
+	}
+	if container, ok := SniffUnsupported([]byte("ID3\x04\x00")); ok {
+		t.Errorf("MP3 не должен считаться неподдерживаемым, получено %s", container)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		name        string
+		header      []byte
+		contentType string
+		path        string
+		format      Format
+	}{
+		{"сигнатура важнее расширения", []byte("fLaC"), "audio/mpeg", "mix.mp3", FormatFLAC},
+		{"Content-Type", nil, "audio/ogg; codecs=vorbis", "mix", FormatOgg},
+		{"расширение URL", nil, "binary/octet-stream", "https://bucket.s3.amazonaws.com/Mix.FLAC?x=1", FormatFLAC},
+		{"расширение файла", []byte("garbage"), "", "/tmp/set.wav", FormatWAV},
+		{"по умолчанию MP3", nil, "", "https://example.com/track", FormatMP3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if format := Detect(tc.header, tc.contentType, tc.path); format != tc.format {
+				t.Errorf("Ожидался формат %s, получено %s", tc.format, format)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	if format, err := Parse(""); err != nil || format != FormatMP3 {
+		t.Errorf("Пустой формат должен означать MP3, получено %s, %v", format, err)
+	}
+	if format, err := Parse("FLAC"); err != nil || format != FormatFLAC {
+		t.Errorf("Ожидался FLAC, получено %s, %v", format, err)
+	}
+	if _, err := Parse("aac"); err == nil {
+		t.Error("Ожидалась ошибка для неподдерживаемого формата")
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Ошибка создания файла: %v", err)
+	}
+	format := beep.Format{SampleRate: 8000, NumChannels: 2, Precision: 2}
+	tone, err := generators.SineTone(format.SampleRate, 440)
+	if err != nil {
+		t.Fatalf("Ошибка создания тона: %v", err)
+	}
+	if err := wav.Encode(file, beep.Take(8000, tone), format); err != nil {
+		t.Fatalf("Ошибка записи WAV: %v", err)
+	}
+	file.Close()
+
+	file, err = os.Open(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия файла: %v", err)
+	}
+	header, err := ReadHeader(file)
+	if err != nil {
+		t.Fatalf("Ошибка чтения заголовка: %v", err)
+	}
+	detected := Detect(header, "", path)
+	if detected != FormatWAV {
+		t.Fatalf("Ожидался формат WAV, получено %s", detected)
+	}
+
+	streamer, decoded, err := Decode(detected, file)
+	if err != nil {
+		t.Fatalf("Ошибка декодирования: %v", err)
+	}
+	defer streamer.Close()
+
+	if decoded.SampleRate != format.SampleRate {
+		t.Errorf("Ожидалась частота %d, получено %d", format.SampleRate, decoded.SampleRate)
+	}
+	if streamer.Len() != 8000 {
+		t.Errorf("Ожидалось 8000 сэмплов, получено %d", streamer.Len())
+	}
+	if err := streamer.Seek(4000); err != nil {
+		t.Errorf("Ошибка перемотки: %v", err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, format := range Formats() {
+		rc := io.NopCloser(bytes.NewReader([]byte("not audio at all")))
+		if _, _, err := Decode(format, rc); err == nil {
+			t.Errorf("%s: ожидалась ошибка для некорректных данных", format)
+		}
+	}
+	if _, _, err := Decode("aac", io.NopCloser(bytes.NewReader(nil))); err == nil {
+		t.Error("Ожидалась ошибка для неподдерживаемого формата")
+	}
+}
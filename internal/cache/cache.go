@@ -0,0 +1,341 @@
+// Package cache содержит локальный дисковый кэш аудиофайлов треков
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	audioExt = ".mp3" // Расширение полностью скачанных файлов
+	pinExt   = ".pin" // Расширение файлов-меток закрепленных треков
+	partExt  = ".part"
+)
+
+// Cache хранит скачанные треки на диске. Файлы адресуются хешем URL трека,
+// общий размер ограничен: при превышении удаляются файлы, которые дольше
+// всего не воспроизводились. Закрепленные файлы не удаляются.
+type Cache struct {
+	dir     string
+	maxSize int64
+	mutex   sync.Mutex
+}
+
+// Stats содержит сведения о содержимом кэша
+type Stats struct {
+	Files   int   // Количество файлов в кэше
+	Pinned  int   // Количество закрепленных файлов
+	Size    int64 // Общий размер файлов в байтах
+	MaxSize int64 // Ограничение размера в байтах
+}
+
+// File представляет открытый файл из кэша
+type File struct {
+	*os.File
+	size int64
+}
+
+// Size возвращает размер файла в байтах
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// entry описывает файл в кэше
+type entry struct {
+	key     string
+	size    int64
+	usedAt  time.Time
+	pinned  bool
+	partial bool
+}
+
+// New создает кэш в указанной директории. Директория создается, если ее нет.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории кэша: %w", err)
+	}
+	return &Cache{dir: dir, maxSize: maxSize}, nil
+}
+
+// Dir возвращает директорию кэша
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Key возвращает ключ кэша для URL трека
+func Key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// path возвращает путь к файлу кэша с указанным ключом и расширением
+func (c *Cache) path(key, ext string) string {
+	return filepath.Join(c.dir, key+ext)
+}
+
+// Has возвращает true, если трек полностью сохранен в кэше
+func (c *Cache) Has(url string) bool {
+	_, err := os.Stat(c.path(Key(url), audioExt))
+	return err == nil
+}
+
+// Open открывает трек из кэша и отмечает его как недавно использованный
+func (c *Cache) Open(url string) (*File, error) {
+	path := c.path(Key(url), audioExt)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("трек отсутствует в кэше: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("ошибка чтения файла кэша: %w", err)
+	}
+
+	// Время изменения файла служит временем последнего использования
+	now := time.Now()
+	_ = os.Chtimes(path, now, now) // Не критично: файл лишь раньше попадет под вытеснение
+
+	return &File{File: file, size: info.Size()}, nil
+}
+
+// Store сохраняет трек в кэш целиком из ридера
+func (c *Cache) Store(url string, r io.Reader) error {
+	file, err := c.createPart(Key(url))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("ошибка записи в кэш: %w", err)
+	}
+
+	return c.commit(file, Key(url))
+}
+
+// createPart создает временный файл для скачивания трека с указанным ключом
+func (c *Cache) createPart(key string) (*os.File, error) {
+	file, err := os.CreateTemp(c.dir, key+"-*"+partExt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания файла в кэше: %w", err)
+	}
+	return file, nil
+}
+
+// commit закрывает временный файл, делает его файлом трека и освобождает
+// место, если кэш переполнен
+func (c *Cache) commit(file *os.File, key string) error {
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("ошибка записи в кэш: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("ошибка записи в кэш: %w", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := os.Rename(file.Name(), c.path(key, audioExt)); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("ошибка сохранения файла в кэше: %w", err)
+	}
+
+	return c.evict(key)
+}
+
+// Remove удаляет трек и его закрепление из кэша
+func (c *Cache) Remove(url string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, ext := range []string{audioExt, pinExt} {
+		if err := os.Remove(c.path(Key(url), ext)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("ошибка удаления трека из кэша: %w", err)
+		}
+	}
+	return nil
+}
+
+// Pin закрепляет трек: закрепленные треки не вытесняются из кэша.
+// Трек можно закрепить до того, как он будет скачан.
+func (c *Cache) Pin(url string) error {
+	if err := os.WriteFile(c.path(Key(url), pinExt), []byte(url+"\n"), 0644); err != nil {
+		return fmt.Errorf("ошибка закрепления трека: %w", err)
+	}
+	return nil
+}
+
+// Unpin снимает закрепление трека
+func (c *Cache) Unpin(url string) error {
+	if err := os.Remove(c.path(Key(url), pinExt)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка снятия закрепления: %w", err)
+	}
+	return nil
+}
+
+// IsPinned возвращает true, если трек закреплен
+func (c *Cache) IsPinned(url string) bool {
+	_, err := os.Stat(c.path(Key(url), pinExt))
+	return err == nil
+}
+
+// Status возвращает сведения о содержимом кэша
+func (c *Cache) Status() (Stats, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{MaxSize: c.maxSize}
+	for _, e := range entries {
+		if e.partial {
+			continue
+		}
+		stats.Files++
+		stats.Size += e.size
+		if e.pinned {
+			stats.Pinned++
+		}
+	}
+	return stats, nil
+}
+
+// Clear удаляет файлы из кэша. Закрепленные треки удаляются вместе с
+// закреплением только при keepPinned == false. Возвращает количество
+// удаленных файлов треков.
+func (c *Cache) Clear(keepPinned bool) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if e.partial {
+			continue // Недокачанные файлы могут принадлежать идущему воспроизведению
+		}
+		if e.pinned && keepPinned {
+			continue
+		}
+		if err := os.Remove(c.path(e.key, audioExt)); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("ошибка удаления файла из кэша: %w", err)
+		}
+		removed++
+	}
+
+	if !keepPinned {
+		pins, err := filepath.Glob(filepath.Join(c.dir, "*"+pinExt))
+		if err != nil {
+			return removed, fmt.Errorf("ошибка чтения директории кэша: %w", err)
+		}
+		for _, pin := range pins {
+			if err := os.Remove(pin); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("ошибка снятия закрепления: %w", err)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// evict удаляет давно не использованные файлы, пока размер кэша превышает
+// ограничение. Файл с ключом keep (только что сохраненный) не удаляется.
+// Должен вызываться под мьютексом.
+func (c *Cache) evict(keep string) error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	// Сначала вытесняем файлы, которые дольше всего не использовались
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].usedAt.Before(entries[j].usedAt)
+	})
+
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if e.pinned || e.partial || e.key == keep {
+			continue
+		}
+		if err := os.Remove(c.path(e.key, audioExt)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("ошибка вытеснения файла из кэша: %w", err)
+		}
+		total -= e.size
+	}
+
+	return nil
+}
+
+// entries возвращает файлы треков в кэше, включая недокачанные
+func (c *Cache) entries() ([]entry, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения директории кэша: %w", err)
+	}
+
+	pinned := make(map[string]bool)
+	for _, file := range files {
+		if name := file.Name(); strings.HasSuffix(name, pinExt) {
+			pinned[strings.TrimSuffix(name, pinExt)] = true
+		}
+	}
+
+	var entries []entry
+	for _, file := range files {
+		name := file.Name()
+
+		var e entry
+		switch {
+		case strings.HasSuffix(name, audioExt):
+			e.key = strings.TrimSuffix(name, audioExt)
+		case strings.HasSuffix(name, partExt):
+			e.key = name
+			e.partial = true
+		default:
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue // Файл удален во время чтения директории
+		}
+		e.size = info.Size()
+		e.usedAt = info.ModTime()
+		e.pinned = pinned[e.key]
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// memorySource реализует Source поверх байтового буфера
+type memorySource struct {
+	*bytes.Reader
+	closed bool
+}
+
+func newMemorySource(data []byte) *memorySource {
+	return &memorySource{Reader: bytes.NewReader(data)}
+}
+
+func (s *memorySource) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *memorySource) Size() int64 {
+	return s.Reader.Size()
+}
+
+func newTestCache(t *testing.T, maxSize int64) *Cache {
+	c, err := New(t.TempDir(), maxSize)
+	if err != nil {
+		t.Fatalf("Ошибка создания кэша: %v", err)
+	}
+	return c
+}
+
+func TestStoreAndOpen(t *testing.T) {
+	c := newTestCache(t, 0)
+	url := "https://s3.example.com/track.mp3"
+	content := []byte("ID3 audio data")
+
+	if c.Has(url) {
+		t.Fatal("Пустой кэш не должен содержать трек")
+	}
+
+	if err := c.Store(url, bytes.NewReader(content)); err != nil {
+		t.Fatalf("Ошибка сохранения в кэш: %v", err)
+	}
+	if !c.Has(url) {
+		t.Fatal("Трек должен быть в кэше после сохранения")
+	}
+
+	file, err := c.Open(url)
+	if err != nil {
+		t.Fatalf("Ошибка открытия трека из кэша: %v", err)
+	}
+	defer file.Close()
+
+	data, _ := io.ReadAll(file)
+	if !bytes.Equal(data, content) || file.Size() != int64(len(content)) {
+		t.Errorf("Из кэша прочитано %q (размер %d), ожидалось %q", data, file.Size(), content)
+	}
+}
+
+func TestRecorderWithSeeks(t *testing.T) {
+	c := newTestCache(t, 0)
+	url := "https://s3.example.com/mix.mp3"
+	content := bytes.Repeat([]byte("0123456789"), 100)
+
+	source := newMemorySource(content)
+	recorder, err := c.Record(url, source)
+	if err != nil {
+		t.Fatalf("Ошибка начала записи: %v", err)
+	}
+
+	// Читаем начало, перематываем в конец и возвращаемся к пропущенной части
+	buf := make([]byte, 300)
+	io.ReadFull(recorder, buf)
+	recorder.Seek(700, io.SeekStart)
+	io.ReadAll(recorder)
+	if recorder.Complete() {
+		t.Fatal("Запись с пропуском не должна считаться полной")
+	}
+	recorder.Seek(250, io.SeekStart)
+	io.ReadFull(recorder, make([]byte, 500))
+
+	if !recorder.Complete() {
+		t.Fatalf("После чтения всех байтов запись должна быть полной, диапазоны: %v", recorder.spans)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Ошибка закрытия: %v", err)
+	}
+	if !source.closed {
+		t.Error("Источник должен закрываться вместе с записью")
+	}
+
+	file, err := c.Open(url)
+	if err != nil {
+		t.Fatalf("Трек должен попасть в кэш: %v", err)
+	}
+	defer file.Close()
+	if data, _ := io.ReadAll(file); !bytes.Equal(data, content) {
+		t.Error("Содержимое кэша не совпадает с источником")
+	}
+}
+
+func TestRecorderIncomplete(t *testing.T) {
+	c := newTestCache(t, 0)
+	url := "https://s3.example.com/partial.mp3"
+
+	recorder, err := c.Record(url, newMemorySource(make([]byte, 1000)))
+	if err != nil {
+		t.Fatalf("Ошибка начала записи: %v", err)
+	}
+	io.ReadFull(recorder, make([]byte, 100))
+	recorder.Close()
+
+	if c.Has(url) {
+		t.Error("Недослушанный трек не должен попадать в кэш")
+	}
+
+	// Временные файлы удаляются
+	files, _ := os.ReadDir(c.Dir())
+	if len(files) != 0 {
+		t.Errorf("В директории кэша остались файлы: %d", len(files))
+	}
+}
+
+func TestEvictionAndPins(t *testing.T) {
+	c := newTestCache(t, 250)
+	urls := []string{"https://s3/a.mp3", "https://s3/b.mp3", "https://s3/c.mp3"}
+
+	if err := c.Pin(urls[0]); err != nil {
+		t.Fatalf("Ошибка закрепления: %v", err)
+	}
+
+	// Сохраняем файлы с разным временем использования
+	for i, url := range urls {
+		if err := c.Store(url, bytes.NewReader(make([]byte, 100))); err != nil {
+			t.Fatalf("Ошибка сохранения: %v", err)
+		}
+		used := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(c.path(Key(url), audioExt), used, used)
+	}
+
+	// Третий файл превысил лимит: вытесняется самый старый незакрепленный (b)
+	if !c.Has(urls[0]) {
+		t.Error("Закрепленный трек не должен вытесняться")
+	}
+	if c.Has(urls[1]) {
+		t.Error("Давно не использованный трек должен быть вытеснен")
+	}
+	if !c.Has(urls[2]) {
+		t.Error("Только что сохраненный трек не должен вытесняться")
+	}
+
+	stats, err := c.Status()
+	if err != nil {
+		t.Fatalf("Ошибка получения статуса: %v", err)
+	}
+	if stats.Files != 2 || stats.Pinned != 1 || stats.Size != 200 {
+		t.Errorf("Неверный статус кэша: %+v", stats)
+	}
+
+	// Очистка с сохранением закрепленных треков
+	removed, err := c.Clear(true)
+	if err != nil || removed != 1 {
+		t.Errorf("Ожидалось удаление 1 файла, удалено %d (ошибка: %v)", removed, err)
+	}
+	if !c.Has(urls[0]) || !c.IsPinned(urls[0]) {
+		t.Error("Закрепленный трек должен сохраниться при очистке")
+	}
+
+	// Полная очистка удаляет и закрепления
+	if _, err := c.Clear(false); err != nil {
+		t.Fatalf("Ошибка очистки: %v", err)
+	}
+	if c.Has(urls[0]) || c.IsPinned(urls[0]) {
+		t.Error("Полная очистка должна удалить закрепленный трек")
+	}
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"io"
+	"os"
+)
+
+// Source представляет источник данных трека с произвольным доступом
+type Source interface {
+	io.ReadSeekCloser
+	Size() int64 // Размер в байтах (-1, если неизвестен)
+}
+
+// span описывает полуинтервал [start, end) записанных байтов
+type span struct {
+	start, end int64
+}
+
+// Recorder читает трек из источника и параллельно записывает прочитанные
+// данные в кэш. Перемотка поддерживается: данные пишутся по своим
+// смещениям, а файл попадает в кэш при закрытии, только если за время
+// воспроизведения были прочитаны все его байты.
+type Recorder struct {
+	source Source
+	cache  *Cache
+	key    string
+	file   *os.File
+	offset int64
+	spans  []span // Записанные диапазоны, упорядоченные и без пересечений
+	failed bool   // Запись в кэш не удалась, файл будет удален
+	closed bool
+}
+
+// Record начинает запись трека из источника в кэш
+func (c *Cache) Record(url string, source Source) (*Recorder, error) {
+	key := Key(url)
+	file, err := c.createPart(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		source: source,
+		cache:  c,
+		key:    key,
+		file:   file,
+	}, nil
+}
+
+// Read читает данные из источника и записывает их в кэш
+func (r *Recorder) Read(p []byte) (int, error) {
+	n, err := r.source.Read(p)
+	if n > 0 && !r.failed {
+		if _, werr := r.file.WriteAt(p[:n], r.offset); werr != nil {
+			// Ошибка кэша не должна прерывать воспроизведение
+			r.failed = true
+		} else {
+			r.addSpan(r.offset, r.offset+int64(n))
+		}
+	}
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek перематывает источник
+func (r *Recorder) Seek(offset int64, whence int) (int64, error) {
+	position, err := r.source.Seek(offset, whence)
+	if err != nil {
+		return position, err
+	}
+	r.offset = position
+	return position, nil
+}
+
+// Size возвращает размер источника в байтах
+func (r *Recorder) Size() int64 {
+	return r.source.Size()
+}
+
+// Complete возвращает true, если записаны все байты трека
+func (r *Recorder) Complete() bool {
+	size := r.source.Size()
+	return !r.failed && size > 0 && len(r.spans) == 1 &&
+		r.spans[0].start == 0 && r.spans[0].end >= size
+}
+
+// Close закрывает источник и сохраняет файл в кэш, если трек записан целиком
+func (r *Recorder) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.source.Close()
+
+	if r.Complete() {
+		_ = r.cache.commit(r.file, r.key) // Трек просто будет скачан заново в следующий раз
+	} else {
+		r.file.Close()
+		os.Remove(r.file.Name())
+	}
+
+	return err
+}
+
+// addSpan добавляет записанный диапазон, объединяя его с соседними
+func (r *Recorder) addSpan(start, end int64) {
+	merged := span{start, end}
+	spans := r.spans[:0:0]
+
+	for _, s := range r.spans {
+		if s.end < merged.start || s.start > merged.end {
+			spans = append(spans, s)
+			continue
+		}
+		merged.start = min(merged.start, s.start)
+		merged.end = max(merged.end, s.end)
+	}
+
+	// Вставляем объединенный диапазон, сохраняя порядок
+	i := 0
+	for i < len(spans) && spans[i].start < merged.start {
+		i++
+	}
+	spans = append(spans, span{})
+	copy(spans[i+1:], spans[i:])
+	spans[i] = merged
+
+	r.spans = spans
+}
@@ -2,6 +2,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -16,8 +17,29 @@ type Config struct {
 	AwsRegion     string `yaml:"aws_region"`
 	AwsEndpoint   string `yaml:"aws_endpoint"`
 	DownloadDir   string `yaml:"download_dir"`
+	CacheDir      string `yaml:"cache_dir"`     // Директория локального кэша треков
+	CacheSizeMB   int64  `yaml:"cache_size_mb"` // Ограничение размера кэша в мегабайтах
+	// Длительность кроссфейда между треками очереди в секундах (0 - без кроссфейда)
+	CrossfadeSeconds int `yaml:"crossfade_seconds"`
+	// Хранилище библиотеки треков: BackendYAML или BackendDB
+	LibraryBackend string `yaml:"library_backend"`
 }
 
+// Значения по умолчанию для кэша треков
+const (
+	DefaultCacheDir    = "~/.snatcher_cache"
+	DefaultCacheSizeMB = 2048
+)
+
+// Хранилища библиотеки треков
+const (
+	BackendYAML = "yaml" // Треки в файле данных вместе с настройками плеера
+	BackendDB   = "db"   // Треки во встроенной базе данных
+)
+
+// MaxCrossfadeSeconds - максимальная длительность кроссфейда в секундах
+const MaxCrossfadeSeconds = 15
+
 // LoadConfig загружает конфигурацию приложения из указанного файла
 func LoadConfig(filePath string) (*Config, error) {
 	home, err := os.UserHomeDir()
@@ -41,9 +63,32 @@ func LoadConfig(filePath string) (*Config, error) {
 	if config.DownloadDir == "" {
 		config.DownloadDir = "~/Downloads"
 	}
+	if config.CacheDir == "" {
+		config.CacheDir = DefaultCacheDir
+	}
+	if config.CacheSizeMB <= 0 {
+		config.CacheSizeMB = DefaultCacheSizeMB
+	}
+	config.CrossfadeSeconds = max(0, min(config.CrossfadeSeconds, MaxCrossfadeSeconds))
+	if config.LibraryBackend == "" {
+		config.LibraryBackend = BackendYAML
+	}
+	if err := ValidateBackend(config.LibraryBackend); err != nil {
+		return nil, err
+	}
 
-	// Раскрываем тильду в пути загрузки
+	// Раскрываем тильду в путях загрузки и кэша
 	config.DownloadDir = strings.Replace(config.DownloadDir, "~", home, 1)
+	config.CacheDir = strings.Replace(config.CacheDir, "~", home, 1)
 
 	return config, nil
 }
+
+// ValidateBackend проверяет название хранилища библиотеки
+func ValidateBackend(backend string) error {
+	switch backend {
+	case BackendYAML, BackendDB:
+		return nil
+	}
+	return fmt.Errorf("неизвестное хранилище библиотеки %q: используйте %s или %s", backend, BackendYAML, BackendDB)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -103,6 +104,18 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Ожидался DownloadDir по умолчанию: %s, получено: %s", expectedDownloadDir, loadedConfig.DownloadDir)
 	}
 
+	// Проверяем значения по умолчанию для кэша
+	expectedCacheDir := filepath.Join(home, ".snatcher_cache")
+	if loadedConfig.CacheDir != expectedCacheDir {
+		t.Errorf("Ожидался CacheDir по умолчанию: %s, получено: %s", expectedCacheDir, loadedConfig.CacheDir)
+	}
+	if loadedConfig.CacheSizeMB != DefaultCacheSizeMB {
+		t.Errorf("Ожидался CacheSizeMB по умолчанию: %d, получено: %d", DefaultCacheSizeMB, loadedConfig.CacheSizeMB)
+	}
+	if loadedConfig.LibraryBackend != BackendYAML {
+		t.Errorf("Ожидался LibraryBackend по умолчанию: %s, получено: %s", BackendYAML, loadedConfig.LibraryBackend)
+	}
+
 	// Проверяем, что остальные поля загружены корректно
 	if loadedConfig.AwsBucketName != "test-bucket" {
 		t.Errorf("Ожидался AwsBucketName: test-bucket, получено: %s", loadedConfig.AwsBucketName)
@@ -251,3 +264,41 @@ func TestLoadConfigWithTilde(t *testing.T) {
 		t.Errorf("Ожидался DownloadDir с раскрытой тильдой: %s, получено: %s", expectedDownloadDir, loadedConfig.DownloadDir)
 	}
 }
+
+func TestCrossfadeSecondsLimit(t *testing.T) {
+	tests := []struct {
+		value    int
+		expected int
+	}{
+		{0, 0},
+		{8, 8},
+		{60, MaxCrossfadeSeconds},
+		{-3, 0},
+	}
+
+	for _, test := range tests {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		data := []byte("aws_bucket_name: test-bucket\ncrossfade_seconds: " + strconv.Itoa(test.value) + "\n")
+		if err := os.WriteFile(configPath, data, 0644); err != nil {
+			t.Fatalf("Ошибка записи файла конфигурации: %v", err)
+		}
+
+		loadedConfig, err := LoadConfig(configPath)
+		if err != nil {
+			t.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		}
+		if loadedConfig.CrossfadeSeconds != test.expected {
+			t.Errorf("crossfade_seconds: %d: ожидалось %d, получено %d", test.value, test.expected, loadedConfig.CrossfadeSeconds)
+		}
+	}
+}
+
+func TestUnknownLibraryBackend(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("library_backend: sqlite\n"), 0644); err != nil {
+		t.Fatalf("Ошибка записи файла конфигурации: %v", err)
+	}
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного хранилища библиотеки")
+	}
+}
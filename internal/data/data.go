@@ -2,34 +2,123 @@
 package data
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// TrackMetadata содержит метаданные музыкального трека
+// TrackMetadata содержит метаданные музыкального трека. Необязательные
+// теги не записываются в файл данных, пока они пустые, поэтому файлы,
+// сохраненные старыми версиями, читаются без изменений.
 type TrackMetadata struct {
-	ID        int    `yaml:"id"`
-	Artist    string `yaml:"artist"`
-	Title     string `yaml:"title"`
-	Album     string `yaml:"album"`
-	Length    int    `yaml:"length"`     // Длина трека в секундах
-	FileSize  int64  `yaml:"file_size"`  // Размер файла в байтах
-	URL       string `yaml:"url"`        // URL трека в хранилище S3
-	SourceURL string `yaml:"source_url"` // URL источника, откуда скачан материал
+	ID          int       `yaml:"id"`
+	UID         string    `yaml:"uid,omitempty"` // Уникальный идентификатор (ULID), не зависящий от библиотеки
+	Artist      string    `yaml:"artist"`
+	Title       string    `yaml:"title"`
+	Album       string    `yaml:"album"`
+	Year        int       `yaml:"year,omitempty"`
+	Genre       string    `yaml:"genre,omitempty"`
+	Comment     string    `yaml:"comment,omitempty"`
+	TrackNumber int       `yaml:"track_number,omitempty"` // Номер трека в альбоме
+	BPM         int       `yaml:"bpm,omitempty"`          // Темп в ударах в минуту
+	Key         string    `yaml:"key,omitempty"`          // Тональность (например, "Am" или "8A")
+	Artwork     string    `yaml:"artwork,omitempty"`      // MIME-тип встроенной обложки, если она есть
+	Length      int       `yaml:"length"`                 // Длина трека в секундах
+	FileSize    int64     `yaml:"file_size"`              // Размер файла в байтах
+	SHA256      string    `yaml:"sha256,omitempty"`       // SHA-256 содержимого файла (hex) для поиска дубликатов
+	URL         string    `yaml:"url"`                    // URL трека в хранилище S3
+	ArtworkURL  string    `yaml:"artwork_url,omitempty"`  // URL обложки в хранилище S3
+	SourceURL   string    `yaml:"source_url"`             // URL источника, откуда скачан материал
+	UploadDate  string    `yaml:"upload_date,omitempty"`  // Дата публикации источника (ГГГГ-ММ-ДД)
+	Format      string    `yaml:"format,omitempty"`       // Формат аудиофайла (mp3, flac, ogg, wav); пустой означает mp3
+	Chapters    []Chapter `yaml:"chapters,omitempty"`     // Главы микса по возрастанию времени начала
+}
+
+// Chapter - глава микса: трек внутри длинной записи
+type Chapter struct {
+	Start  int    `yaml:"start"` // Начало главы в секундах
+	Artist string `yaml:"artist,omitempty"`
+	Title  string `yaml:"title"`
+}
+
+// String возвращает главу в виде "Artist - Title"
+func (c Chapter) String() string {
+	if c.Artist == "" {
+		return c.Title
+	}
+	return c.Artist + " - " + c.Title
+}
+
+// ChapterAt возвращает индекс главы, которая играет на позиции position,
+// или -1, если у трека нет глав или позиция раньше первой из них
+func (t TrackMetadata) ChapterAt(position time.Duration) int {
+	current := -1
+	for i, chapter := range t.Chapters {
+		if time.Duration(chapter.Start)*time.Second > position {
+			break
+		}
+		current = i
+	}
+	return current
+}
+
+// TagSummary возвращает необязательные теги трека одной строкой
+// (например, "2021 • House • трек 3 • 124 BPM • 8A") или пустую строку
+func (t TrackMetadata) TagSummary() string {
+	var parts []string
+	if t.Year > 0 {
+		parts = append(parts, strconv.Itoa(t.Year))
+	}
+	if t.Genre != "" {
+		parts = append(parts, t.Genre)
+	}
+	if t.TrackNumber > 0 {
+		parts = append(parts, fmt.Sprintf("трек %d", t.TrackNumber))
+	}
+	if t.BPM > 0 {
+		parts = append(parts, fmt.Sprintf("%d BPM", t.BPM))
+	}
+	if t.Key != "" {
+		parts = append(parts, t.Key)
+	}
+	return strings.Join(parts, " • ")
 }
 
 // AppData содержит все данные приложения
 type AppData struct {
-	Tracks []TrackMetadata `yaml:"tracks"`
+	// Версия схемы файла данных (см. SchemaVersion и migrations)
+	SchemaVersion int             `yaml:"schema_version"`
+	Tracks        []TrackMetadata `yaml:"tracks"`
+	// Следующий ID трека. Счетчик только растет, поэтому ID удаленного
+	// трека не достается новому.
+	NextID int  `yaml:"next_id,omitempty"`
+	Volume *int `yaml:"volume,omitempty"` // Последняя громкость плеера в процентах
+	// Позиции для возобновления воспроизведения в секундах по ID трека
+	Positions map[int]int `yaml:"positions,omitempty"`
+
+	// Содержимое файла данных при последней загрузке или сохранении: по
+	// нему SaveData замечает изменения, сделанные другим процессом
+	loaded []byte
+	synced bool // Данные загружены из файла или сохранены в него
+
+	// mu защищает Positions (плеер сообщает позиции из своих горутин),
+	// loaded и synced. LoadData и SaveData держат его все время работы,
+	// поэтому позиции не меняются, пока данные сливаются и сериализуются.
+	// Остальные поля меняются только из горутины, которая сохраняет данные.
+	mu sync.Mutex
 }
 
 // NewAppData создает новую структуру AppData
 func NewAppData() *AppData {
 	return &AppData{
-		Tracks: make([]TrackMetadata, 0),
+		SchemaVersion: SchemaVersion,
+		Tracks:        make([]TrackMetadata, 0),
 	}
 }
 
@@ -41,44 +130,83 @@ func (d *AppData) LoadData(filePath string) error {
 	}
 	path := strings.Replace(filePath, "~", home, 1)
 
-	data, err := os.ReadFile(path)
+	// Файл заменяется атомарно, поэтому читать его можно без блокировки
+	content, err := readDataFile(path)
 	if err != nil {
-		// Если файл не найден, инициализируем пустыми данными
-		if os.IsNotExist(err) {
-			*d = *NewAppData()
-			return nil
-		}
-		return fmt.Errorf("ошибка чтения файла данных: %w", err)
+		return err
 	}
-	if len(data) == 0 {
-		*d = *NewAppData()
-		return nil
+	// Файл старой версии обновляется на диске до разбора
+	if _, version, err := parseDocument(content); err != nil {
+		return err
+	} else if version < SchemaVersion {
+		if content, err = migrateDataFile(path); err != nil {
+			return err
+		}
 	}
-	if err := yaml.Unmarshal(data, d); err != nil {
-		return fmt.Errorf("ошибка разбора данных: %w", err)
+	loaded, err := parseData(content)
+	if err != nil {
+		return err
 	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setFields(loaded)
+	d.loaded, d.synced = content, true
 	return nil
 }
 
-// AddTrack добавляет новый трек в AppData
-func (d *AppData) AddTrack(track TrackMetadata) {
-	// Найдем максимальный ID и присваиваем новый треку
-	if len(d.Tracks) > 0 {
-		maxID := d.Tracks[0].ID
-		for _, t := range d.Tracks {
-			if t.ID > maxID {
-				maxID = t.ID
-			}
+// setFields копирует в d поля, которые хранятся в файле данных
+func (d *AppData) setFields(src *AppData) {
+	d.SchemaVersion, d.Tracks, d.NextID = src.SchemaVersion, src.Tracks, src.NextID
+	d.Volume, d.Positions = src.Volume, src.Positions
+}
+
+// parseData разбирает содержимое файла данных. Пустой файл (или его
+// отсутствие) дает пустые данные, файл старой версии схемы обновляется в
+// памяти, а файл более новой версии не разбирается.
+func parseData(content []byte) (*AppData, error) {
+	d := NewAppData()
+	if len(content) == 0 {
+		return d, nil
+	}
+	doc, version, err := parseDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	if version < SchemaVersion {
+		if err := migrateDocument(doc, version); err != nil {
+			return nil, err
 		}
-		track.ID = maxID + 1
-	} else {
-		track.ID = 1 // Если треков нет, начинаем с 1
+		if content, err = yaml.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("ошибка сериализации данных: %w", err)
+		}
+	}
+	if err := yaml.Unmarshal(content, d); err != nil {
+		return nil, fmt.Errorf("ошибка разбора данных: %w", err)
+	}
+	return d, nil
+}
+
+// AddTrack добавляет новый трек в AppData, назначая ему следующий ID и,
+// если его еще нет, UID
+func (d *AppData) AddTrack(track TrackMetadata) {
+	id := max(d.NextID, 1)
+	for _, t := range d.Tracks {
+		id = max(id, t.ID+1)
+	}
+	track.ID = id
+	d.NextID = id + 1
+	if track.UID == "" {
+		track.UID = NewUID()
 	}
 	// Добавляем трек в список
 	d.Tracks = append(d.Tracks, track)
 }
 
-// SaveData сохраняет данные в файл
+// SaveData сохраняет данные в файл. Пока файл заблокирован, он
+// перечитывается, и если другой процесс изменил его после LoadData, их
+// изменения сливаются с изменениями этого процесса (см. mergeData).
+// Предыдущее содержимое файла остается в резервной копии с суффиксом .bak.
 func (d *AppData) SaveData(filePath string) error {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -86,16 +214,107 @@ func (d *AppData) SaveData(filePath string) error {
 	}
 	path := strings.Replace(filePath, "~", home, 1)
 
-	data, err := yaml.Marshal(d)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	unlock, err := lockDataFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	onDisk, err := readDataFile(path)
+	if err != nil {
+		return err
+	}
+	// Файл более новой версии схемы не перезаписывается: его поля потерялись бы
+	if _, _, err := parseDocument(onDisk); err != nil {
+		return err
+	}
+
+	if d.synced && !bytes.Equal(onDisk, d.loaded) {
+		if err := d.merge(onDisk); err != nil {
+			return err
+		}
+	}
+	d.SchemaVersion = SchemaVersion
+	// Сериализуется копия полей: yaml копирует структуру целиком, вместе с mu
+	var snapshot AppData
+	snapshot.setFields(d)
+	content, err := yaml.Marshal(&snapshot)
 	if err != nil {
 		return fmt.Errorf("ошибка сериализации данных: %w", err)
 	}
-	if err := os.WriteFile(path, data, 0644); err != nil {
+
+	if len(onDisk) > 0 && !bytes.Equal(onDisk, content) {
+		if err := writeFileAtomic(path+backupSuffix, onDisk); err != nil {
+			return fmt.Errorf("ошибка записи резервной копии: %w", err)
+		}
+	}
+	if err := writeFileAtomic(path, content); err != nil {
 		return fmt.Errorf("ошибка записи файла данных: %w", err)
 	}
+	d.loaded, d.synced = content, true
+	return nil
+}
+
+// merge сливает данные с содержимым файла, измененного другим процессом
+func (d *AppData) merge(onDisk []byte) error {
+	base, err := parseData(d.loaded)
+	if err != nil {
+		return err
+	}
+	theirs, err := parseData(onDisk)
+	if err != nil {
+		return fmt.Errorf("файл данных изменен другим процессом: %w", err)
+	}
+	merged := mergeData(base, d, theirs)
+	d.Tracks, d.NextID = merged.Tracks, merged.NextID
+	d.Volume, d.Positions = merged.Volume, merged.Positions
 	return nil
 }
 
+// SetVolume запоминает громкость плеера и сообщает, изменилась ли она
+func (d *AppData) SetVolume(level int) bool {
+	if d.Volume != nil && *d.Volume == level {
+		return false
+	}
+	d.Volume = &level
+	return true
+}
+
+// SetPosition запоминает позицию трека в секундах для возобновления
+// воспроизведения; нулевая позиция удаляет запись. Возвращает true,
+// если данные изменились.
+func (d *AppData) SetPosition(trackID, seconds int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if seconds <= 0 {
+		if _, ok := d.Positions[trackID]; !ok {
+			return false
+		}
+		delete(d.Positions, trackID)
+		return true
+	}
+
+	if d.Positions[trackID] == seconds {
+		return false
+	}
+	if d.Positions == nil {
+		d.Positions = make(map[int]int)
+	}
+	d.Positions[trackID] = seconds
+	return true
+}
+
+// Position возвращает сохраненную позицию трека в секундах или 0
+func (d *AppData) Position(trackID int) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.Positions[trackID]
+}
+
 // TrackByID возвращает трек по ID
 func (d *AppData) TrackByID(id int) (*TrackMetadata, error) {
 	for i := range d.Tracks {
@@ -112,6 +331,8 @@ func (d *AppData) DeleteTrackByID(id int) error {
 		if track.ID == id {
 			// Удаляем элемент из слайса
 			d.Tracks = append(d.Tracks[:i], d.Tracks[i+1:]...)
+			// Позиция не должна достаться новому треку с тем же ID
+			d.SetPosition(id, 0)
 			return nil
 		}
 	}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDataWithoutExtendedTags(t *testing.T) {
+	// Файл данных в формате старых версий: без расширенных тегов
+	legacy := `tracks:
+    - id: 1
+      artist: Test Artist
+      title: Test Title
+      album: Test Album
+      length: 180
+      file_size: 1024
+      url: https://example.com/test.mp3
+      source_url: ""
+`
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Ошибка создания файла данных: %v", err)
+	}
+
+	appData := NewAppData()
+	if err := appData.LoadData(path); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	if len(appData.Tracks) != 1 || appData.Tracks[0].Title != "Test Title" {
+		t.Fatalf("Трек не загружен: %+v", appData.Tracks)
+	}
+	if summary := appData.Tracks[0].TagSummary(); summary != "" {
+		t.Errorf("У трека без тегов ожидалась пустая сводка, получено %q", summary)
+	}
+
+	// Пустые теги не добавляются в файл при сохранении
+	if err := appData.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Ошибка чтения файла данных: %v", err)
+	}
+	for _, key := range []string{"year:", "genre:", "bpm:", "key:", "artwork:"} {
+		if strings.Contains(string(saved), key) {
+			t.Errorf("Файл данных не должен содержать пустой тег %s:\n%s", key, saved)
+		}
+	}
+}
+
+func TestTagSummary(t *testing.T) {
+	track := TrackMetadata{Year: 2021, Genre: "Techno", TrackNumber: 3, BPM: 132, Key: "Am", Comment: "не входит в сводку"}
+	if summary := track.TagSummary(); summary != "2021 • Techno • трек 3 • 132 BPM • Am" {
+		t.Errorf("Неверная сводка тегов: %q", summary)
+	}
+}
+
+func TestChapterAt(t *testing.T) {
+	track := TrackMetadata{Chapters: []Chapter{
+		{Start: 10, Artist: "A", Title: "First"},
+		{Start: 60, Title: "Second"},
+	}}
+	tests := []struct {
+		position time.Duration
+		expected int
+	}{
+		{5 * time.Second, -1},
+		{10 * time.Second, 0},
+		{59 * time.Second, 0},
+		{time.Hour, 1},
+	}
+	for _, test := range tests {
+		if got := track.ChapterAt(test.position); got != test.expected {
+			t.Errorf("ChapterAt(%v) = %d, ожидалось %d", test.position, got, test.expected)
+		}
+	}
+
+	if track.Chapters[0].String() != "A - First" || track.Chapters[1].String() != "Second" {
+		t.Errorf("Неверное представление глав: %q, %q", track.Chapters[0], track.Chapters[1])
+	}
+	if (TrackMetadata{}).ChapterAt(time.Minute) != -1 {
+		t.Error("У трека без глав не должно быть текущей главы")
+	}
+}
+
+func TestSaveDataMergesConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	initial := NewAppData()
+	initial.AddTrack(TrackMetadata{Artist: "A", Title: "One"})
+	initial.AddTrack(TrackMetadata{Artist: "B", Title: "Two"})
+	if err := initial.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+
+	// Два процесса загрузили один и тот же файл
+	first, second := NewAppData(), NewAppData()
+	for _, d := range []*AppData{first, second} {
+		if err := d.LoadData(path); err != nil {
+			t.Fatalf("Ошибка загрузки данных: %v", err)
+		}
+	}
+
+	first.Tracks[0].Title = "One (Edit)"
+	first.AddTrack(TrackMetadata{Artist: "C", Title: "Three"})
+	if err := first.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+
+	// Второй процесс не знает об изменениях первого
+	second.AddTrack(TrackMetadata{Artist: "D", Title: "Four"})
+	second.SetPosition(3, 42)
+	if err := second.DeleteTrackByID(2); err != nil {
+		t.Fatalf("Ошибка удаления трека: %v", err)
+	}
+	if err := second.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+
+	saved := NewAppData()
+	if err := saved.LoadData(path); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	var titles []string
+	for _, track := range saved.Tracks {
+		titles = append(titles, fmt.Sprintf("%d:%s", track.ID, track.Title))
+	}
+	if got := strings.Join(titles, ", "); got != "1:One (Edit), 3:Three, 4:Four" {
+		t.Errorf("Неверный результат слияния: %s", got)
+	}
+	if saved.Position(4) != 42 || saved.Position(3) != 0 {
+		t.Errorf("Позиция не перенесена на новый ID трека: %v", saved.Positions)
+	}
+	if len(second.Tracks) != 3 || second.Tracks[2].ID != 4 {
+		t.Errorf("Данные в памяти не обновлены после слияния: %+v", second.Tracks)
+	}
+}
+
+func TestSaveDataKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.yaml")
+
+	appData := NewAppData()
+	appData.AddTrack(TrackMetadata{Title: "First"})
+	if err := appData.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Ошибка чтения файла данных: %v", err)
+	}
+
+	appData.AddTrack(TrackMetadata{Title: "Second"})
+	if err := appData.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+	backup, err := os.ReadFile(path + backupSuffix)
+	if err != nil {
+		t.Fatalf("Резервная копия не создана: %v", err)
+	}
+	if string(backup) != string(previous) {
+		t.Errorf("Резервная копия должна содержать предыдущую версию:\n%s", backup)
+	}
+
+	// Временные файлы не остаются рядом с файлом данных
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Ошибка чтения директории: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp") {
+			t.Errorf("Остался временный файл: %s", entry.Name())
+		}
+	}
+}
+
+func TestSaveDataWhilePositionsChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	appData := NewAppData()
+	appData.AddTrack(TrackMetadata{Title: "Mix"})
+
+	// Плеер сообщает позиции из своей горутины, пока данные сохраняются
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 200; i++ {
+			appData.SetPosition(1, i)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := appData.SaveData(path); err != nil {
+			t.Fatalf("Ошибка сохранения данных: %v", err)
+		}
+	}
+	<-done
+
+	if err := appData.SaveData(path); err != nil {
+		t.Fatalf("Ошибка сохранения данных: %v", err)
+	}
+	loaded := NewAppData()
+	if err := loaded.LoadData(path); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	if position := loaded.Position(1); position != 200 {
+		t.Errorf("Ожидалась позиция 200, получено %d", position)
+	}
+}
@@ -0,0 +1,522 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dbMagic - заголовок файла базы данных
+const dbMagic = "SNATCHERDB1\n"
+
+// dbRecordHeaderSize - размер заголовка записи: длина и CRC32 содержимого
+const dbRecordHeaderSize = 8
+
+// maxDBRecordSize ограничивает размер одной записи, чтобы испорченная
+// длина не приводила к попытке прочитать гигабайты
+const maxDBRecordSize = 64 << 20
+
+// DB - встроенная база данных библиотеки в одном файле. Файл - журнал
+// транзакций: каждая транзакция дописывается в конец одной записью с
+// контрольной суммой и сбрасывается на диск, поэтому прерванная запись
+// теряет только эту транзакцию. При открытии журнал читается целиком, треки
+// и индексы по UID, исполнителю, названию и URL источника держатся в памяти.
+// Когда устаревших записей становится много, журнал уплотняется.
+//
+// Записи из нескольких процессов упорядочиваются блокировкой файла; перед
+// каждой операцией DB дочитывает записи, добавленные другими процессами.
+type DB struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	offset  int64 // Конец последней прочитанной записи
+	records int   // Число записей в журнале
+
+	tracks   map[int]TrackMetadata
+	nextID   int // Следующий свободный ID трека
+	byUID    dbIndex
+	byArtist dbIndex
+	byTitle  dbIndex
+	bySource dbIndex
+	bySHA256 dbIndex
+}
+
+// DB реализует Store
+var _ Store = (*DB)(nil)
+
+// dbRecord - запись журнала: изменения одной транзакции
+type dbRecord struct {
+	NextID  int             `yaml:"next_id"`
+	Puts    []TrackMetadata `yaml:"puts,omitempty"`
+	Deletes []int           `yaml:"deletes,omitempty"`
+}
+
+// dbIndex - индекс ID треков по нормализованному значению поля
+type dbIndex map[string]map[int]struct{}
+
+// add добавляет ID трека в индекс
+func (idx dbIndex) add(value string, id int) {
+	key := indexKey(value)
+	if idx[key] == nil {
+		idx[key] = make(map[int]struct{})
+	}
+	idx[key][id] = struct{}{}
+}
+
+// remove удаляет ID трека из индекса
+func (idx dbIndex) remove(value string, id int) {
+	key := indexKey(value)
+	delete(idx[key], id)
+	if len(idx[key]) == 0 {
+		delete(idx, key)
+	}
+}
+
+// OpenDB открывает базу данных, создавая файл, если его нет
+func OpenDB(filePath string) (*DB, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{path: strings.Replace(filePath, "~", home, 1)}
+
+	unlock, err := lockDataFile(db.path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := db.open(); err != nil {
+		return nil, err
+	}
+	if err := db.assignUIDs(); err != nil {
+		db.file.Close()
+		return nil, err
+	}
+	if db.records > 2*len(db.tracks)+64 {
+		if err := db.compact(); err != nil {
+			db.file.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// open открывает файл базы данных и читает журнал. Вызывается под
+// блокировкой файла: недописанная запись в конце журнала отрезается.
+func (db *DB) open() error {
+	file, err := os.OpenFile(db.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия базы данных: %w", err)
+	}
+
+	header := make([]byte, len(dbMagic))
+	n, err := file.ReadAt(header, 0)
+	switch {
+	case n == 0 && errors.Is(err, io.EOF):
+		// Новая база данных
+		if _, err := file.WriteAt([]byte(dbMagic), 0); err != nil {
+			file.Close()
+			return fmt.Errorf("ошибка записи заголовка базы данных: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return fmt.Errorf("ошибка сброса базы данных на диск: %w", err)
+		}
+	case string(header[:n]) != dbMagic:
+		file.Close()
+		return fmt.Errorf("файл %s не является базой данных snatcher", db.path)
+	}
+
+	db.file = file
+	db.offset = int64(len(dbMagic))
+	db.records = 0
+	db.tracks = make(map[int]TrackMetadata)
+	db.nextID = 1
+	db.byUID, db.byArtist, db.byTitle = make(dbIndex), make(dbIndex), make(dbIndex)
+	db.bySource, db.bySHA256 = make(dbIndex), make(dbIndex)
+	return db.readRecords(true)
+}
+
+// refresh дочитывает записи, которые другие процессы добавили в журнал, и
+// открывает файл заново, если его заменило уплотнение. truncate разрешает
+// отрезать недописанную запись: это можно делать только под блокировкой.
+func (db *DB) refresh(truncate bool) error {
+	current, err := os.Stat(db.path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения базы данных: %w", err)
+	}
+	opened, err := db.file.Stat()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения базы данных: %w", err)
+	}
+	if !os.SameFile(current, opened) {
+		db.file.Close()
+		return db.open()
+	}
+	return db.readRecords(truncate)
+}
+
+// readRecords читает записи журнала начиная с db.offset
+func (db *DB) readRecords(truncate bool) error {
+	header := make([]byte, dbRecordHeaderSize)
+	for {
+		if _, err := db.file.ReadAt(header, db.offset); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("ошибка чтения базы данных: %w", err)
+		}
+		size := binary.LittleEndian.Uint32(header[:4])
+		if size > maxDBRecordSize {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := db.file.ReadAt(payload, db.offset+dbRecordHeaderSize); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("ошибка чтения базы данных: %w", err)
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+			break
+		}
+		var record dbRecord
+		if err := yaml.Unmarshal(payload, &record); err != nil {
+			break
+		}
+		db.apply(record)
+		db.offset += dbRecordHeaderSize + int64(size)
+		db.records++
+	}
+
+	if !truncate {
+		return nil
+	}
+	info, err := db.file.Stat()
+	if err != nil {
+		return fmt.Errorf("ошибка чтения базы данных: %w", err)
+	}
+	if info.Size() > db.offset {
+		// Транзакция прервалась на середине записи
+		if err := db.file.Truncate(db.offset); err != nil {
+			return fmt.Errorf("ошибка восстановления базы данных: %w", err)
+		}
+	}
+	return nil
+}
+
+// apply применяет запись журнала к трекам и индексам в памяти
+func (db *DB) apply(record dbRecord) {
+	for _, id := range record.Deletes {
+		if track, ok := db.tracks[id]; ok {
+			db.unindex(track)
+			delete(db.tracks, id)
+		}
+	}
+	for _, track := range record.Puts {
+		if old, ok := db.tracks[track.ID]; ok {
+			db.unindex(old)
+		}
+		db.tracks[track.ID] = track
+		db.byUID.add(track.UID, track.ID)
+		db.byArtist.add(track.Artist, track.ID)
+		db.byTitle.add(track.Title, track.ID)
+		db.bySource.add(track.SourceURL, track.ID)
+		db.bySHA256.add(track.SHA256, track.ID)
+		db.nextID = max(db.nextID, track.ID+1)
+	}
+	db.nextID = max(db.nextID, record.NextID)
+}
+
+// unindex удаляет трек из индексов
+func (db *DB) unindex(track TrackMetadata) {
+	db.byUID.remove(track.UID, track.ID)
+	db.byArtist.remove(track.Artist, track.ID)
+	db.byTitle.remove(track.Title, track.ID)
+	db.bySource.remove(track.SourceURL, track.ID)
+	db.bySHA256.remove(track.SHA256, track.ID)
+}
+
+// assignUIDs выдает UID трекам, сохраненным до их появления. Вызывается
+// под блокировкой файла.
+func (db *DB) assignUIDs() error {
+	var record dbRecord
+	for _, track := range db.sorted(nil) {
+		if track.UID == "" {
+			track.UID = NewUID()
+			record.Puts = append(record.Puts, track)
+		}
+	}
+	if len(record.Puts) == 0 {
+		return nil
+	}
+	record.NextID = db.nextID
+	return db.appendRecord(record)
+}
+
+// appendRecord дописывает запись в журнал и сбрасывает ее на диск
+func (db *DB) appendRecord(record dbRecord) error {
+	payload, err := yaml.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации данных: %w", err)
+	}
+	buf := make([]byte, dbRecordHeaderSize, dbRecordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	buf = append(buf, payload...)
+
+	if _, err := db.file.WriteAt(buf, db.offset); err != nil {
+		return fmt.Errorf("ошибка записи в базу данных: %w", err)
+	}
+	if err := db.file.Sync(); err != nil {
+		return fmt.Errorf("ошибка сброса базы данных на диск: %w", err)
+	}
+	db.apply(record)
+	db.offset += int64(len(buf))
+	db.records++
+	return nil
+}
+
+// compact переписывает журнал одной записью со всеми треками. Вызывается
+// под блокировкой файла.
+func (db *DB) compact() error {
+	record := dbRecord{NextID: db.nextID, Puts: db.sorted(nil)}
+	payload, err := yaml.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации данных: %w", err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(dbMagic)
+	header := make([]byte, dbRecordHeaderSize)
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	buf.Write(header)
+	buf.Write(payload)
+
+	if err := writeFileAtomic(db.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("ошибка уплотнения базы данных: %w", err)
+	}
+	db.file.Close()
+	return db.open()
+}
+
+// sorted возвращает треки, для которых keep возвращает true (nil - все),
+// по возрастанию ID
+func (db *DB) sorted(keep func(id int) bool) []TrackMetadata {
+	tracks := make([]TrackMetadata, 0, len(db.tracks))
+	for id, track := range db.tracks {
+		if keep == nil || keep(id) {
+			tracks = append(tracks, track)
+		}
+	}
+	slices.SortFunc(tracks, func(a, b TrackMetadata) int { return a.ID - b.ID })
+	return tracks
+}
+
+// Get возвращает трек по ID
+func (db *DB) Get(id int) (TrackMetadata, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.refresh(false); err != nil {
+		return TrackMetadata{}, err
+	}
+	track, ok := db.tracks[id]
+	if !ok {
+		return TrackMetadata{}, fmt.Errorf("трека с ID %d не найдено", id)
+	}
+	return track, nil
+}
+
+// List возвращает все треки по возрастанию ID
+func (db *DB) List() ([]TrackMetadata, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.refresh(false); err != nil {
+		return nil, err
+	}
+	return db.sorted(nil), nil
+}
+
+// Query находит треки по индексам
+func (db *DB) Query(q Query) ([]TrackMetadata, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.refresh(false); err != nil {
+		return nil, err
+	}
+
+	var candidates []map[int]struct{}
+	for _, condition := range []struct {
+		value string
+		index dbIndex
+	}{
+		{q.UID, db.byUID},
+		{q.Artist, db.byArtist},
+		{q.Title, db.byTitle},
+		{q.SourceURL, db.bySource},
+		{q.SHA256, db.bySHA256},
+	} {
+		if condition.value != "" {
+			candidates = append(candidates, condition.index[indexKey(condition.value)])
+		}
+	}
+	if len(candidates) == 0 {
+		return db.sorted(nil), nil
+	}
+	return db.sorted(func(id int) bool {
+		for _, ids := range candidates {
+			if _, ok := ids[id]; !ok {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+// Put сохраняет трек отдельной транзакцией
+func (db *DB) Put(track *TrackMetadata) error {
+	return db.Update(func(tx Tx) error {
+		return tx.Put(track)
+	})
+}
+
+// Delete удаляет трек отдельной транзакцией
+func (db *DB) Delete(id int) error {
+	return db.Update(func(tx Tx) error {
+		return tx.Delete(id)
+	})
+}
+
+// Update выполняет fn в транзакции. Пока она выполняется, файл базы данных
+// заблокирован для других процессов.
+func (db *DB) Update(fn func(tx Tx) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	unlock, err := lockDataFile(db.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := db.refresh(true); err != nil {
+		return err
+	}
+
+	tx := &dbTx{
+		db:      db,
+		puts:    make(map[int]TrackMetadata),
+		deleted: make(map[int]bool),
+		nextID:  db.nextID,
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.puts) == 0 && len(tx.deleted) == 0 {
+		return nil
+	}
+	return db.appendRecord(tx.record())
+}
+
+// Close закрывает файл базы данных
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.file.Close()
+}
+
+// dbTx - транзакция базы данных. Изменения копятся в памяти и попадают в
+// журнал одной записью, когда транзакция завершается успешно.
+type dbTx struct {
+	db      *DB
+	puts    map[int]TrackMetadata
+	deleted map[int]bool
+	nextID  int
+}
+
+// Get возвращает трек по ID с учетом изменений транзакции
+func (tx *dbTx) Get(id int) (TrackMetadata, error) {
+	if track, ok := tx.puts[id]; ok {
+		return track, nil
+	}
+	if track, ok := tx.db.tracks[id]; ok && !tx.deleted[id] {
+		return track, nil
+	}
+	return TrackMetadata{}, fmt.Errorf("трека с ID %d не найдено", id)
+}
+
+// List возвращает все треки с учетом изменений транзакции
+func (tx *dbTx) List() ([]TrackMetadata, error) {
+	tracks := tx.db.sorted(func(id int) bool {
+		_, replaced := tx.puts[id]
+		return !replaced && !tx.deleted[id]
+	})
+	for _, track := range tx.puts {
+		tracks = append(tracks, track)
+	}
+	slices.SortFunc(tracks, func(a, b TrackMetadata) int { return a.ID - b.ID })
+	return tracks, nil
+}
+
+// Query находит треки с учетом изменений транзакции
+func (tx *dbTx) Query(q Query) ([]TrackMetadata, error) {
+	tracks, err := tx.List()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tracks, func(track TrackMetadata) bool { return !q.Match(track) }), nil
+}
+
+// Put сохраняет трек в транзакции
+func (tx *dbTx) Put(track *TrackMetadata) error {
+	if track.ID == 0 {
+		track.ID = tx.nextID
+	}
+	if track.UID == "" {
+		if existing, err := tx.Get(track.ID); err == nil {
+			track.UID = existing.UID
+		} else {
+			track.UID = NewUID()
+		}
+	}
+	tx.nextID = max(tx.nextID, track.ID+1)
+	delete(tx.deleted, track.ID)
+	tx.puts[track.ID] = *track
+	return nil
+}
+
+// Delete удаляет трек в транзакции
+func (tx *dbTx) Delete(id int) error {
+	if _, err := tx.Get(id); err != nil {
+		return err
+	}
+	delete(tx.puts, id)
+	if _, ok := tx.db.tracks[id]; ok {
+		tx.deleted[id] = true
+	}
+	return nil
+}
+
+// record собирает запись журнала из изменений транзакции
+func (tx *dbTx) record() dbRecord {
+	record := dbRecord{NextID: tx.nextID}
+	for _, track := range tx.puts {
+		record.Puts = append(record.Puts, track)
+	}
+	slices.SortFunc(record.Puts, func(a, b TrackMetadata) int { return a.ID - b.ID })
+	for id := range tx.deleted {
+		record.Deletes = append(record.Deletes, id)
+	}
+	slices.Sort(record.Deletes)
+	return record
+}
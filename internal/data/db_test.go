@@ -0,0 +1,197 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDBPutQueryDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.db")
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+
+	tracks := []TrackMetadata{
+		{Artist: "The Beatles", Title: "Hey Jude"},
+		{Artist: "Queen", Title: "Bohemian Rhapsody", SourceURL: "https://youtu.be/queen"},
+		{Artist: "the beatles", Title: "Let It Be"},
+	}
+	for i := range tracks {
+		if err := db.Put(&tracks[i]); err != nil {
+			t.Fatalf("Ошибка сохранения трека: %v", err)
+		}
+	}
+	if tracks[2].ID != 3 {
+		t.Errorf("Ожидался ID 3, получено %d", tracks[2].ID)
+	}
+
+	found, err := db.Query(Query{Artist: "THE BEATLES"})
+	if err != nil || len(found) != 2 || found[1].Title != "Let It Be" {
+		t.Errorf("Неверный результат поиска по исполнителю: %+v, %v", found, err)
+	}
+	found, _ = db.Query(Query{SourceURL: "https://youtu.be/queen"})
+	if len(found) != 1 || found[0].ID != 2 {
+		t.Errorf("Неверный результат поиска по источнику: %+v", found)
+	}
+
+	tracks[0].Title = "Hey Jude (Remastered)"
+	if err := db.Put(&tracks[0]); err != nil {
+		t.Fatalf("Ошибка обновления трека: %v", err)
+	}
+	if found, _ := db.Query(Query{Title: "Hey Jude"}); len(found) != 0 {
+		t.Errorf("Индекс названия не обновлен: %+v", found)
+	}
+	if err := db.Delete(3); err != nil {
+		t.Fatalf("Ошибка удаления трека: %v", err)
+	}
+	if err := db.Delete(3); err == nil {
+		t.Error("Ожидалась ошибка удаления несуществующего трека")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Ошибка закрытия базы данных: %v", err)
+	}
+
+	// После повторного открытия данные и счетчик ID сохраняются
+	db, err = OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer db.Close()
+	list, _ := db.List()
+	if len(list) != 2 || list[0].Title != "Hey Jude (Remastered)" {
+		t.Errorf("Неверные треки после повторного открытия: %+v", list)
+	}
+	track := TrackMetadata{Title: "New"}
+	if err := db.Put(&track); err != nil || track.ID != 4 {
+		t.Errorf("ID удаленного трека не должен использоваться повторно: %d, %v", track.ID, err)
+	}
+}
+
+func TestDBUpdateRollback(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer db.Close()
+
+	failed := errors.New("отмена")
+	err = db.Update(func(tx Tx) error {
+		if err := tx.Put(&TrackMetadata{Title: "One"}); err != nil {
+			return err
+		}
+		if tracks, _ := tx.List(); len(tracks) != 1 {
+			t.Errorf("Трек не виден внутри транзакции: %+v", tracks)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Ожидалась ошибка транзакции, получено %v", err)
+	}
+	if tracks, _ := db.List(); len(tracks) != 0 {
+		t.Errorf("Изменения отмененной транзакции применены: %+v", tracks)
+	}
+}
+
+func TestDBRecoversTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.db")
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	if err := db.Put(&TrackMetadata{Title: "Safe"}); err != nil {
+		t.Fatalf("Ошибка сохранения трека: %v", err)
+	}
+	db.Close()
+
+	// Запись прервалась на середине: в конце файла неполная запись
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Ошибка открытия файла: %v", err)
+	}
+	_, _ = file.Write([]byte{0xff, 0x00, 0x00, 0x00, 0x01, 0x02})
+	file.Close()
+
+	db, err = OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных после сбоя: %v", err)
+	}
+	defer db.Close()
+	track := TrackMetadata{Title: "After"}
+	if err := db.Put(&track); err != nil {
+		t.Fatalf("Ошибка сохранения трека: %v", err)
+	}
+	if tracks, _ := db.List(); len(tracks) != 2 || tracks[0].Title != "Safe" || tracks[1].Title != "After" {
+		t.Errorf("Неверные треки после восстановления: %+v", tracks)
+	}
+}
+
+func TestDBSeesOtherProcessWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.db")
+	first, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer first.Close()
+	second, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer second.Close()
+
+	one, two := TrackMetadata{Title: "One"}, TrackMetadata{Title: "Two"}
+	if err := first.Put(&one); err != nil {
+		t.Fatalf("Ошибка сохранения трека: %v", err)
+	}
+	if err := second.Put(&two); err != nil {
+		t.Fatalf("Ошибка сохранения трека: %v", err)
+	}
+	if one.ID == two.ID {
+		t.Errorf("Треки из разных процессов получили одинаковый ID %d", one.ID)
+	}
+	if tracks, _ := first.List(); len(tracks) != 2 {
+		t.Errorf("Изменения другого процесса не видны: %+v", tracks)
+	}
+}
+
+func TestDBCompactsJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.db")
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	keep := TrackMetadata{Artist: "Keep", Title: "Me"}
+	if err := db.Put(&keep); err != nil {
+		t.Fatalf("Ошибка сохранения трека: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		track := TrackMetadata{Title: "Temporary"}
+		if err := db.Put(&track); err != nil {
+			t.Fatalf("Ошибка сохранения трека: %v", err)
+		}
+		if err := db.Delete(track.ID); err != nil {
+			t.Fatalf("Ошибка удаления трека: %v", err)
+		}
+	}
+	db.Close()
+	before, _ := os.Stat(path)
+
+	db, err = OpenDB(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer db.Close()
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Журнал не уплотнен: %d -> %d байт", before.Size(), after.Size())
+	}
+	if found, _ := db.Query(Query{Artist: "keep"}); len(found) != 1 || found[0].ID != keep.ID {
+		t.Errorf("Трек потерян при уплотнении: %+v", found)
+	}
+	track := TrackMetadata{Title: "Next"}
+	if err := db.Put(&track); err != nil || track.ID != 52 {
+		t.Errorf("Счетчик ID не сохранен при уплотнении: %d, %v", track.ID, err)
+	}
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DuplicateReason - признак, по которому треки считаются дубликатами
+type DuplicateReason string
+
+const (
+	// DuplicateContent - одинаковый хеш содержимого файла
+	DuplicateContent DuplicateReason = "sha256"
+	// DuplicateSource - одинаковый URL источника
+	DuplicateSource DuplicateReason = "source_url"
+)
+
+// DuplicateGroup - группа треков с одинаковым содержимым или источником
+type DuplicateGroup struct {
+	Reason DuplicateReason
+	Value  string          // Общий хеш или URL источника
+	Tracks []TrackMetadata // Треки по возрастанию ID
+}
+
+// FindDuplicates находит в библиотеке группы дубликатов. Сначала треки
+// группируются по хешу содержимого, затем по URL источника; группа по
+// источнику не выводится, если все ее треки уже попали в одну группу по
+// хешу. Треки без хеша и без источника не сравниваются.
+func FindDuplicates(tracks []TrackMetadata) []DuplicateGroup {
+	var groups []DuplicateGroup
+	contentGroup := make(map[int]int) // ID трека -> номер группы по хешу
+
+	for _, group := range groupTracks(tracks, func(track TrackMetadata) string { return track.SHA256 }) {
+		for _, track := range group {
+			contentGroup[track.ID] = len(groups)
+		}
+		groups = append(groups, DuplicateGroup{Reason: DuplicateContent, Value: group[0].SHA256, Tracks: group})
+	}
+
+	for _, group := range groupTracks(tracks, func(track TrackMetadata) string { return track.SourceURL }) {
+		first, ok := contentGroup[group[0].ID]
+		covered := ok && !slices.ContainsFunc(group, func(track TrackMetadata) bool {
+			index, ok := contentGroup[track.ID]
+			return !ok || index != first
+		})
+		if !covered {
+			groups = append(groups, DuplicateGroup{Reason: DuplicateSource, Value: group[0].SourceURL, Tracks: group})
+		}
+	}
+
+	return groups
+}
+
+// groupTracks группирует треки с одинаковым непустым значением ключа и
+// возвращает группы из двух и более треков в порядке первого трека
+func groupTracks(tracks []TrackMetadata, key func(TrackMetadata) string) [][]TrackMetadata {
+	sorted := slices.Clone(tracks)
+	slices.SortFunc(sorted, func(a, b TrackMetadata) int { return a.ID - b.ID })
+
+	var order []string
+	byKey := make(map[string][]TrackMetadata)
+	for _, track := range sorted {
+		value := indexKey(key(track))
+		if value == "" {
+			continue
+		}
+		if _, ok := byKey[value]; !ok {
+			order = append(order, value)
+		}
+		byKey[value] = append(byKey[value], track)
+	}
+
+	var groups [][]TrackMetadata
+	for _, value := range order {
+		if len(byKey[value]) > 1 {
+			groups = append(groups, byKey[value])
+		}
+	}
+	return groups
+}
+
+// FindDuplicatesOf возвращает треки хранилища с тем же хешем содержимого
+// или тем же URL источника по возрастанию ID. Пустые значения не ищутся.
+func FindDuplicatesOf(tx Tx, sha256, sourceURL string) ([]TrackMetadata, error) {
+	var found []TrackMetadata
+	seen := make(map[int]bool)
+	for _, q := range []Query{{SHA256: sha256}, {SourceURL: sourceURL}} {
+		if q == (Query{}) {
+			continue
+		}
+		tracks, err := tx.Query(q)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка поиска дубликатов: %w", err)
+		}
+		for _, track := range tracks {
+			if !seen[track.ID] {
+				seen[track.ID] = true
+				found = append(found, track)
+			}
+		}
+	}
+	slices.SortFunc(found, func(a, b TrackMetadata) int { return a.ID - b.ID })
+	return found, nil
+}
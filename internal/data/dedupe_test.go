@@ -0,0 +1,60 @@
+package data
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	tracks := []TrackMetadata{
+		{ID: 1, Title: "Mix", SHA256: "aaa", SourceURL: "https://youtu.be/mix"},
+		{ID: 2, Title: "Other", SHA256: "bbb"},
+		{ID: 3, Title: "Mix (copy)", SHA256: "AAA", SourceURL: "https://youtu.be/mix"},
+		{ID: 4, Title: "Mix (re-encoded)", SHA256: "ccc", SourceURL: "https://youtu.be/mix"},
+		{ID: 5, Title: "No hash"},
+		{ID: 6, Title: "No hash either"},
+	}
+
+	groups := FindDuplicates(tracks)
+	if len(groups) != 2 {
+		t.Fatalf("Ожидалось 2 группы дубликатов, получено %d: %+v", len(groups), groups)
+	}
+	if groups[0].Reason != DuplicateContent || len(groups[0].Tracks) != 2 || groups[0].Tracks[1].ID != 3 {
+		t.Errorf("Неверная группа по хешу: %+v", groups[0])
+	}
+	// Трек 4 с другим хешем из того же источника выводится группой по источнику
+	if groups[1].Reason != DuplicateSource || len(groups[1].Tracks) != 3 {
+		t.Errorf("Неверная группа по источнику: %+v", groups[1])
+	}
+
+	// Источник, все треки которого уже в одной группе по хешу, не повторяется
+	if groups := FindDuplicates(tracks[:3]); len(groups) != 1 {
+		t.Errorf("Ожидалась 1 группа дубликатов, получено %d: %+v", len(groups), groups)
+	}
+}
+
+func TestFindDuplicatesOf(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("Ошибка открытия базы данных: %v", err)
+	}
+	defer db.Close()
+
+	for _, track := range []TrackMetadata{
+		{Title: "Mix", SHA256: "aaa"},
+		{Title: "Video", SourceURL: "https://youtu.be/video"},
+		{Title: "Other", SHA256: "bbb"},
+	} {
+		if err := db.Put(&track); err != nil {
+			t.Fatalf("Ошибка сохранения трека: %v", err)
+		}
+	}
+
+	found, err := FindDuplicatesOf(db, "AAA", "https://youtu.be/video")
+	if err != nil || len(found) != 2 || found[0].ID != 1 || found[1].ID != 2 {
+		t.Errorf("Неверные дубликаты: %+v, %v", found, err)
+	}
+	if found, _ := FindDuplicatesOf(db, "", ""); len(found) != 0 {
+		t.Errorf("Пустые значения не должны находить треки: %+v", found)
+	}
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// backupSuffix - суффикс резервной копии файла данных: перед каждой
+// записью в нее попадает предыдущее содержимое файла
+const backupSuffix = ".bak"
+
+// lockSuffix - суффикс файла блокировки рядом с файлом данных. Блокируется
+// отдельный файл, потому что сам файл данных заменяется при каждой записи.
+const lockSuffix = ".lock"
+
+// lockDataFile берет эксклюзивную рекомендательную блокировку файла данных
+// и возвращает функцию для ее снятия. Вызов ждет, пока блокировку не
+// освободит другой процесс snatcher.
+func lockDataFile(path string) (func(), error) {
+	file, err := os.OpenFile(path+lockSuffix, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла блокировки: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("ошибка блокировки файла данных: %w", err)
+	}
+	return func() {
+		_ = unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// writeFileAtomic записывает файл так, чтобы при сбое на диске осталось
+// либо старое, либо новое содержимое целиком: данные пишутся во временный
+// файл в той же директории, сбрасываются на диск и переименовываются.
+func writeFileAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания временного файла: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // После переименования удалять уже нечего
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи временного файла: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка сброса временного файла на диск: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка закрытия временного файла: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("ошибка установки прав временного файла: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ошибка замены файла: %w", err)
+	}
+
+	// Переименование надежно только после сброса на диск самой директории
+	if dirFile, err := os.Open(dir); err == nil {
+		_ = dirFile.Sync()
+		dirFile.Close()
+	}
+	return nil
+}
+
+// readDataFile читает файл данных. Отсутствующий файл читается как пустой.
+func readDataFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ошибка чтения файла данных: %w", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package data
+
+import "os"
+
+// lockFile ничего не делает: на этой платформе flock недоступен, и от
+// потери данных защищает только слияние изменений при сохранении
+func lockFile(*os.File) error {
+	return nil
+}
+
+// unlockFile ничего не делает
+func unlockFile(*os.File) error {
+	return nil
+}
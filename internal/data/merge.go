@@ -0,0 +1,106 @@
+package data
+
+import (
+	"reflect"
+)
+
+// mergeData сливает изменения этого процесса с изменениями, которые другой
+// процесс успел записать в файл данных. base - данные на момент загрузки,
+// ours - данные этого процесса, theirs - текущее содержимое файла.
+//
+// Трек, который этот процесс изменил, берется из ours, иначе из theirs.
+// Удаленный любой из сторон трек удаляется. Треки, добавленные этим
+// процессом, дописываются в конец; если их ID уже заняты треками из
+// theirs, им выдаются новые ID, и позиции воспроизведения переезжают
+// вместе с ними.
+func mergeData(base, ours, theirs *AppData) *AppData {
+	baseTracks := tracksByID(base.Tracks)
+	ourTracks := tracksByID(ours.Tracks)
+
+	merged := &AppData{Tracks: make([]TrackMetadata, 0, len(theirs.Tracks)+len(ours.Tracks))}
+	taken := make(map[int]bool)
+	maxID := 0
+	for _, track := range theirs.Tracks {
+		baseTrack, inBase := baseTracks[track.ID]
+		ourTrack, inOurs := ourTracks[track.ID]
+		switch {
+		case inBase && !inOurs:
+			continue // Удален этим процессом
+		case inBase && !reflect.DeepEqual(ourTrack, baseTrack):
+			track = ourTrack
+		}
+		merged.Tracks = append(merged.Tracks, track)
+		taken[track.ID] = true
+		maxID = max(maxID, track.ID)
+	}
+
+	// Треки, добавленные этим процессом
+	var added []TrackMetadata
+	for _, track := range ours.Tracks {
+		if _, inBase := baseTracks[track.ID]; !inBase {
+			added = append(added, track)
+			maxID = max(maxID, track.ID)
+		}
+	}
+	renumbered := make(map[int]int)
+	for _, track := range added {
+		if taken[track.ID] {
+			maxID++
+			renumbered[track.ID] = maxID
+			track.ID = maxID
+		}
+		merged.Tracks = append(merged.Tracks, track)
+	}
+
+	merged.NextID = max(ours.NextID, theirs.NextID, maxID+1)
+
+	merged.Volume = theirs.Volume
+	if !reflect.DeepEqual(ours.Volume, base.Volume) {
+		merged.Volume = ours.Volume
+	}
+
+	merged.Positions = mergePositions(base.Positions, ours.Positions, theirs.Positions, renumbered)
+	mergedTracks := tracksByID(merged.Tracks)
+	for id := range merged.Positions {
+		if _, ok := mergedTracks[id]; !ok {
+			delete(merged.Positions, id)
+		}
+	}
+	return merged
+}
+
+// mergePositions сливает позиции воспроизведения: позиция, которую этот
+// процесс изменил или удалил, берется из ours, остальные - из theirs
+func mergePositions(base, ours, theirs map[int]int, renumbered map[int]int) map[int]int {
+	merged := make(map[int]int)
+	for id, seconds := range theirs {
+		merged[id] = seconds
+	}
+	for id := range base {
+		if _, ok := ours[id]; !ok {
+			delete(merged, id)
+		}
+	}
+	for id, seconds := range ours {
+		if baseSeconds, ok := base[id]; ok && baseSeconds == seconds {
+			continue
+		}
+		if newID, ok := renumbered[id]; ok {
+			id = newID
+		}
+		merged[id] = seconds
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// tracksByID строит индекс треков по ID
+func tracksByID(tracks []TrackMetadata) map[int]TrackMetadata {
+	index := make(map[int]TrackMetadata, len(tracks))
+	for _, track := range tracks {
+		index[track.ID] = track
+	}
+	return index
+}
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion - версия схемы файла данных, которую понимает эта версия
+// snatcher. Файлы без поля schema_version имеют версию 0.
+const SchemaVersion = 2
+
+// migration обновляет документ файла данных со схемы From на From+1.
+// Документ - разобранный YAML, поэтому миграция может переименовывать и
+// переносить поля, о которых структуры пакета уже не знают.
+type migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]any) error
+}
+
+// migrations - реестр миграций по порядку версий. Каждая новая версия схемы
+// добавляет сюда шаг и увеличивает SchemaVersion.
+var migrations = []migration{
+	{
+		From:        0,
+		Description: "номер версии схемы в файле данных",
+		Apply:       func(map[string]any) error { return nil },
+	},
+	{
+		From:        1,
+		Description: "UID треков и счетчик ID",
+		Apply:       assignTrackUIDs,
+	},
+}
+
+// schemaVersionKey - поле файла данных с версией схемы
+const schemaVersionKey = "schema_version"
+
+// parseDocument разбирает файл данных в документ и возвращает его версию
+// схемы. Пустой файл считается файлом текущей версии.
+func parseDocument(content []byte) (map[string]any, int, error) {
+	doc := make(map[string]any)
+	if len(content) == 0 {
+		return doc, SchemaVersion, nil
+	}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, 0, fmt.Errorf("ошибка разбора данных: %w", err)
+	}
+	if doc == nil {
+		return make(map[string]any), SchemaVersion, nil
+	}
+
+	version := 0
+	if value, ok := doc[schemaVersionKey]; ok {
+		if version, ok = value.(int); !ok {
+			return nil, 0, fmt.Errorf("неверная версия схемы файла данных: %v", value)
+		}
+	}
+	if version > SchemaVersion {
+		return nil, 0, fmt.Errorf("файл данных создан более новой версией snatcher (версия схемы %d, поддерживается до %d): обновите snatcher", version, SchemaVersion)
+	}
+	return doc, version, nil
+}
+
+// migrateDocument последовательно применяет миграции к документу версии
+// version и возвращает документ текущей версии
+func migrateDocument(doc map[string]any, version int) error {
+	for ; version < SchemaVersion; version++ {
+		step := migrations[version]
+		if step.From != version {
+			return fmt.Errorf("нет миграции файла данных с версии %d", version)
+		}
+		if err := step.Apply(doc); err != nil {
+			return fmt.Errorf("ошибка миграции файла данных с версии %d (%s): %w", version, step.Description, err)
+		}
+		doc[schemaVersionKey] = version + 1
+	}
+	return nil
+}
+
+// migrateDataFile обновляет файл данных до текущей версии схемы и
+// возвращает его новое содержимое. Перед миграцией исходный файл
+// копируется в резервную копию <файл>.v<версия>.bak, которую не
+// перезаписывают следующие сохранения.
+func migrateDataFile(path string) ([]byte, error) {
+	unlock, err := lockDataFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Пока файл не был заблокирован, его могла обновить другая команда
+	content, err := readDataFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, version, err := parseDocument(content)
+	if err != nil || version == SchemaVersion {
+		return content, err
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d%s", path, version, backupSuffix)
+	if err := writeFileAtomic(backupPath, content); err != nil {
+		return nil, fmt.Errorf("ошибка записи резервной копии перед миграцией: %w", err)
+	}
+	if err := migrateDocument(doc, version); err != nil {
+		return nil, err
+	}
+
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации данных: %w", err)
+	}
+	if err := writeFileAtomic(path, migrated); err != nil {
+		return nil, fmt.Errorf("ошибка записи файла данных: %w", err)
+	}
+	return migrated, nil
+}
+
+// assignTrackUIDs выдает UID трекам без него и заводит счетчик ID, который
+// продолжает самый большой из существующих ID
+func assignTrackUIDs(doc map[string]any) error {
+	tracks, _ := doc["tracks"].([]any)
+	maxID := 0
+	for _, item := range tracks {
+		track, ok := item.(map[string]any)
+		if !ok {
+			return fmt.Errorf("неверная запись трека: %v", item)
+		}
+		if id, ok := track["id"].(int); ok {
+			maxID = max(maxID, id)
+		}
+		if uid, _ := track["uid"].(string); uid == "" {
+			track["uid"] = NewUID()
+		}
+	}
+	doc["next_id"] = maxID + 1
+	return nil
+}
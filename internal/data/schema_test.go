@@ -0,0 +1,89 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	if len(migrations) != SchemaVersion {
+		t.Fatalf("Ожидалось %d миграций, зарегистрировано %d", SchemaVersion, len(migrations))
+	}
+	for version, step := range migrations {
+		if step.From != version {
+			t.Errorf("Миграция %d начинается с версии %d", version, step.From)
+		}
+	}
+}
+
+func TestLoadDataMigratesSchema(t *testing.T) {
+	legacy := `tracks:
+    - id: 1
+      artist: Test Artist
+      title: Test Title
+      album: ""
+      length: 180
+      file_size: 1024
+      url: https://example.com/test.mp3
+      source_url: ""
+volume: 70
+`
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Ошибка создания файла данных: %v", err)
+	}
+
+	appData := NewAppData()
+	if err := appData.LoadData(path); err != nil {
+		t.Fatalf("Ошибка загрузки данных: %v", err)
+	}
+	if len(appData.Tracks) != 1 || appData.Volume == nil || *appData.Volume != 70 {
+		t.Fatalf("Данные потеряны при миграции: %+v", appData)
+	}
+	if appData.SchemaVersion != SchemaVersion {
+		t.Errorf("Ожидалась версия схемы %d, получено %d", SchemaVersion, appData.SchemaVersion)
+	}
+	if !IsUID(appData.Tracks[0].UID) {
+		t.Errorf("Треку не выдан UID при миграции: %q", appData.Tracks[0].UID)
+	}
+	if appData.NextID != 2 {
+		t.Errorf("Ожидался счетчик ID 2, получено %d", appData.NextID)
+	}
+
+	// Исходный файл сохранен перед миграцией, а файл данных обновлен
+	backup, err := os.ReadFile(path + ".v0" + backupSuffix)
+	if err != nil || string(backup) != legacy {
+		t.Errorf("Резервная копия перед миграцией не создана: %q, %v", backup, err)
+	}
+	migrated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Ошибка чтения файла данных: %v", err)
+	}
+	if !strings.Contains(string(migrated), fmt.Sprintf("schema_version: %d", SchemaVersion)) {
+		t.Errorf("Версия схемы не записана в файл данных:\n%s", migrated)
+	}
+}
+
+func TestLoadDataRefusesNewerSchema(t *testing.T) {
+	newer := "schema_version: 999\ntracks: []\nplaylists: [favorites]\n"
+	path := filepath.Join(t.TempDir(), "data.yaml")
+	if err := os.WriteFile(path, []byte(newer), 0644); err != nil {
+		t.Fatalf("Ошибка создания файла данных: %v", err)
+	}
+
+	appData := NewAppData()
+	if err := appData.LoadData(path); err == nil || !strings.Contains(err.Error(), "более новой версией") {
+		t.Fatalf("Ожидалась ошибка для файла более новой версии, получено %v", err)
+	}
+
+	// Сохранение поверх файла новой версии тоже отклоняется
+	if err := appData.SaveData(path); err == nil {
+		t.Error("Ожидалась ошибка сохранения поверх файла более новой версии")
+	}
+	if content, _ := os.ReadFile(path); string(content) != newer {
+		t.Errorf("Файл более новой версии изменен:\n%s", content)
+	}
+}
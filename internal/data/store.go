@@ -0,0 +1,149 @@
+package data
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Store - хранилище библиотеки треков. Есть две реализации: AppData
+// хранит треки в YAML-файле данных вместе с настройками плеера, DB - во
+// встроенной базе данных с индексами (см. OpenDB).
+type Store interface {
+	Tx
+
+	// Update выполняет fn в транзакции: изменения, сделанные через tx,
+	// применяются целиком, если fn вернула nil, и отбрасываются, если она
+	// вернула ошибку
+	Update(fn func(tx Tx) error) error
+
+	// Close закрывает хранилище
+	Close() error
+}
+
+// Tx - операции с треками хранилища (или транзакции)
+type Tx interface {
+	// Get возвращает трек по ID
+	Get(id int) (TrackMetadata, error)
+	// List возвращает все треки по возрастанию ID
+	List() ([]TrackMetadata, error)
+	// Query возвращает треки, подходящие под условия запроса
+	Query(q Query) ([]TrackMetadata, error)
+	// Put сохраняет трек. Трек с нулевым ID добавляется с новым ID, который
+	// записывается в track; трек с ненулевым ID заменяет трек с тем же ID
+	// или добавляется с этим ID, если такого трека нет.
+	Put(track *TrackMetadata) error
+	// Delete удаляет трек по ID
+	Delete(id int) error
+}
+
+// Query - условия поиска треков. Пустые поля не учитываются, заполненные
+// сравниваются без учета регистра и пробелов по краям.
+type Query struct {
+	UID       string
+	Artist    string
+	Title     string
+	SourceURL string
+	SHA256    string
+}
+
+// Match проверяет, что трек подходит под условия запроса
+func (q Query) Match(track TrackMetadata) bool {
+	return matchField(q.UID, track.UID) &&
+		matchField(q.Artist, track.Artist) &&
+		matchField(q.Title, track.Title) &&
+		matchField(q.SourceURL, track.SourceURL) &&
+		matchField(q.SHA256, track.SHA256)
+}
+
+// matchField сравнивает поле трека с условием запроса
+func matchField(want, value string) bool {
+	return want == "" || indexKey(want) == indexKey(value)
+}
+
+// indexKey нормализует значение поля для сравнения и индексов
+func indexKey(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// AppData реализует Store. Изменения остаются в памяти, пока не будет
+// вызван SaveData.
+var _ Store = (*AppData)(nil)
+
+// Get возвращает копию трека по ID
+func (d *AppData) Get(id int) (TrackMetadata, error) {
+	track, err := d.TrackByID(id)
+	if err != nil {
+		return TrackMetadata{}, err
+	}
+	return *track, nil
+}
+
+// List возвращает копию списка треков по возрастанию ID
+func (d *AppData) List() ([]TrackMetadata, error) {
+	tracks := slices.Clone(d.Tracks)
+	slices.SortStableFunc(tracks, func(a, b TrackMetadata) int { return a.ID - b.ID })
+	return tracks, nil
+}
+
+// Query возвращает треки, подходящие под условия запроса
+func (d *AppData) Query(q Query) ([]TrackMetadata, error) {
+	var tracks []TrackMetadata
+	for _, track := range d.Tracks {
+		if q.Match(track) {
+			tracks = append(tracks, track)
+		}
+	}
+	slices.SortStableFunc(tracks, func(a, b TrackMetadata) int { return a.ID - b.ID })
+	return tracks, nil
+}
+
+// Put сохраняет трек в памяти
+func (d *AppData) Put(track *TrackMetadata) error {
+	if track.ID == 0 {
+		d.AddTrack(*track)
+		*track = d.Tracks[len(d.Tracks)-1]
+		return nil
+	}
+	existing, err := d.TrackByID(track.ID)
+	if err != nil {
+		if track.UID == "" {
+			track.UID = NewUID()
+		}
+		d.Tracks = append(d.Tracks, *track)
+		d.NextID = max(d.NextID, track.ID+1)
+		return nil
+	}
+	if track.UID == "" {
+		track.UID = existing.UID
+	}
+	return d.UpdateTrack(*track)
+}
+
+// Delete удаляет трек вместе с его позицией воспроизведения
+func (d *AppData) Delete(id int) error {
+	return d.DeleteTrackByID(id)
+}
+
+// Update выполняет fn над данными в памяти и при ошибке возвращает треки,
+// счетчик ID и позиции воспроизведения к состоянию до вызова
+func (d *AppData) Update(fn func(tx Tx) error) error {
+	tracks, nextID := slices.Clone(d.Tracks), d.NextID
+	d.mu.Lock()
+	positions := maps.Clone(d.Positions)
+	d.mu.Unlock()
+
+	if err := fn(d); err != nil {
+		d.Tracks, d.NextID = tracks, nextID
+		d.mu.Lock()
+		d.Positions = positions
+		d.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Close ничего не делает: данные записываются в файл через SaveData
+func (d *AppData) Close() error {
+	return nil
+}
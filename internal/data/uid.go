@@ -0,0 +1,74 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// crockford - алфавит Crockford Base32, которым записываются ULID
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// uidLength - длина ULID в символах
+const uidLength = 26
+
+// NewUID возвращает новый уникальный идентификатор трека в формате ULID:
+// 48 бит времени создания в миллисекундах и 80 случайных бит, записанные
+// 26 символами Crockford Base32. В отличие от числового ID он не зависит
+// от библиотеки и никогда не используется повторно.
+func NewUID() string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(time.Now().UnixMilli())<<16)
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("ошибка генерации UID: %v", err))
+	}
+
+	// 128 бит записываются 26 символами по 5 бит: в первом символе 3 бита
+	var b strings.Builder
+	b.Grow(uidLength)
+	for i := 0; i < uidLength; i++ {
+		value := 0
+		for bit := i*5 - 2; bit < i*5+3; bit++ {
+			value <<= 1
+			if bit >= 0 && id[bit/8]&(0x80>>(bit%8)) != 0 {
+				value |= 1
+			}
+		}
+		b.WriteByte(crockford[value])
+	}
+	return b.String()
+}
+
+// IsUID проверяет, что строка похожа на ULID
+func IsUID(s string) bool {
+	if len(s) != uidLength || s[0] > '7' {
+		return false
+	}
+	for _, r := range strings.ToUpper(s) {
+		if !strings.ContainsRune(crockford, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindTrack находит трек по числовому ID или по UID
+func FindTrack(tx Tx, ref string) (TrackMetadata, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return tx.Get(id)
+	}
+	if !IsUID(ref) {
+		return TrackMetadata{}, fmt.Errorf("неверный ID трека '%s': укажите числовой ID или UID", ref)
+	}
+	tracks, err := tx.Query(Query{UID: ref})
+	if err != nil {
+		return TrackMetadata{}, err
+	}
+	if len(tracks) == 0 {
+		return TrackMetadata{}, fmt.Errorf("трека с UID %s не найдено", ref)
+	}
+	return tracks[0], nil
+}
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewUID(t *testing.T) {
+	first, second := NewUID(), NewUID()
+	if !IsUID(first) || !IsUID(second) {
+		t.Fatalf("Неверный формат UID: %s, %s", first, second)
+	}
+	if first == second {
+		t.Error("UID должны быть уникальными")
+	}
+	// Первые 10 символов - время создания, поэтому UID упорядочены по времени
+	if first[:10] > second[:10] {
+		t.Errorf("UID не упорядочены по времени: %s > %s", first, second)
+	}
+
+	for _, invalid := range []string{"", "123", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "01ARZ3NDEKTSV4RRFFQ69G5FAU"} {
+		if IsUID(invalid) {
+			t.Errorf("Строка %q не должна считаться UID", invalid)
+		}
+	}
+}
+
+func TestAddTrackNeverReusesID(t *testing.T) {
+	appData := NewAppData()
+	appData.AddTrack(TrackMetadata{Title: "One"})
+	appData.AddTrack(TrackMetadata{Title: "Two"})
+	deleted := appData.Tracks[1]
+	if err := appData.DeleteTrackByID(deleted.ID); err != nil {
+		t.Fatalf("Ошибка удаления трека: %v", err)
+	}
+
+	appData.AddTrack(TrackMetadata{Title: "Three"})
+	added := appData.Tracks[1]
+	if added.ID != 3 {
+		t.Errorf("ID удаленного трека использован повторно: %d", added.ID)
+	}
+	if added.UID == "" || added.UID == deleted.UID {
+		t.Errorf("Новому треку нужен новый UID: %q", added.UID)
+	}
+}
+
+func TestFindTrack(t *testing.T) {
+	appData := NewAppData()
+	appData.AddTrack(TrackMetadata{Title: "One"})
+	appData.AddTrack(TrackMetadata{Title: "Two"})
+	uid := appData.Tracks[1].UID
+
+	for _, ref := range []string{"2", uid, strings.ToLower(uid)} {
+		track, err := FindTrack(appData, ref)
+		if err != nil || track.Title != "Two" {
+			t.Errorf("Трек не найден по %q: %+v, %v", ref, track, err)
+		}
+	}
+	if _, err := FindTrack(appData, NewUID()); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного UID")
+	}
+	if _, err := FindTrack(appData, "abc"); err == nil {
+		t.Error("Ожидалась ошибка для неверного ID")
+	}
+}
+
+func TestAppDataUpdateRollback(t *testing.T) {
+	appData := NewAppData()
+	appData.AddTrack(TrackMetadata{Title: "One"})
+
+	failed := errors.New("отмена")
+	err := appData.Update(func(tx Tx) error {
+		if err := tx.Put(&TrackMetadata{Title: "Two"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Ожидалась ошибка транзакции, получено %v", err)
+	}
+	if len(appData.Tracks) != 1 {
+		t.Errorf("Изменения отмененной транзакции применены: %+v", appData.Tracks)
+	}
+
+	// Отмененная транзакция не расходует ID
+	track := TrackMetadata{Title: "Three"}
+	if err := appData.Put(&track); err != nil || track.ID != 2 {
+		t.Errorf("Ожидался ID 2, получено %d, %v", track.ID, err)
+	}
+	// Put записывает в трек и выданный UID
+	if stored, _ := appData.Get(track.ID); !IsUID(track.UID) || track.UID != stored.UID {
+		t.Errorf("UID не записан в трек: %q, в хранилище %q", track.UID, stored.UID)
+	}
+}
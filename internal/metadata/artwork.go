@@ -0,0 +1,48 @@
+package metadata
+
+import (
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// artworkExts - расширения файлов обложек, которые ищутся рядом с аудиофайлом
+var artworkExts = []string{".jpg", ".jpeg", ".png"}
+
+// ArtworkPath возвращает путь к файлу обложки рядом с аудиофайлом: с тем
+// же именем и расширением ext (без точки)
+func ArtworkPath(audioPath, ext string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + "." + ext
+}
+
+// SidecarArtwork читает обложку из файла рядом с аудиофайлом. Так
+// сохраняется превью видео при скачивании с YouTube. Возвращает nil,
+// если файла обложки нет.
+func SidecarArtwork(audioPath string) *Artwork {
+	for _, ext := range artworkExts {
+		path := ArtworkPath(audioPath, strings.TrimPrefix(ext, "."))
+		data, err := os.ReadFile(path)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		return &Artwork{
+			MIMEType: mime.TypeByExtension(ext),
+			Ext:      strings.TrimPrefix(ext, "."),
+			Data:     data,
+		}
+	}
+	return nil
+}
+
+// FileExt возвращает расширение файла обложки без точки, определяя его
+// по MIME-типу, если расширение неизвестно
+func (a *Artwork) FileExt() string {
+	if a.Ext != "" {
+		return strings.ToLower(a.Ext)
+	}
+	if a.MIMEType == "image/png" {
+		return "png"
+	}
+	return "jpg"
+}
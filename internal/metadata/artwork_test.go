@@ -0,0 +1,31 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSidecarArtwork(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "mix.mp3")
+	if artwork := SidecarArtwork(audioPath); artwork != nil {
+		t.Fatalf("Обложки рядом с файлом нет, получено %+v", artwork)
+	}
+
+	coverPath := ArtworkPath(audioPath, "png")
+	if coverPath != filepath.Join(dir, "mix.png") {
+		t.Errorf("Неверный путь обложки: %s", coverPath)
+	}
+	if err := os.WriteFile(coverPath, []byte("\x89PNG"), 0644); err != nil {
+		t.Fatalf("Ошибка создания обложки: %v", err)
+	}
+
+	artwork := SidecarArtwork(audioPath)
+	if artwork == nil {
+		t.Fatal("Обложка рядом с файлом не найдена")
+	}
+	if artwork.MIMEType != "image/png" || artwork.FileExt() != "png" || string(artwork.Data) != "\x89PNG" {
+		t.Errorf("Неверная обложка: %s, %s, %q", artwork.MIMEType, artwork.FileExt(), artwork.Data)
+	}
+}
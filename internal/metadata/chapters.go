@@ -0,0 +1,214 @@
+package metadata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazadus/go-snatcher/internal/metadata/id3"
+)
+
+// Chapter - глава микса: трек, который начинается с указанной позиции
+type Chapter struct {
+	Start  time.Duration `yaml:"start"`
+	Artist string        `yaml:"artist,omitempty"`
+	Title  string        `yaml:"title"`
+}
+
+// chapterSidecarExts - расширения файлов с главами, которые ищутся рядом с
+// аудиофайлом: CUE-лист и текстовый треклист
+var chapterSidecarExts = []string{".cue", ".txt"}
+
+// tracklistLine - строка треклиста вида "00:00 Artist - Title". Перед
+// временем допускаются номер ("1." или "01)"), время может быть в скобках,
+// а после него - разделитель и время окончания ("00:00 - 03:15 Title").
+var tracklistLine = regexp.MustCompile(`^(?:\d+[.)]\s*)?[\[(]?((?:\d{1,2}:)?\d{1,3}:\d{2})[\])]?\s+(?:[-–—|:.]\s+)?(?:(?:\d{1,2}:)?\d{1,3}:\d{2}\s+(?:[-–—|:.]\s+)?)?(.+)$`)
+
+// titleSeparators - разделители исполнителя и названия в строке треклиста
+var titleSeparators = []string{" - ", " – ", " — "}
+
+// ParseTracklist разбирает текстовый треклист: каждая строка с временем в
+// начале ("00:00 Artist - Title", "1:02:03 Title") становится главой,
+// остальные строки пропускаются. Главы сортируются по времени начала.
+func ParseTracklist(text string) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(text, "\n") {
+		match := tracklistLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		start, err := parseTimestamp(match[1])
+		if err != nil {
+			continue
+		}
+		artist, title := splitArtistTitle(match[2])
+		if title == "" {
+			continue
+		}
+		chapters = append(chapters, Chapter{Start: start, Artist: artist, Title: title})
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	return chapters
+}
+
+// parseTimestamp разбирает время в формате "m:ss" или "h:mm:ss"
+func parseTimestamp(value string) (time.Duration, error) {
+	var total time.Duration
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("неверное время %q", value)
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, nil
+}
+
+// splitArtistTitle разделяет строку "Artist - Title". Без разделителя вся
+// строка считается названием.
+func splitArtistTitle(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	for _, separator := range titleSeparators {
+		if artist, title, ok := strings.Cut(value, separator); ok {
+			return strings.TrimSpace(artist), strings.TrimSpace(title)
+		}
+	}
+	return "", value
+}
+
+// ParseCue разбирает CUE-лист. Исполнитель из заголовка листа
+// используется для треков, у которых он не указан. Время начала берется из
+// INDEX 01 в формате "mm:ss:ff" (75 кадров в секунде).
+func ParseCue(r io.Reader) ([]Chapter, error) {
+	var chapters []Chapter
+	var albumArtist string
+	var current *Chapter
+	hasIndex := false
+
+	flush := func() {
+		if current != nil && hasIndex {
+			if current.Artist == "" {
+				current.Artist = albumArtist
+			}
+			chapters = append(chapters, *current)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		command, args, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		args = strings.TrimSpace(args)
+		switch strings.ToUpper(command) {
+		case "TRACK":
+			flush()
+			current, hasIndex = &Chapter{}, false
+		case "TITLE":
+			if current != nil {
+				current.Title = unquote(args)
+			}
+		case "PERFORMER":
+			if current != nil {
+				current.Artist = unquote(args)
+			} else {
+				albumArtist = unquote(args)
+			}
+		case "INDEX":
+			number, position, _ := strings.Cut(args, " ")
+			if current == nil || number != "01" {
+				continue
+			}
+			start, err := parseCueTime(strings.TrimSpace(position))
+			if err != nil {
+				return nil, err
+			}
+			current.Start, hasIndex = start, true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения CUE-листа: %w", err)
+	}
+	flush()
+	return chapters, nil
+}
+
+// parseCueTime разбирает время CUE-листа "mm:ss:ff"
+func parseCueTime(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("неверное время в CUE-листе: %q", value)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("неверное время в CUE-листе: %q", value)
+		}
+		numbers[i] = n
+	}
+	frames := time.Duration(numbers[2]) * time.Second / 75
+	return time.Duration(numbers[0])*time.Minute + time.Duration(numbers[1])*time.Second + frames, nil
+}
+
+// unquote убирает кавычки вокруг значения CUE-листа
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// ParseChapters разбирает главы из CUE-листа или текстового треклиста.
+// Формат определяется по содержимому: CUE-лист содержит команды TRACK.
+func ParseChapters(text string) ([]Chapter, error) {
+	if isCue(text) {
+		return ParseCue(strings.NewReader(text))
+	}
+	return ParseTracklist(text), nil
+}
+
+// isCue проверяет, похож ли текст на CUE-лист
+func isCue(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if command, _, _ := strings.Cut(strings.TrimSpace(line), " "); strings.EqualFold(command, "TRACK") {
+			return true
+		}
+	}
+	return false
+}
+
+// SidecarChapters читает главы из CUE-листа или текстового треклиста рядом
+// с аудиофайлом (с тем же именем). Возвращает nil, если таких файлов нет
+// или в них нет глав.
+func SidecarChapters(audioPath string) []Chapter {
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	for _, ext := range chapterSidecarExts {
+		text, err := os.ReadFile(base + ext)
+		if err != nil {
+			continue
+		}
+		if chapters, err := ParseChapters(string(text)); err == nil && len(chapters) > 0 {
+			return chapters
+		}
+	}
+	return nil
+}
+
+// id3Chapters читает главы из фреймов CHAP тега ID3v2
+func id3Chapters(src io.ReaderAt) []Chapter {
+	frames, err := id3.ReadChapters(src)
+	if err != nil {
+		return nil
+	}
+	var chapters []Chapter
+	for _, frame := range frames {
+		chapters = append(chapters, Chapter{Start: frame.Start, Artist: frame.Artist, Title: frame.Title})
+	}
+	return chapters
+}
@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTracklist(t *testing.T) {
+	text := `Tracklist:
+00:00 Intro
+1. 03:15 Artist One - First Track
+[1:02:03] Artist Two – Second Track
+10:30 - Artist Three - Third - Remix
+not a chapter 12:00
+`
+	chapters := ParseTracklist(text)
+	expected := []Chapter{
+		{Start: 0, Title: "Intro"},
+		{Start: 3*time.Minute + 15*time.Second, Artist: "Artist One", Title: "First Track"},
+		{Start: 10*time.Minute + 30*time.Second, Artist: "Artist Three", Title: "Third - Remix"},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second, Artist: "Artist Two", Title: "Second Track"},
+	}
+	if len(chapters) != len(expected) {
+		t.Fatalf("Ожидалось %d глав, получено %+v", len(expected), chapters)
+	}
+	for i := range expected {
+		if chapters[i] != expected[i] {
+			t.Errorf("Глава %d: ожидалось %+v, получено %+v", i, expected[i], chapters[i])
+		}
+	}
+}
+
+func TestParseCue(t *testing.T) {
+	cue := `PERFORMER "Mix Artist"
+TITLE "Live Mix"
+FILE "mix.mp3" MP3
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Peak"
+    PERFORMER "Guest"
+    INDEX 00 04:58:00
+    INDEX 01 05:00:37
+`
+	chapters, err := ParseChapters(cue)
+	if err != nil {
+		t.Fatalf("Ошибка разбора CUE-листа: %v", err)
+	}
+	expected := []Chapter{
+		{Start: 0, Artist: "Mix Artist", Title: "Opening"},
+		{Start: 5*time.Minute + 37*time.Second/75, Artist: "Guest", Title: "Peak"},
+	}
+	if len(chapters) != len(expected) {
+		t.Fatalf("Ожидалось %d главы, получено %+v", len(expected), chapters)
+	}
+	for i := range expected {
+		if chapters[i] != expected[i] {
+			t.Errorf("Глава %d: ожидалось %+v, получено %+v", i, expected[i], chapters[i])
+		}
+	}
+
+	if _, err := ParseCue(strings.NewReader("TRACK 01 AUDIO\nINDEX 01 5:00\n")); err == nil {
+		t.Error("Ожидалась ошибка для неверного времени в CUE-листе")
+	}
+}
+
+func TestSidecarChapters(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "mix.mp3")
+	if chapters := SidecarChapters(audioPath); chapters != nil {
+		t.Errorf("Без файла треклиста ожидался nil, получено %+v", chapters)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "mix.txt"), []byte("00:00 A - One\n02:00 B - Two\n"), 0644); err != nil {
+		t.Fatalf("Ошибка записи треклиста: %v", err)
+	}
+	chapters := SidecarChapters(audioPath)
+	if len(chapters) != 2 || chapters[1].Artist != "B" || chapters[1].Start != 2*time.Minute {
+		t.Errorf("Неверные главы из треклиста: %+v", chapters)
+	}
+}
+
+func TestParseTracklistFromDescription(t *testing.T) {
+	description := `Recorded live in Berlin.
+
+(0:00) Intro
+(05:12) Artist - Track
+12:40 - 18:00 Other Artist - Other Track
+
+Follow us: https://example.com`
+	chapters := ParseTracklist(description)
+	if len(chapters) != 3 {
+		t.Fatalf("Ожидалось 3 главы, получено %+v", chapters)
+	}
+	if chapters[1] != (Chapter{Start: 5*time.Minute + 12*time.Second, Artist: "Artist", Title: "Track"}) {
+		t.Errorf("Неверная глава в скобках: %+v", chapters[1])
+	}
+	if chapters[2] != (Chapter{Start: 12*time.Minute + 40*time.Second, Artist: "Other Artist", Title: "Other Track"}) {
+		t.Errorf("Неверная глава с временем окончания: %+v", chapters[2])
+	}
+}
@@ -4,26 +4,54 @@ package metadata
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dhowden/tag"
-	"github.com/gopxl/beep/mp3"
+
+	"github.com/hazadus/go-snatcher/internal/audio"
 )
 
 // TrackMetadata хранит метаданные трека
 type TrackMetadata struct {
-	Artist string
-	Title  string
-	Album  string
+	Artist      string
+	Title       string
+	Album       string
+	Year        int
+	Genre       string
+	Comment     string
+	TrackNumber int
+	BPM         int
+	Key         string    // Тональность (например, "Am" или "8A")
+	Artwork     *Artwork  // Встроенная обложка (nil, если ее нет)
+	Chapters    []Chapter // Главы микса (nil, если их нет)
+	SourceURL   string    // URL источника, откуда скачан файл
+	UploadDate  string    // Дата публикации источника (ГГГГ-ММ-ДД)
+}
+
+// Artwork содержит встроенную в файл обложку
+type Artwork struct {
+	MIMEType string
+	Ext      string // Расширение файла изображения без точки
+	Data     []byte
 }
 
+// Имена необработанных тегов с темпом и тональностью: кадры ID3v2.2,
+// ID3v2.3/2.4 и комментарии Vorbis (FLAC, Ogg)
+var (
+	bpmTags = []string{"TBP", "TBPM", "bpm", "tempo"}
+	keyTags = []string{"TKE", "TKEY", "initialkey", "key"}
+)
+
 // FileInfo содержит информацию о файле
 type FileInfo struct {
 	Size     int64
 	Duration time.Duration
+	Format   audio.Format
 }
 
 // Extractor извлекает метаданные из аудио файлов
@@ -36,21 +64,80 @@ func NewExtractor() *Extractor {
 
 // ExtractFromReader извлекает метаданные из io.Reader
 func (e *Extractor) ExtractFromReader(reader io.ReadSeeker, source string) TrackMetadata {
+	result, _ := e.extract(reader, source)
+	return result
+}
+
+// extract извлекает метаданные из io.Reader и сообщает, удалось ли прочитать
+// теги. Если не удалось, метаданные составляются по имени файла.
+func (e *Extractor) extract(reader io.ReadSeeker, source string) (TrackMetadata, bool) {
 	// Сбрасываем reader в начало
 	if _, err := reader.Seek(0, io.SeekStart); err != nil {
-		return e.getDefaultMetadata(source)
+		return e.getDefaultMetadata(source), false
 	}
 
 	metadata, err := tag.ReadFrom(reader)
 	if err != nil {
-		return e.getDefaultMetadata(source)
+		return e.getDefaultMetadata(source), false
 	}
 
-	return TrackMetadata{
-		Artist: metadata.Artist(),
-		Title:  metadata.Title(),
-		Album:  metadata.Album(),
+	trackNumber, _ := metadata.Track()
+	result := TrackMetadata{
+		Artist:      metadata.Artist(),
+		Title:       metadata.Title(),
+		Album:       metadata.Album(),
+		Year:        metadata.Year(),
+		Genre:       metadata.Genre(),
+		Comment:     comment(metadata),
+		TrackNumber: trackNumber,
+		BPM:         parseBPM(rawTag(metadata.Raw(), bpmTags)),
+		Key:         rawTag(metadata.Raw(), keyTags),
+	}
+	if picture := metadata.Picture(); picture != nil && len(picture.Data) > 0 {
+		result.Artwork = &Artwork{
+			MIMEType: picture.MIMEType,
+			Ext:      picture.Ext,
+			Data:     picture.Data,
+		}
+	}
+	if readerAt, ok := reader.(io.ReaderAt); ok {
+		result.Chapters = id3Chapters(readerAt)
 	}
+	return result, true
+}
+
+// comment возвращает текст комментария. Для ID3v2 метод Comment библиотеки
+// tag возвращает описание кадра COMM вместо текста, если описание заполнено.
+func comment(metadata tag.Metadata) string {
+	for _, name := range []string{"COMM", "COM"} {
+		if comm, ok := metadata.Raw()[name].(*tag.Comm); ok {
+			if text := strings.TrimSpace(comm.Text); text != "" {
+				return text
+			}
+		}
+	}
+	return metadata.Comment()
+}
+
+// rawTag возвращает первое непустое текстовое значение из необработанных тегов
+func rawTag(raw map[string]interface{}, names []string) string {
+	for _, name := range names {
+		if value, ok := raw[name].(string); ok {
+			if value = strings.TrimSpace(strings.Trim(value, "\x00")); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// parseBPM разбирает темп, округляя дробные значения вроде "127.96"
+func parseBPM(value string) int {
+	bpm, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil || bpm <= 0 {
+		return 0
+	}
+	return int(math.Round(bpm))
 }
 
 // ExtractFromFile извлекает метаданные из файла
@@ -61,25 +148,78 @@ func (e *Extractor) ExtractFromFile(filePath string) TrackMetadata {
 	}
 	defer file.Close()
 
-	return e.ExtractFromReader(file, filePath)
+	metadata, tagged := e.extract(file, filePath)
+
+	// Без встроенной обложки используем файл обложки рядом с аудиофайлом
+	if metadata.Artwork == nil {
+		metadata.Artwork = SidecarArtwork(filePath)
+	}
+	// Главы без фреймов CHAP берутся из CUE-листа или треклиста рядом с файлом
+	if len(metadata.Chapters) == 0 {
+		metadata.Chapters = SidecarChapters(filePath)
+	}
+	// Файл, скачанный командой download, дополняется сведениями о видео
+	if source := SidecarSourceInfo(filePath); source != nil {
+		source.apply(&metadata, tagged)
+	}
+	return metadata
+}
+
+// DetectFormat определяет формат аудиофайла по сигнатуре и расширению
+func (e *Extractor) DetectFormat(filePath string) (audio.Format, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	defer file.Close()
+
+	header, err := audio.ReadHeader(file)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения заголовка файла: %w", err)
+	}
+	// Без этой проверки файл с незнакомой сигнатурой считается MP3
+	if container, ok := audio.SniffUnsupported(header); ok {
+		return "", fmt.Errorf("неподдерживаемый формат аудио: %s", container)
+	}
+	return audio.Detect(header, "", filePath), nil
 }
 
-// GetDuration получает длительность MP3 файла
+// GetDuration получает длительность аудиофайла
 func (e *Extractor) GetDuration(filePath string) (time.Duration, error) {
+	format, err := e.DetectFormat(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return e.formatDuration(filePath, format)
+}
+
+// formatDuration получает длительность аудиофайла указанного формата.
+// Длительность MP3 берется из заголовков, и только если их разобрать не
+// удалось, файл декодируется целиком.
+func (e *Extractor) formatDuration(filePath string, format audio.Format) (time.Duration, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return 0, fmt.Errorf("ошибка открытия файла: %w", err)
 	}
-	defer file.Close()
 
-	streamer, format, err := mp3.Decode(file)
+	if format == audio.FormatMP3 {
+		if info, err := file.Stat(); err == nil {
+			if duration, err := MP3Duration(file, info.Size()); err == nil {
+				file.Close()
+				return duration, nil
+			}
+		}
+	}
+
+	// Декодер закрывает файл вместе с собой
+	streamer, decoded, err := audio.Decode(format, file)
 	if err != nil {
-		return 0, fmt.Errorf("ошибка декодирования MP3: %w", err)
+		return 0, err
 	}
 	defer streamer.Close()
 
 	// Вычисляем длительность
-	return format.SampleRate.D(streamer.Len()), nil
+	return decoded.SampleRate.D(streamer.Len()), nil
 }
 
 // GetFileInfo получает информацию о файле (размер и длительность)
@@ -90,8 +230,12 @@ func (e *Extractor) GetFileInfo(filePath string) (*FileInfo, error) {
 		return nil, fmt.Errorf("ошибка получения информации о файле: %w", err)
 	}
 
-	// Получаем длительность
-	duration, err := e.GetDuration(filePath)
+	// Определяем формат и длительность
+	format, err := e.DetectFormat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка определения формата: %w", err)
+	}
+	duration, err := e.formatDuration(filePath, format)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения длительности: %w", err)
 	}
@@ -99,6 +243,7 @@ func (e *Extractor) GetFileInfo(filePath string) (*FileInfo, error) {
 	return &FileInfo{
 		Size:     fileInfo.Size(),
 		Duration: duration,
+		Format:   format,
 	}, nil
 }
 
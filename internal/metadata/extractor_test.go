@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"encoding/binary"
 	"os"
 	"path/filepath"
 	"strings"
@@ -28,6 +29,63 @@ func TestExtractMetadata(t *testing.T) {
 	}
 }
 
+// id3v23Frame собирает кадр ID3v2.3 с указанным содержимым
+func id3v23Frame(id string, payload []byte) []byte {
+	frame := []byte(id)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, 0, 0) // Флаги
+	return append(frame, payload...)
+}
+
+// id3v23Text собирает текстовый кадр ID3v2.3 в кодировке ISO-8859-1
+func id3v23Text(id, text string) []byte {
+	return id3v23Frame(id, append([]byte{0}, text...))
+}
+
+func TestExtractExtendedTags(t *testing.T) {
+	var frames []byte
+	frames = append(frames, id3v23Text("TPE1", "Test Artist")...)
+	frames = append(frames, id3v23Text("TIT2", "Test Title")...)
+	frames = append(frames, id3v23Text("TYER", "2021")...)
+	frames = append(frames, id3v23Text("TCON", "Deep House")...)
+	frames = append(frames, id3v23Text("TRCK", "3/12")...)
+	frames = append(frames, id3v23Text("TBPM", "123.6")...)
+	frames = append(frames, id3v23Text("TKEY", "8A")...)
+	frames = append(frames, id3v23Frame("COMM", []byte("\x00engdesc\x00Recorded live"))...)
+	picture := []byte("\x00image/png\x00\x03\x00\x89PNG")
+	frames = append(frames, id3v23Frame("APIC", picture)...)
+
+	// Заголовок тега: размер в формате synchsafe
+	size := len(frames)
+	content := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	content = append(content, frames...)
+	content = append(content, buildMP3(10, nil)...)
+
+	testFilePath := filepath.Join(t.TempDir(), "tagged.mp3")
+	if err := os.WriteFile(testFilePath, content, 0644); err != nil {
+		t.Fatalf("Ошибка создания тестового файла: %v", err)
+	}
+
+	metadata := NewExtractor().ExtractFromFile(testFilePath)
+
+	if metadata.Artist != "Test Artist" || metadata.Title != "Test Title" {
+		t.Errorf("Неверные исполнитель и название: %+v", metadata)
+	}
+	if metadata.Year != 2021 || metadata.Genre != "Deep House" || metadata.TrackNumber != 3 {
+		t.Errorf("Неверные год, жанр или номер трека: %+v", metadata)
+	}
+	if metadata.BPM != 124 || metadata.Key != "8A" {
+		t.Errorf("Неверные темп или тональность: %d, %q", metadata.BPM, metadata.Key)
+	}
+	if metadata.Comment != "Recorded live" {
+		t.Errorf("Неверный комментарий: %q", metadata.Comment)
+	}
+	if metadata.Artwork == nil || metadata.Artwork.MIMEType != "image/png" || string(metadata.Artwork.Data) != "\x89PNG" {
+		t.Errorf("Неверная обложка: %+v", metadata.Artwork)
+	}
+}
+
 func TestExtractFromNoMetadataFile(t *testing.T) {
 	// Создаем временный файл без метаданных
 	tempDir := t.TempDir()
@@ -174,6 +232,19 @@ func TestGetFileInfo(t *testing.T) {
 	}
 }
 
+func TestGetFileInfoUnsupportedContainer(t *testing.T) {
+	// Аудио YouTube в MP4 под именем .mp3 не должно считаться MP3
+	testFilePath := filepath.Join(t.TempDir(), "video.mp3")
+	if err := os.WriteFile(testFilePath, []byte("\x00\x00\x00\x18ftypdash\x00\x00\x00\x00"), 0644); err != nil {
+		t.Fatalf("Ошибка создания тестового файла: %v", err)
+	}
+
+	_, err := NewExtractor().GetFileInfo(testFilePath)
+	if err == nil || !strings.Contains(err.Error(), "неподдерживаемый формат аудио: MP4") {
+		t.Errorf("Ожидалась ошибка неподдерживаемого формата, получено: %v", err)
+	}
+}
+
 func TestGetFileInfoNonExistentFile(t *testing.T) {
 	extractor := NewExtractor()
 	_, err := extractor.GetFileInfo("/non/existent/file.mp3")
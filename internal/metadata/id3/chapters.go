@@ -0,0 +1,156 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Chapter - глава из фрейма CHAP (спецификация ID3v2 Chapter Frame Addendum)
+type Chapter struct {
+	Start  time.Duration
+	Artist string // Значение вложенного фрейма TPE1
+	Title  string // Значение вложенного фрейма TIT2
+}
+
+// ReadChapters читает главы из фреймов CHAP тега ID3v2.3 или ID3v2.4.
+// Если в теге есть оглавление верхнего уровня (CTOC), главы возвращаются
+// в его порядке, иначе - все главы по возрастанию времени начала. Файл без
+// тега или без глав не считается ошибкой.
+func ReadChapters(src io.ReaderAt) ([]Chapter, error) {
+	_, _, frames, err := readTag(src)
+	if err != nil || len(frames) == 0 {
+		return nil, err
+	}
+
+	chapters := make(map[string]Chapter)
+	var ids, order []string
+	for _, f := range frames {
+		switch f.id {
+		case "CHAP":
+			id, chapter, ok := parseChapter(f.data)
+			if ok {
+				if _, seen := chapters[id]; !seen {
+					ids = append(ids, id)
+				}
+				chapters[id] = chapter
+			}
+		case "CTOC":
+			if children, topLevel := parseTOC(f.data); topLevel && order == nil {
+				order = children
+			}
+		}
+	}
+
+	var result []Chapter
+	for _, id := range order {
+		if chapter, ok := chapters[id]; ok {
+			result = append(result, chapter)
+		}
+	}
+	if len(result) == 0 {
+		for _, id := range ids {
+			result = append(result, chapters[id])
+		}
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Start < result[j].Start })
+	}
+	return result, nil
+}
+
+// parseChapter разбирает фрейм CHAP: идентификатор элемента, время начала
+// и конца в миллисекундах, смещения в байтах и вложенные фреймы ID3v2.4
+// (readTag переводит в ID3v2.4 и фреймы, вложенные в главы ID3v2.3)
+func parseChapter(data []byte) (string, Chapter, bool) {
+	id, rest, ok := cutString(data)
+	if !ok || len(rest) < 16 {
+		return "", Chapter{}, false
+	}
+	chapter := Chapter{
+		Start: time.Duration(binary.BigEndian.Uint32(rest[:4])) * time.Millisecond,
+	}
+	for _, sub := range parseFrames(rest[16:], 4, false) {
+		switch sub.id {
+		case "TIT2":
+			chapter.Title = decodeText(sub.data)
+		case "TPE1":
+			chapter.Artist = decodeText(sub.data)
+		}
+	}
+	return id, chapter, true
+}
+
+// parseTOC разбирает фрейм CTOC и возвращает идентификаторы дочерних
+// элементов и признак оглавления верхнего уровня
+func parseTOC(data []byte) ([]string, bool) {
+	_, rest, ok := cutString(data)
+	if !ok || len(rest) < 2 {
+		return nil, false
+	}
+	topLevel := rest[0]&0x02 != 0
+	count := int(rest[1])
+	rest = rest[2:]
+
+	children := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		var child string
+		if child, rest, ok = cutString(rest); !ok {
+			break
+		}
+		children = append(children, child)
+	}
+	return children, topLevel
+}
+
+// cutString отделяет строку, которая заканчивается нулевым байтом
+func cutString(data []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(data[:i]), data[i+1:], true
+}
+
+// decodeText декодирует содержимое текстового фрейма с байтом кодировки
+// в начале. Из нескольких значений берется первое.
+func decodeText(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	encoding, text := data[0], data[1:]
+
+	var value string
+	switch encoding {
+	case 1, 2: // UTF-16 с BOM или UTF-16BE
+		order := binary.ByteOrder(binary.BigEndian)
+		if len(text) >= 2 && text[0] == 0xff && text[1] == 0xfe {
+			order, text = binary.LittleEndian, text[2:]
+		} else if len(text) >= 2 && text[0] == 0xfe && text[1] == 0xff {
+			text = text[2:]
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			unit := order.Uint16(text[i:])
+			if unit == 0 {
+				break
+			}
+			units = append(units, unit)
+		}
+		value = string(utf16.Decode(units))
+	case 3: // UTF-8
+		value, _, _ = strings.Cut(string(text), "\x00")
+	default: // ISO-8859-1
+		runes := make([]rune, 0, len(text))
+		for _, b := range text {
+			if b == 0 {
+				break
+			}
+			runes = append(runes, rune(b))
+		}
+		value = string(runes)
+	}
+	return strings.TrimSpace(value)
+}
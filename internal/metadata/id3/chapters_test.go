@@ -0,0 +1,106 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// v23Chapter собирает фрейм CHAP ID3v2.3 с названием и исполнителем
+func v23Chapter(id string, start time.Duration, artist, title string) []byte {
+	data := append([]byte(id), 0)
+	times := make([]byte, 16)
+	binary.BigEndian.PutUint32(times[0:4], uint32(start/time.Millisecond))
+	for i := 8; i < 16; i++ {
+		times[i] = 0xff // Смещения в байтах не используются
+	}
+	data = append(data, times...)
+	data = append(data, v23Frame("TIT2", []byte("\x00"+title))...)
+	if artist != "" {
+		// UTF-16 с BOM
+		text := []byte{1, 0xff, 0xfe}
+		for _, r := range artist {
+			text = append(text, byte(r), byte(r>>8))
+		}
+		data = append(data, v23Frame("TPE1", append(text, 0, 0))...)
+	}
+	return v23Frame("CHAP", data)
+}
+
+func TestReadChaptersInTOCOrder(t *testing.T) {
+	toc := []byte("toc\x00\x03\x02ch2\x00ch1\x00")
+	file := append(v23Tag(8,
+		v23Frame("TIT2", []byte("\x00Mix")),
+		v23Chapter("ch1", 0, "Первый", "Intro"),
+		v23Chapter("ch2", 90*time.Second, "", "Outro"),
+		v23Frame("CTOC", toc),
+	), testAudio...)
+
+	chapters, err := ReadChapters(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Ошибка чтения глав: %v", err)
+	}
+	expected := []Chapter{
+		{Start: 90 * time.Second, Title: "Outro"},
+		{Start: 0, Artist: "Первый", Title: "Intro"},
+	}
+	if len(chapters) != len(expected) {
+		t.Fatalf("Ожидалось %d главы, получено %+v", len(expected), chapters)
+	}
+	for i := range expected {
+		if chapters[i] != expected[i] {
+			t.Errorf("Глава %d: ожидалось %+v, получено %+v", i, expected[i], chapters[i])
+		}
+	}
+}
+
+func TestReadChaptersWithoutTOC(t *testing.T) {
+	file := append(v23Tag(0,
+		v23Chapter("b", 2*time.Minute, "B", "Second"),
+		v23Chapter("a", 0, "A", "First"),
+	), testAudio...)
+
+	chapters, err := ReadChapters(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Ошибка чтения глав: %v", err)
+	}
+	if len(chapters) != 2 || chapters[0].Title != "First" || chapters[1].Start != 2*time.Minute {
+		t.Errorf("Главы должны быть отсортированы по времени начала: %+v", chapters)
+	}
+
+	// Файл без тега
+	if chapters, err := ReadChapters(bytes.NewReader(testAudio)); err != nil || chapters != nil {
+		t.Errorf("Для файла без тега ожидался пустой результат: %+v, %v", chapters, err)
+	}
+}
+
+func TestRewriteUpgradesChapterFrames(t *testing.T) {
+	// Размер вложенного фрейма от 128 байт в ID3v2.3 и ID3v2.4 кодируется по-разному
+	longTitle := strings.Repeat("Long chapter title ", 10)
+	toc := []byte("toc\x00\x03\x02ch2\x00ch1\x00")
+	toc = append(toc, v23Frame("TIT2", []byte("\x00"+longTitle))...)
+	file := append(v23Tag(0,
+		v23Chapter("ch1", 0, "Первый", longTitle),
+		v23Chapter("ch2", 90*time.Second, "", "Outro"),
+		v23Frame("CTOC", toc),
+	), testAudio...)
+
+	chapters, err := ReadChapters(bytes.NewReader(rewrite(t, file, Tag{Title: "Mix"})))
+	if err != nil {
+		t.Fatalf("Ошибка чтения глав: %v", err)
+	}
+	expected := []Chapter{
+		{Start: 90 * time.Second, Title: "Outro"},
+		{Start: 0, Artist: "Первый", Title: strings.TrimSpace(longTitle)},
+	}
+	if len(chapters) != len(expected) {
+		t.Fatalf("Ожидалось %d главы, получено %+v", len(expected), chapters)
+	}
+	for i := range expected {
+		if chapters[i] != expected[i] {
+			t.Errorf("Глава %d: ожидалось %+v, получено %+v", i, expected[i], chapters[i])
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package id3 перезаписывает теги ID3v2 в MP3-файлах. Новый тег всегда
+// записывается в версии ID3v2.4, а аудиоданные копируются без изменений.
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// headerSize - размер заголовка тега и заголовка фрейма ID3v2
+const headerSize = 10
+
+// frame - фрейм тега ID3v2 с уже декодированным содержимым
+type frame struct {
+	id   string
+	data []byte
+}
+
+// droppedFrames - фреймы ID3v2.3, которых нет в ID3v2.4. Дата выпуска в
+// ID3v2.4 хранится во фрейме TDRC, который записывается заново.
+var droppedFrames = map[string]bool{
+	"TYER": true, "TDAT": true, "TIME": true, "TRDA": true,
+	"TORY": true, "TSIZ": true, "EQUA": true, "RVAD": true, "IPLS": true,
+}
+
+// readTag читает тег ID3v2 в начале файла. Возвращает полный размер тега
+// (0, если тега нет), размер области фреймов, которую можно переиспользовать,
+// и фреймы, которые можно перенести в ID3v2.4, уже в формате ID3v2.4.
+func readTag(src io.ReaderAt) (tagSize, bodySize int64, frames []frame, err error) {
+	header := make([]byte, headerSize)
+	if n, err := src.ReadAt(header, 0); n < headerSize {
+		if err != nil && err != io.EOF {
+			return 0, 0, nil, fmt.Errorf("ошибка чтения заголовка: %w", err)
+		}
+		return 0, 0, nil, nil
+	}
+	if string(header[:3]) != "ID3" {
+		return 0, 0, nil, nil
+	}
+
+	version, flags := header[3], header[5]
+	bodySize = int64(synchsafe(header[6:10]))
+	tagSize = headerSize + bodySize
+	if flags&0x10 != 0 {
+		tagSize += headerSize // Футер
+	}
+
+	body := make([]byte, bodySize)
+	if _, err := src.ReadAt(body, headerSize); err != nil {
+		return 0, 0, nil, fmt.Errorf("ошибка чтения тега ID3v2: %w", err)
+	}
+
+	switch version {
+	case 3:
+		if flags&0x80 != 0 {
+			body = removeUnsync(body)
+		}
+		if flags&0x40 != 0 && len(body) >= 4 {
+			body = body[min(len(body), 4+int(binary.BigEndian.Uint32(body))):]
+		}
+		frames = upgradeFrames(parseFrames(body, 3, false))
+	case 4:
+		if flags&0x40 != 0 && len(body) >= 4 {
+			body = body[min(len(body), synchsafe(body[:4])):]
+		}
+		frames = parseFrames(body, 4, flags&0x80 != 0)
+	default:
+		// Фреймы ID3v2.2 имеют другой формат и не переносятся
+	}
+
+	return tagSize, bodySize, frames, nil
+}
+
+// parseFrames разбирает фреймы ID3v2.3 или ID3v2.4. Сжатые и
+// зашифрованные фреймы, а также фреймы, которых нет в ID3v2.4, пропускаются.
+func parseFrames(body []byte, version byte, unsync bool) []frame {
+	var frames []frame
+	for len(body) >= headerSize && body[0] != 0 {
+		id := string(body[:4])
+		var size int
+		if version == 4 {
+			size = synchsafe(body[4:8])
+		} else {
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		format := body[9]
+		if size > len(body)-headerSize {
+			break // Поврежденный фрейм
+		}
+		data := body[headerSize : headerSize+size]
+		body = body[headerSize+size:]
+
+		if droppedFrames[id] {
+			continue
+		}
+		data, ok := decodeFrame(data, format, version, unsync)
+		if !ok {
+			continue
+		}
+		frames = append(frames, frame{id: id, data: data})
+	}
+	return frames
+}
+
+// upgradeFrames переводит фреймы ID3v2.3 в формат ID3v2.4. Содержимое
+// большинства фреймов не меняется, но в CHAP и CTOC вложены фреймы с
+// заголовками ID3v2.3, размеры которых в ID3v2.4 записываются в
+// synchsafe-формате. Вложенные фреймы таких фреймов собираются заново, а
+// если это не удается, фрейм отбрасывается.
+func upgradeFrames(frames []frame) []frame {
+	result := make([]frame, 0, len(frames))
+	for _, f := range frames {
+		if f.id == "CHAP" || f.id == "CTOC" {
+			offset, ok := embeddedOffset(f)
+			if !ok {
+				continue
+			}
+			embedded, err := encodeFrames(parseFrames(f.data[offset:], 3, false))
+			if err != nil {
+				continue
+			}
+			// Новый срез: f.data ссылается на тело тега с остальными фреймами
+			f.data = append(append([]byte{}, f.data[:offset]...), embedded...)
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// embeddedOffset возвращает смещение вложенных фреймов в CHAP или CTOC:
+// они следуют за идентификатором элемента и времени главы или списком
+// дочерних элементов оглавления
+func embeddedOffset(f frame) (int, bool) {
+	_, rest, ok := cutString(f.data)
+	if !ok {
+		return 0, false
+	}
+	switch f.id {
+	case "CHAP":
+		if len(rest) < 16 {
+			return 0, false
+		}
+		rest = rest[16:]
+	case "CTOC":
+		if len(rest) < 2 {
+			return 0, false
+		}
+		count := int(rest[1])
+		rest = rest[2:]
+		for i := 0; i < count; i++ {
+			if _, rest, ok = cutString(rest); !ok {
+				return 0, false
+			}
+		}
+	}
+	return len(f.data) - len(rest), true
+}
+
+// encodeFrames собирает фреймы ID3v2.4 с заголовками без флагов
+func encodeFrames(frames []frame) ([]byte, error) {
+	var body bytes.Buffer
+	for _, f := range frames {
+		header := make([]byte, headerSize)
+		copy(header, f.id)
+		if err := putSynchsafe(header[4:8], len(f.data)); err != nil {
+			return nil, fmt.Errorf("фрейм %s: %w", f.id, err)
+		}
+		body.Write(header)
+		body.Write(f.data)
+	}
+	return body.Bytes(), nil
+}
+
+// decodeFrame снимает с содержимого фрейма группировку, индикатор длины и
+// рассинхронизацию. Возвращает false для сжатых и зашифрованных фреймов.
+func decodeFrame(data []byte, format, version byte, unsync bool) ([]byte, bool) {
+	if version == 3 {
+		if format&0xc0 != 0 {
+			return nil, false
+		}
+		if format&0x20 != 0 && len(data) > 0 {
+			data = data[1:]
+		}
+		return data, true
+	}
+
+	if format&0x0c != 0 {
+		return nil, false
+	}
+	if format&0x40 != 0 && len(data) > 0 {
+		data = data[1:]
+	}
+	if format&0x01 != 0 && len(data) >= 4 {
+		data = data[4:]
+	}
+	if unsync || format&0x02 != 0 {
+		data = removeUnsync(data)
+	}
+	return data, true
+}
+
+// isDefaultComment проверяет, что фрейм COMM - обычный комментарий без
+// описания. Комментарии с описанием (например, iTunNORM) переносятся.
+func isDefaultComment(data []byte) bool {
+	if len(data) < 4 {
+		return true
+	}
+	description := data[4:]
+	switch data[0] {
+	case 1, 2: // UTF-16: описание заканчивается двумя нулевыми байтами
+		if bytes.HasPrefix(description, []byte{0xff, 0xfe}) || bytes.HasPrefix(description, []byte{0xfe, 0xff}) {
+			description = description[2:]
+		}
+		return len(description) >= 2 && description[0] == 0 && description[1] == 0
+	default:
+		return len(description) == 0 || description[0] == 0
+	}
+}
+
+// synchsafe декодирует четырехбайтовое число, в каждом байте которого
+// используется только 7 бит
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// errTagTooLarge означает, что тег не помещается в 28-битный размер ID3v2
+var errTagTooLarge = errors.New("тег ID3v2 слишком большой")
+
+// putSynchsafe кодирует число в четырехбайтовый synchsafe-формат
+func putSynchsafe(b []byte, value int) error {
+	if value < 0 || value >= 1<<28 {
+		return errTagTooLarge
+	}
+	b[0] = byte(value>>21) & 0x7f
+	b[1] = byte(value>>14) & 0x7f
+	b[2] = byte(value>>7) & 0x7f
+	b[3] = byte(value) & 0x7f
+	return nil
+}
+
+// removeUnsync отменяет рассинхронизацию: удаляет нулевой байт,
+// вставленный после каждого 0xFF
+func removeUnsync(data []byte) []byte {
+	if !bytes.Contains(data, []byte{0xff, 0x00}) {
+		return data
+	}
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		result = append(result, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0x00 {
+			i++
+		}
+	}
+	return result
+}
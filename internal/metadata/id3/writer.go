@@ -0,0 +1,189 @@
+package id3
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// defaultPadding - свободное место, которое оставляется в новом теге, чтобы
+// следующие правки помещались в него без сдвига аудиоданных
+const defaultPadding = 2048
+
+// id3v1Size - размер тега ID3v1 в конце файла
+const id3v1Size = 128
+
+// Tag содержит значения тегов, которые записываются в файл. Пустые строки и
+// нулевые числа удаляют соответствующие фреймы.
+type Tag struct {
+	Artist      string
+	Title       string
+	Album       string
+	Year        int
+	Genre       string
+	Comment     string
+	TrackNumber int
+	BPM         int
+	Key         string
+}
+
+// managedFrames - фреймы, значения которых задаются структурой Tag
+var managedFrames = map[string]bool{
+	"TPE1": true, "TIT2": true, "TALB": true, "TDRC": true, "TCON": true,
+	"TRCK": true, "TBPM": true, "TKEY": true,
+}
+
+// frames возвращает фреймы ID3v2.4 с непустыми значениями тега
+func (t Tag) frames() []frame {
+	var frames []frame
+	addText := func(id, value string) {
+		if value != "" {
+			frames = append(frames, frame{id: id, data: append([]byte{encodingUTF8}, value...)})
+		}
+	}
+	addNumber := func(id string, value int) {
+		if value > 0 {
+			addText(id, strconv.Itoa(value))
+		}
+	}
+
+	addText("TPE1", t.Artist)
+	addText("TIT2", t.Title)
+	addText("TALB", t.Album)
+	addNumber("TDRC", t.Year)
+	addText("TCON", t.Genre)
+	addNumber("TRCK", t.TrackNumber)
+	addNumber("TBPM", t.BPM)
+	addText("TKEY", t.Key)
+	if t.Comment != "" {
+		// Кодировка, язык и пустое описание, за которыми следует текст
+		data := append([]byte{encodingUTF8}, "eng\x00"...)
+		frames = append(frames, frame{id: "COMM", data: append(data, t.Comment...)})
+	}
+	return frames
+}
+
+// encodingUTF8 - код кодировки UTF-8 в текстовых фреймах ID3v2.4
+const encodingUTF8 = 3
+
+// Rewrite записывает в dst содержимое MP3-файла src размером size с новым
+// тегом ID3v2.4. Фреймы старого тега, которые не задаются структурой Tag
+// (обложка, главы и т.п.), переносятся в новый тег. Если новый тег
+// помещается в старый, его размер сохраняется, и аудиоданные остаются на
+// прежнем смещении. Аудиоданные копируются побайтно; тег ID3v1 в конце
+// файла, если он есть, обновляется.
+func Rewrite(dst io.Writer, src io.ReaderAt, size int64, tag Tag) error {
+	oldSize, oldBody, oldFrames, err := readTag(src)
+	if err != nil {
+		return err
+	}
+	if oldSize > size {
+		return fmt.Errorf("тег ID3v2 длиннее файла")
+	}
+
+	frames := tag.frames()
+	for _, f := range oldFrames {
+		if managedFrames[f.id] || f.id == "COMM" && isDefaultComment(f.data) {
+			continue
+		}
+		frames = append(frames, f)
+	}
+
+	body, err := encodeFrames(frames)
+	if err != nil {
+		return err
+	}
+
+	// Свободное место заполняется нулями
+	bodySize := int64(len(body))
+	if bodySize <= oldBody {
+		bodySize = oldBody
+	} else {
+		bodySize += defaultPadding
+	}
+
+	header := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+	if err := putSynchsafe(header[6:10], int(bodySize)); err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return fmt.Errorf("ошибка записи тега: %w", err)
+	}
+	if _, err := dst.Write(body); err != nil {
+		return fmt.Errorf("ошибка записи тега: %w", err)
+	}
+	if _, err := dst.Write(make([]byte, bodySize-int64(len(body)))); err != nil {
+		return fmt.Errorf("ошибка записи тега: %w", err)
+	}
+
+	// Аудиоданные копируются без изменений. Новый тег пишется без футера,
+	// а футер старого тега входит в oldSize и не копируется.
+	audioEnd := size
+	v1 := readID3v1(src, oldSize, size)
+	if v1 != nil {
+		audioEnd -= id3v1Size
+	}
+	if _, err := io.Copy(dst, io.NewSectionReader(src, oldSize, audioEnd-oldSize)); err != nil {
+		return fmt.Errorf("ошибка копирования аудиоданных: %w", err)
+	}
+
+	if v1 != nil {
+		tag.fillID3v1(v1)
+		if _, err := dst.Write(v1); err != nil {
+			return fmt.Errorf("ошибка записи тега ID3v1: %w", err)
+		}
+	}
+	return nil
+}
+
+// readID3v1 возвращает тег ID3v1 в конце файла или nil, если его нет
+func readID3v1(src io.ReaderAt, audioStart, size int64) []byte {
+	if size-audioStart < id3v1Size {
+		return nil
+	}
+	v1 := make([]byte, id3v1Size)
+	if _, err := src.ReadAt(v1, size-id3v1Size); err != nil || string(v1[:3]) != "TAG" {
+		return nil
+	}
+	return v1
+}
+
+// fillID3v1 записывает значения тега в поля ID3v1. Жанр в ID3v1 задается
+// номером из фиксированного списка, поэтому он не меняется.
+func (t Tag) fillID3v1(v1 []byte) {
+	putLatin1(v1[3:33], t.Title)
+	putLatin1(v1[33:63], t.Artist)
+	putLatin1(v1[63:93], t.Album)
+	year := ""
+	if t.Year > 0 {
+		year = strconv.Itoa(t.Year)
+	}
+	putLatin1(v1[93:97], year)
+
+	// ID3v1.1: номер трека в последнем байте комментария
+	putLatin1(v1[97:125], t.Comment)
+	v1[125] = 0
+	v1[126] = 0
+	if t.TrackNumber > 0 && t.TrackNumber < 256 {
+		v1[126] = byte(t.TrackNumber)
+	}
+}
+
+// putLatin1 записывает строку в поле фиксированной длины в кодировке
+// ISO-8859-1, заменяя остальные символы на "?" и дополняя поле нулями
+func putLatin1(field []byte, value string) {
+	i := 0
+	for len(value) > 0 && i < len(field) {
+		r, size := utf8.DecodeRuneInString(value)
+		value = value[size:]
+		if r > 0xff {
+			r = '?'
+		}
+		field[i] = byte(r)
+		i++
+	}
+	for ; i < len(field); i++ {
+		field[i] = 0
+	}
+}
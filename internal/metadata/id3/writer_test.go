@@ -0,0 +1,182 @@
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// v23Frame собирает фрейм ID3v2.3
+func v23Frame(id string, data []byte) []byte {
+	header := make([]byte, headerSize)
+	copy(header, id)
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(data)))
+	return append(header, data...)
+}
+
+// v23Tag собирает тег ID3v2.3 с указанными фреймами и свободным местом
+func v23Tag(padding int, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	body = append(body, make([]byte, padding)...)
+	header := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 0}
+	if err := putSynchsafe(header[6:10], len(body)); err != nil {
+		panic(err)
+	}
+	return append(header, body...)
+}
+
+// testAudio имитирует аудиоданные, в том числе байты синхронизации MPEG
+var testAudio = bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00, 0x00, 0xff, 0x00, 0x42}, 64)
+
+func rewrite(t *testing.T, file []byte, newTag Tag) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Rewrite(&out, bytes.NewReader(file), int64(len(file)), newTag); err != nil {
+		t.Fatalf("Ошибка перезаписи тега: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestRewriteReplacesTags(t *testing.T) {
+	picture := append([]byte("\x00image/jpeg\x00\x03\x00"), 0xff, 0xd8, 0xff, 0xe0)
+	oldTag := v23Tag(16,
+		v23Frame("TPE1", []byte("\x00Old Artist")),
+		v23Frame("TIT2", []byte("\x00Old Title")),
+		v23Frame("TYER", []byte("\x001999")),
+		v23Frame("COMM", []byte("\x00eng\x00old comment")),
+		v23Frame("COMM", []byte("\x00engiTunNORM\x00 0000")),
+		v23Frame("APIC", picture),
+	)
+	file := append(oldTag, testAudio...)
+
+	newTag := Tag{
+		Artist:      "Новый исполнитель",
+		Title:       "New Title",
+		Year:        2024,
+		Genre:       "Techno",
+		Comment:     "fresh",
+		TrackNumber: 3,
+		BPM:         128,
+		Key:         "Am",
+	}
+	result := rewrite(t, file, newTag)
+
+	if result[3] != 4 {
+		t.Errorf("Ожидался тег ID3v2.4, получена версия %d", result[3])
+	}
+
+	read, err := tag.ReadFrom(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("Ошибка чтения нового тега: %v", err)
+	}
+	if read.Artist() != newTag.Artist || read.Title() != newTag.Title || read.Genre() != newTag.Genre {
+		t.Errorf("Неверные теги: %q, %q, %q", read.Artist(), read.Title(), read.Genre())
+	}
+	if read.Year() != 2024 {
+		t.Errorf("Ожидался год 2024, получено %d", read.Year())
+	}
+	if number, _ := read.Track(); number != 3 {
+		t.Errorf("Ожидался номер трека 3, получено %d", number)
+	}
+	if read.Picture() == nil || !bytes.Equal(read.Picture().Data, []byte{0xff, 0xd8, 0xff, 0xe0}) {
+		t.Error("Обложка не перенесена в новый тег")
+	}
+
+	tagSize, _, frames, err := readTag(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("Ошибка разбора нового тега: %v", err)
+	}
+	counts := map[string]int{}
+	for _, f := range frames {
+		counts[f.id]++
+	}
+	if counts["TYER"] != 0 || counts["TDRC"] != 1 {
+		t.Errorf("Год должен храниться только во фрейме TDRC: %v", counts)
+	}
+	if counts["COMM"] != 2 || counts["TBPM"] != 1 || counts["TKEY"] != 1 {
+		t.Errorf("Ожидались новый комментарий, iTunNORM, темп и тональность: %v", counts)
+	}
+
+	// Аудиоданные сохранены побайтно
+	if !bytes.Equal(result[tagSize:], testAudio) {
+		t.Error("Аудиоданные изменились")
+	}
+}
+
+func TestRewriteReusesTagSpace(t *testing.T) {
+	file := append(v23Tag(4096, v23Frame("TIT2", []byte("\x00Title"))), testAudio...)
+	result := rewrite(t, file, Tag{Artist: "Artist", Title: "Title"})
+
+	if len(result) != len(file) {
+		t.Errorf("Новый тег помещается в старый, размер файла не должен меняться: %d -> %d", len(file), len(result))
+	}
+	if !bytes.Equal(result[len(result)-len(testAudio):], testAudio) {
+		t.Error("Аудиоданные изменились")
+	}
+
+	// Удаленные значения не записываются
+	_, _, frames, err := readTag(bytes.NewReader(rewrite(t, result, Tag{Title: "Title"})))
+	if err != nil {
+		t.Fatalf("Ошибка разбора тега: %v", err)
+	}
+	if len(frames) != 1 || frames[0].id != "TIT2" {
+		t.Errorf("Ожидался только фрейм TIT2, получено %v", frames)
+	}
+}
+
+func TestRewriteDropsFooter(t *testing.T) {
+	// Тег ID3v2.4 с футером: копия заголовка с идентификатором "3DI" после фреймов
+	frame := []byte{'T', 'I', 'T', '2', 0, 0, 0, 6, 0, 0, encodingUTF8, 'T', 'i', 't', 'l', 'e'}
+	header := []byte{'I', 'D', '3', 4, 0, 0x10, 0, 0, 0, 0}
+	if err := putSynchsafe(header[6:10], len(frame)); err != nil {
+		t.Fatal(err)
+	}
+	footer := append([]byte("3DI"), header[3:]...)
+	file := bytes.Join([][]byte{header, frame, footer, testAudio}, nil)
+
+	result := rewrite(t, file, Tag{Title: "New Title"})
+	if result[5]&0x10 != 0 {
+		t.Error("Новый тег не должен иметь футер")
+	}
+	tagSize, _, _, err := readTag(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("Ошибка разбора нового тега: %v", err)
+	}
+	if !bytes.Equal(result[tagSize:], testAudio) {
+		t.Errorf("Аудиоданные изменились: %q", result[tagSize:min(int64(len(result)), tagSize+16)])
+	}
+}
+
+func TestRewriteWithoutTag(t *testing.T) {
+	v1 := make([]byte, id3v1Size)
+	copy(v1, "TAG")
+	copy(v1[3:], "Old Title")
+	v1[127] = 18 // Жанр Techno
+	file := append(append([]byte{}, testAudio...), v1...)
+
+	result := rewrite(t, file, Tag{Artist: "Artist", Title: "Ünïcode 🎵", Year: 2020, TrackNumber: 7})
+
+	tagSize, _, _, err := readTag(bytes.NewReader(result))
+	if err != nil || tagSize == 0 {
+		t.Fatalf("Новый тег не записан: %v", err)
+	}
+	if !bytes.Equal(result[tagSize:len(result)-id3v1Size], testAudio) {
+		t.Error("Аудиоданные изменились")
+	}
+
+	newV1 := result[len(result)-id3v1Size:]
+	if string(bytes.TrimRight(newV1[3:33], "\x00")) != "\xdcn\xefcode ?" {
+		t.Errorf("Неверное название в ID3v1: %q", newV1[3:33])
+	}
+	if string(newV1[93:97]) != "2020" || newV1[126] != 7 || newV1[127] != 18 {
+		t.Errorf("Неверные год, номер трека или жанр в ID3v1: %q %d %d", newV1[93:97], newV1[126], newV1[127])
+	}
+}
+
+func TestRemoveUnsync(t *testing.T) {
+	if got := removeUnsync([]byte{0x01, 0xff, 0x00, 0xe0, 0xff, 0x00}); !bytes.Equal(got, []byte{0x01, 0xff, 0xe0, 0xff}) {
+		t.Errorf("Неверный результат: % x", got)
+	}
+}
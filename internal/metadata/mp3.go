@@ -0,0 +1,281 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// mp3ScanSize - сколько байтов после тега ID3v2 просматривается в поисках
+// первого фрейма. Этого достаточно и для заголовков Xing/VBRI, и для
+// проверки, что следом идет второй фрейм.
+const mp3ScanSize = 16 * 1024
+
+// errNoMP3Frame означает, что в начале файла не найден фрейм MPEG
+var errNoMP3Frame = errors.New("фрейм MPEG не найден")
+
+// Битрейты в кбит/с по индексу из заголовка фрейма
+var (
+	mp3BitratesV1L1 = [16]int{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}
+	mp3BitratesV1L2 = [16]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0}
+	mp3BitratesV1L3 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2L1 = [16]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0}
+	mp3BitratesV2L3 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+)
+
+// mp3SampleRates - частоты дискретизации MPEG-1 по индексу из заголовка;
+// у MPEG-2 они вдвое, а у MPEG-2.5 вчетверо меньше
+var mp3SampleRates = [3]int{44100, 48000, 32000}
+
+// mp3Frame описывает заголовок фрейма MPEG
+type mp3Frame struct {
+	version    int // 1 - MPEG-1, 2 - MPEG-2, 3 - MPEG-2.5
+	layer      int
+	bitrate    int // Битрейт в бит/с
+	sampleRate int
+	samples    int // Сэмплов на фрейм
+	size       int // Размер фрейма в байтах
+	mono       bool
+}
+
+// parseMP3Frame разбирает четырехбайтовый заголовок фрейма MPEG
+func parseMP3Frame(header []byte) (mp3Frame, bool) {
+	if len(header) < 4 || header[0] != 0xff || header[1]&0xe0 != 0xe0 {
+		return mp3Frame{}, false
+	}
+
+	var frame mp3Frame
+	switch (header[1] >> 3) & 0x03 {
+	case 0:
+		frame.version = 3
+	case 2:
+		frame.version = 2
+	case 3:
+		frame.version = 1
+	default:
+		return mp3Frame{}, false
+	}
+
+	frame.layer = 4 - int((header[1]>>1)&0x03)
+	bitrateIndex := header[2] >> 4
+	rateIndex := (header[2] >> 2) & 0x03
+	padding := int((header[2] >> 1) & 0x01)
+	if frame.layer == 4 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+	frame.mono = header[3]>>6 == 3
+
+	var bitrates [16]int
+	switch {
+	case frame.version == 1 && frame.layer == 1:
+		bitrates = mp3BitratesV1L1
+	case frame.version == 1 && frame.layer == 2:
+		bitrates = mp3BitratesV1L2
+	case frame.version == 1:
+		bitrates = mp3BitratesV1L3
+	case frame.layer == 1:
+		bitrates = mp3BitratesV2L1
+	default:
+		bitrates = mp3BitratesV2L3
+	}
+	frame.bitrate = bitrates[bitrateIndex] * 1000
+	if frame.bitrate == 0 {
+		return mp3Frame{}, false // Свободный битрейт не поддерживается
+	}
+
+	frame.sampleRate = mp3SampleRates[rateIndex]
+	if frame.version > 1 {
+		frame.sampleRate /= 2 * (frame.version - 1)
+	}
+
+	switch {
+	case frame.layer == 1:
+		frame.samples = 384
+		frame.size = (12*frame.bitrate/frame.sampleRate + padding) * 4
+		return frame, true
+	case frame.layer == 3 && frame.version > 1:
+		frame.samples = 576
+	default:
+		frame.samples = 1152
+	}
+	frame.size = frame.samples/8*frame.bitrate/frame.sampleRate + padding
+
+	return frame, true
+}
+
+// sideInfoSize возвращает размер служебной информации Layer III после
+// заголовка фрейма: за ней располагается заголовок Xing/Info
+func (f mp3Frame) sideInfoSize() int {
+	switch {
+	case f.version == 1 && f.mono:
+		return 17
+	case f.version == 1:
+		return 32
+	case f.mono:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// MP3Duration вычисляет длительность MP3 по заголовкам, не декодируя файл.
+// Количество фреймов берется из заголовка Xing/Info или VBRI первого
+// фрейма (с поправкой на задержку кодера из тега LAME), а если их нет,
+// длительность оценивается по битрейту и размеру файла как для CBR.
+// Читается только начало файла, поэтому r может быть удаленным ресурсом.
+func MP3Duration(r io.ReaderAt, size int64) (time.Duration, error) {
+	// Пропускаем тег ID3v2
+	var dataStart int64
+	tagHeader := make([]byte, 10)
+	if n, _ := r.ReadAt(tagHeader, 0); n == len(tagHeader) {
+		dataStart = ID3v2Size(tagHeader)
+	}
+
+	buf := make([]byte, mp3ScanSize)
+	n, err := r.ReadAt(buf, dataStart)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("ошибка чтения начала аудиоданных: %w", err)
+	}
+	buf = buf[:n]
+
+	offset, frame, ok := findMP3Frame(buf)
+	if !ok {
+		return 0, errNoMP3Frame
+	}
+	first := buf[offset:]
+
+	if frames, skipped, ok := xingFrames(first, frame); ok {
+		samples := int64(frames)*int64(frame.samples) - int64(skipped)
+		return samplesDuration(samples, frame.sampleRate), nil
+	}
+	if frames, ok := vbriFrames(first); ok {
+		return samplesDuration(int64(frames)*int64(frame.samples), frame.sampleRate), nil
+	}
+
+	// Заголовков нет: считаем битрейт постоянным
+	if size <= 0 {
+		return 0, fmt.Errorf("размер файла неизвестен")
+	}
+	audioSize := size - dataStart - int64(offset)
+	tail := make([]byte, 3)
+	if size >= 128 {
+		if n, _ := r.ReadAt(tail, size-128); n == len(tail) && string(tail) == "TAG" {
+			audioSize -= 128 // Тег ID3v1 в конце файла
+		}
+	}
+	if audioSize <= 0 {
+		return 0, errNoMP3Frame
+	}
+	return time.Duration(float64(audioSize) * 8 / float64(frame.bitrate) * float64(time.Second)), nil
+}
+
+// findMP3Frame ищет первый фрейм MPEG. Синхрослово может случайно
+// встретиться и в мусоре перед аудиоданными, поэтому фрейм засчитывается,
+// только если сразу за ним начинается следующий (или данные кончаются).
+func findMP3Frame(buf []byte) (int, mp3Frame, bool) {
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMP3Frame(buf[i:])
+		if !ok {
+			continue
+		}
+		next := i + frame.size
+		if next+4 > len(buf) {
+			return i, frame, true
+		}
+		if following, ok := parseMP3Frame(buf[next:]); ok &&
+			following.version == frame.version && following.layer == frame.layer &&
+			following.sampleRate == frame.sampleRate {
+			return i, frame, true
+		}
+	}
+	return 0, mp3Frame{}, false
+}
+
+// xingFrames читает количество фреймов из заголовка Xing/Info и задержку
+// кодера с дополнением из следующего за ним тега LAME
+func xingFrames(frame []byte, header mp3Frame) (frames uint32, skipped int, ok bool) {
+	start := 4 + header.sideInfoSize()
+	if len(frame) < start+12 {
+		return 0, 0, false
+	}
+	tag := frame[start:]
+	if !bytes.HasPrefix(tag, []byte("Xing")) && !bytes.HasPrefix(tag, []byte("Info")) {
+		return 0, 0, false
+	}
+
+	const (
+		flagFrames  = 1
+		flagBytes   = 2
+		flagTOC     = 4
+		flagQuality = 8
+	)
+	flags := binary.BigEndian.Uint32(tag[4:8])
+	if flags&flagFrames == 0 {
+		return 0, 0, false
+	}
+	frames = binary.BigEndian.Uint32(tag[8:12])
+	if frames == 0 {
+		return 0, 0, false
+	}
+
+	// Тег LAME следует за необязательными полями заголовка Xing
+	lame := 12
+	if flags&flagBytes != 0 {
+		lame += 4
+	}
+	if flags&flagTOC != 0 {
+		lame += 100
+	}
+	if flags&flagQuality != 0 {
+		lame += 4
+	}
+	if len(tag) >= lame+24 && bytes.HasPrefix(tag[lame:], []byte("LAME")) {
+		// 12 бит задержки и 12 бит дополнения в конце
+		b := tag[lame+21:]
+		delay := int(b[0])<<4 | int(b[1])>>4
+		padding := int(b[1]&0x0f)<<8 | int(b[2])
+		if total := int64(frames) * int64(header.samples); int64(delay+padding) < total {
+			skipped = delay + padding
+		}
+	}
+
+	return frames, skipped, true
+}
+
+// vbriFrames читает количество фреймов из заголовка VBRI (кодер Fraunhofer),
+// который всегда расположен через 32 байта после заголовка фрейма
+func vbriFrames(frame []byte) (uint32, bool) {
+	const start = 4 + 32
+	if len(frame) < start+18 || !bytes.HasPrefix(frame[start:], []byte("VBRI")) {
+		return 0, false
+	}
+	frames := binary.BigEndian.Uint32(frame[start+14 : start+18])
+	return frames, frames > 0
+}
+
+// samplesDuration переводит количество сэмплов в длительность
+func samplesDuration(samples int64, sampleRate int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(sampleRate)
+}
+
+// ID3v2Size возвращает полный размер тега ID3v2 по его заголовку
+// или 0, если заголовок не является тегом ID3v2
+func ID3v2Size(header []byte) int64 {
+	if len(header) < 10 || string(header[:3]) != "ID3" {
+		return 0
+	}
+
+	// Размер тега хранится в формате synchsafe: по 7 бит в каждом байте
+	size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 |
+		int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+	size += 10
+
+	// Флаг наличия футера добавляет еще 10 байт
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size
+}
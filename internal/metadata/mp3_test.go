@@ -0,0 +1,187 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// cbrHeader - заголовок фрейма MPEG-1 Layer III, 128 кбит/с, 44100 Гц, стерео
+var cbrHeader = []byte{0xff, 0xfb, 0x90, 0x00}
+
+// cbrFrameSize - размер фрейма с заголовком cbrHeader
+const cbrFrameSize = 417
+
+// buildMP3 собирает MP3 из count фреймов с заголовком cbrHeader. Данные
+// first записываются в первый фрейм сразу после заголовка.
+func buildMP3(count int, first []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < count; i++ {
+		frame := make([]byte, cbrFrameSize)
+		copy(frame, cbrHeader)
+		if i == 0 {
+			copy(frame[4:], first)
+		}
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+// xingFrame возвращает содержимое первого фрейма с заголовком Xing со
+// всеми полями и тегом LAME
+func xingFrame(frames uint32, delay, padding int) []byte {
+	data := make([]byte, 32) // Служебная информация стереофрейма MPEG-1
+	data = append(data, "Xing"...)
+	data = binary.BigEndian.AppendUint32(data, 0x0f)
+	data = binary.BigEndian.AppendUint32(data, frames)
+	data = binary.BigEndian.AppendUint32(data, frames*cbrFrameSize)
+	data = append(data, make([]byte, 100+4)...) // Таблица перемотки и качество
+	lame := make([]byte, 24)
+	copy(lame, "LAME3.100")
+	lame[21] = byte(delay >> 4)
+	lame[22] = byte(delay<<4) | byte(padding>>8)
+	lame[23] = byte(padding)
+	return append(data, lame...)
+}
+
+func TestParseMP3Frame(t *testing.T) {
+	frame, ok := parseMP3Frame(cbrHeader)
+	if !ok {
+		t.Fatal("Заголовок фрейма не распознан")
+	}
+	if frame.bitrate != 128000 || frame.sampleRate != 44100 || frame.samples != 1152 || frame.size != cbrFrameSize {
+		t.Errorf("Неверно разобран заголовок: %+v", frame)
+	}
+
+	// MPEG-2 Layer III, 64 кбит/с, 22050 Гц, моно
+	frame, ok = parseMP3Frame([]byte{0xff, 0xf3, 0x80, 0xc0})
+	if !ok || frame.sampleRate != 22050 || frame.samples != 576 || frame.size != 208 || !frame.mono {
+		t.Errorf("Неверно разобран заголовок MPEG-2: %+v, %v", frame, ok)
+	}
+
+	for _, header := range [][]byte{{0xff, 0xfb, 0xf0, 0x00}, {0xff, 0xfb, 0x9c, 0x00}, {0xff, 0xe9, 0x90, 0x00}, {0xff}} {
+		if _, ok := parseMP3Frame(header); ok {
+			t.Errorf("Некорректный заголовок % x не должен распознаваться", header)
+		}
+	}
+}
+
+func TestMP3DurationXing(t *testing.T) {
+	content := buildMP3(50, xingFrame(1000, 576, 1000))
+
+	duration, err := MP3Duration(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	expected := samplesDuration(1000*1152-576-1000, 44100)
+	if duration != expected {
+		t.Errorf("Ожидалось %v, получено %v", expected, duration)
+	}
+}
+
+func TestMP3DurationVBRI(t *testing.T) {
+	vbri := make([]byte, 32)
+	vbri = append(vbri, "VBRI"...)
+	vbri = append(vbri, make([]byte, 10)...) // Версия, задержка, качество, размер
+	vbri = binary.BigEndian.AppendUint32(vbri, 2500)
+	content := buildMP3(10, vbri)
+
+	duration, err := MP3Duration(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if expected := samplesDuration(2500*1152, 44100); duration != expected {
+		t.Errorf("Ожидалось %v, получено %v", expected, duration)
+	}
+}
+
+func TestMP3DurationCBR(t *testing.T) {
+	// Тег ID3v2 на 100 байт, 100 фреймов и тег ID3v1
+	content := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 90}
+	content = append(content, make([]byte, 90)...)
+	content = append(content, buildMP3(100, nil)...)
+	content = append(content, "TAG"...)
+	content = append(content, make([]byte, 125)...)
+
+	duration, err := MP3Duration(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	// 100 фреймов по 417 байт при 128 кбит/с
+	if expected := 2606250 * time.Microsecond; duration != expected {
+		t.Errorf("Ожидалось %v, получено %v", expected, duration)
+	}
+}
+
+func TestMP3DurationNoFrames(t *testing.T) {
+	content := []byte("definitely not an mp3 file")
+	if _, err := MP3Duration(bytes.NewReader(content), int64(len(content))); err == nil {
+		t.Error("Ожидалась ошибка для файла без фреймов")
+	}
+}
+
+func TestGetDurationFromHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mix.mp3")
+	if err := os.WriteFile(path, buildMP3(50, xingFrame(100000, 0, 0)), 0644); err != nil {
+		t.Fatalf("Ошибка создания тестового файла: %v", err)
+	}
+
+	// Полное декодирование дало бы длительность 50 фреймов, а не 100000
+	duration, err := NewExtractor().GetDuration(path)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if expected := samplesDuration(100000*1152, 44100); duration != expected {
+		t.Errorf("Ожидалось %v, получено %v", expected, duration)
+	}
+}
+
+func TestGetRemoteDuration(t *testing.T) {
+	content := buildMP3(2000, xingFrame(2000, 0, 0))
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Range") == "" {
+			t.Error("Ожидался запрос с заголовком Range")
+		}
+		http.ServeContent(w, r, "mix.mp3", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	duration, err := NewExtractor().GetRemoteDuration(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if expected := samplesDuration(2000*1152, 44100); duration != expected {
+		t.Errorf("Ожидалось %v, получено %v", expected, duration)
+	}
+	if requests != 1 {
+		t.Errorf("Ожидался 1 запрос, выполнено %d", requests)
+	}
+}
+
+func TestID3v2Size(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		expected int64
+	}{
+		{"без тега", []byte{0xff, 0xfb, 0x90, 0x64, 0, 0, 0, 0, 0, 0}, 0},
+		{"короткий заголовок", []byte("ID3"), 0},
+		{"тег 257 байт", []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0x02, 0x01}, 267},
+		{"тег с футером", []byte{'I', 'D', '3', 4, 0, 0x10, 0, 0, 0, 0x7f}, 147},
+	}
+
+	for _, test := range tests {
+		if got := ID3v2Size(test.header); got != test.expected {
+			t.Errorf("%s: ожидалось %d, получено %d", test.name, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rangeReader читает удаленный файл запросами с заголовком Range и
+// реализует io.ReaderAt. Каждый запрос скачивает не меньше mp3ScanSize
+// байтов, а последний скачанный блок переиспользуется, поэтому разбор
+// заголовков обходится одним-двумя небольшими запросами.
+type rangeReader struct {
+	ctx      context.Context
+	client   *http.Client
+	url      string
+	size     int64 // Полный размер файла (-1, пока неизвестен)
+	block    []byte
+	blockOff int64
+}
+
+// ReadAt реализует интерфейс io.ReaderAt
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < r.blockOff || off+int64(len(p)) > r.blockOff+int64(len(r.block)) {
+		if err := r.fetch(off, max(len(p), mp3ScanSize)); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.block[off-r.blockOff:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch скачивает блок длиной до length байтов, начиная со смещения off
+func (r *rangeReader) fetch(off int64, length int) error {
+	r.block, r.blockOff = nil, off
+	if r.size >= 0 && off >= r.size {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(length)-1))
+	req.Header.Set("Accept-Encoding", "identity")
+	req.Header.Set("User-Agent", "go-snatcher/1.0")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		r.size = parseContentRangeSize(resp.Header.Get("Content-Range"))
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil // Смещение за концом файла
+	default:
+		// Сервер без поддержки Range отдал бы файл целиком
+		return fmt.Errorf("сервер не поддерживает частичную загрузку: %s", resp.Status)
+	}
+
+	r.block, err = io.ReadAll(io.LimitReader(resp.Body, int64(length)))
+	if err != nil {
+		return fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+	return nil
+}
+
+// parseContentRangeSize извлекает полный размер из заголовка вида
+// "bytes 0-1023/4096" или возвращает -1
+func parseContentRangeSize(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// GetRemoteDuration получает длительность MP3 по URL, скачивая только
+// начало файла (и последние 128 байтов, если битрейт постоянный)
+func (e *Extractor) GetRemoteDuration(ctx context.Context, url string) (time.Duration, error) {
+	r := &rangeReader{ctx: ctx, client: http.DefaultClient, url: url, size: -1}
+
+	// Первый запрос заодно сообщает размер файла
+	if _, err := r.ReadAt(make([]byte, 10), 0); err != nil && err != io.EOF {
+		return 0, err
+	}
+	return MP3Duration(r, r.size)
+}
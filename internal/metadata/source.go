@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sourceInfoSuffix - суффикс файла со сведениями об источнике, который
+// сохраняется рядом со скачанным аудиофайлом
+const sourceInfoSuffix = ".source.yaml"
+
+// uploadDateLayout - формат даты публикации источника
+const uploadDateLayout = "2006-01-02"
+
+// SourceInfo содержит сведения об источнике скачанного файла (например,
+// видео на YouTube). Они сохраняются рядом с аудиофайлом и переносятся в
+// библиотеку, когда файл добавляется командой add.
+type SourceInfo struct {
+	URL        string    `yaml:"url"`
+	Title      string    `yaml:"title,omitempty"`       // Название видео
+	Uploader   string    `yaml:"uploader,omitempty"`    // Автор или канал
+	UploadDate string    `yaml:"upload_date,omitempty"` // Дата публикации (ГГГГ-ММ-ДД)
+	Chapters   []Chapter `yaml:"chapters,omitempty"`    // Главы из описания
+}
+
+// SourceInfoPath возвращает путь к файлу со сведениями об источнике рядом
+// с аудиофайлом
+func SourceInfoPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + sourceInfoSuffix
+}
+
+// WriteSourceInfo сохраняет сведения об источнике рядом с аудиофайлом
+func WriteSourceInfo(audioPath string, info SourceInfo) error {
+	data, err := yaml.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации сведений об источнике: %w", err)
+	}
+	if err := os.WriteFile(SourceInfoPath(audioPath), data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи сведений об источнике: %w", err)
+	}
+	return nil
+}
+
+// SidecarSourceInfo читает сведения об источнике рядом с аудиофайлом.
+// Возвращает nil, если файла нет или его не удалось разобрать.
+func SidecarSourceInfo(audioPath string) *SourceInfo {
+	data, err := os.ReadFile(SourceInfoPath(audioPath))
+	if err != nil {
+		return nil
+	}
+	var info SourceInfo
+	if err := yaml.Unmarshal(data, &info); err != nil || info.URL == "" {
+		return nil
+	}
+	return &info
+}
+
+// UploadYear возвращает год публикации источника или 0
+func (s *SourceInfo) UploadYear() int {
+	date, err := time.Parse(uploadDateLayout, s.UploadDate)
+	if err != nil {
+		return 0
+	}
+	return date.Year()
+}
+
+// FormatUploadDate форматирует дату публикации для SourceInfo.UploadDate
+func FormatUploadDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(uploadDateLayout)
+}
+
+// apply дополняет метаданные сведениями об источнике. Исполнитель и
+// название берутся из названия видео вида "Artist - Title" (или автор
+// видео становится исполнителем), только если в тегах файла их нет.
+func (s *SourceInfo) apply(metadata *TrackMetadata, tagged bool) {
+	metadata.SourceURL = s.URL
+	metadata.UploadDate = s.UploadDate
+
+	if !tagged || metadata.Artist == "" || metadata.Title == "" {
+		artist, title := splitArtistTitle(s.Title)
+		if artist == "" {
+			artist = strings.TrimSuffix(s.Uploader, " - Topic")
+		}
+		if artist != "" {
+			metadata.Artist = artist
+		}
+		if title != "" {
+			metadata.Title = title
+		}
+	}
+	if metadata.Year == 0 {
+		metadata.Year = s.UploadYear()
+	}
+	if len(metadata.Chapters) == 0 {
+		metadata.Chapters = s.Chapters
+	}
+}
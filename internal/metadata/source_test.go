@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourceInfoAppliedOnExtract(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "Some_Mix.mp3")
+	// Файл без тегов: метаданные берутся из имени файла и сведений об источнике
+	if err := os.WriteFile(audioPath, []byte("not really audio"), 0644); err != nil {
+		t.Fatalf("Ошибка записи файла: %v", err)
+	}
+
+	info := SourceInfo{
+		URL:        "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		Title:      "Boiler Room Set",
+		Uploader:   "Boiler Room",
+		UploadDate: FormatUploadDate(time.Date(2019, 5, 17, 0, 0, 0, 0, time.UTC)),
+		Chapters:   ParseTracklist("0:00 A - One\n4:05 B - Two"),
+	}
+	if err := WriteSourceInfo(audioPath, info); err != nil {
+		t.Fatalf("Ошибка записи сведений об источнике: %v", err)
+	}
+	if got := SourceInfoPath(audioPath); got != filepath.Join(dir, "Some_Mix.source.yaml") {
+		t.Errorf("Неверный путь к сведениям об источнике: %s", got)
+	}
+
+	metadata := NewExtractor().ExtractFromFile(audioPath)
+	if metadata.SourceURL != info.URL || metadata.UploadDate != "2019-05-17" || metadata.Year != 2019 {
+		t.Errorf("Сведения об источнике не перенесены: %+v", metadata)
+	}
+	if metadata.Artist != "Boiler Room" || metadata.Title != "Boiler Room Set" {
+		t.Errorf("Ожидался автор видео как исполнитель и название видео, получено %q - %q", metadata.Artist, metadata.Title)
+	}
+	if len(metadata.Chapters) != 2 || metadata.Chapters[1].Start != 4*time.Minute+5*time.Second {
+		t.Errorf("Главы из описания не перенесены: %+v", metadata.Chapters)
+	}
+}
+
+func TestSourceInfoSplitsVideoTitle(t *testing.T) {
+	metadata := TrackMetadata{Artist: "Unknown Artist", Title: "file"}
+	info := SourceInfo{URL: "https://example.com", Title: "Artist Name - Track Name", Uploader: "Artist Name - Topic"}
+	info.apply(&metadata, false)
+	if metadata.Artist != "Artist Name" || metadata.Title != "Track Name" {
+		t.Errorf("Ожидалось разделение названия видео, получено %q - %q", metadata.Artist, metadata.Title)
+	}
+
+	// Теги файла важнее сведений об источнике
+	tagged := TrackMetadata{Artist: "Tagged", Title: "Title", Year: 2001}
+	info.UploadDate = "2020-01-02"
+	info.apply(&tagged, true)
+	if tagged.Artist != "Tagged" || tagged.Year != 2001 || tagged.SourceURL != info.URL {
+		t.Errorf("Теги файла не должны перезаписываться: %+v", tagged)
+	}
+}
@@ -0,0 +1,64 @@
+package player
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/gopxl/beep"
+)
+
+// controls ставит поток на паузу и регулирует его громкость. В отличие от
+// beep.Ctrl и effects.Volume, пауза и громкость хранятся в атомарных полях
+// и меняются без блокировки вывода: пока декодер ждет переподключения,
+// вывод заблокирован надолго, а управление должно срабатывать сразу.
+type controls struct {
+	Streamer beep.Streamer // Меняется только под блокировкой вывода
+
+	paused atomic.Bool
+	silent atomic.Bool
+	volume atomic.Uint64 // Экспонента громкости по основанию 2 (math.Float64bits)
+}
+
+// setPaused ставит поток на паузу или снимает с нее
+func (c *controls) setPaused(paused bool) {
+	c.paused.Store(paused)
+}
+
+// setVolume устанавливает громкость: сэмплы умножаются на 2^volume, а при
+// silent == true поток звучит тишиной
+func (c *controls) setVolume(volume float64, silent bool) {
+	c.volume.Store(math.Float64bits(volume))
+	c.silent.Store(silent)
+}
+
+// Stream реализует интерфейс beep.Streamer
+func (c *controls) Stream(samples [][2]float64) (int, bool) {
+	if c.Streamer == nil {
+		return 0, false
+	}
+	if c.paused.Load() {
+		clear(samples)
+		return len(samples), true
+	}
+
+	n, ok := c.Streamer.Stream(samples)
+	gain := 0.0
+	if !c.silent.Load() {
+		gain = math.Pow(2, math.Float64frombits(c.volume.Load()))
+	}
+	if gain != 1 {
+		for i := range samples[:n] {
+			samples[i][0] *= gain
+			samples[i][1] *= gain
+		}
+	}
+	return n, ok
+}
+
+// Err возвращает ошибку потока
+func (c *controls) Err() error {
+	if c.Streamer == nil {
+		return nil
+	}
+	return c.Streamer.Err()
+}
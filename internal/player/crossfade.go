@@ -0,0 +1,123 @@
+package player
+
+import (
+	"math"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/effects"
+)
+
+// fadeSteps - количество ступеней, которыми меняется громкость за переход
+const fadeSteps = 256
+
+// fade плавно меняет громкость потока за length сэмплов: при нарастании
+// от тишины до полной громкости, при затухании от полной громкости до
+// тишины. Громкость меняется ступенями, поэтому поток читается блоками
+// не длиннее одной ступени.
+type fade struct {
+	gain     effects.Gain
+	position int
+	length   int
+	in       bool // Нарастание (true) или затухание (false)
+}
+
+// newFade создает нарастание (in == true) или затухание громкости потока
+func newFade(streamer beep.Streamer, length int, in bool) *fade {
+	return &fade{
+		gain:   effects.Gain{Streamer: streamer},
+		length: length,
+		in:     in,
+	}
+}
+
+// Stream реализует интерфейс beep.Streamer
+func (f *fade) Stream(samples [][2]float64) (n int, ok bool) {
+	for len(samples) > 0 {
+		block := samples
+		progress := 1.0
+		if f.position < f.length {
+			block = samples[:min(len(samples), max(1, f.length/fadeSteps))]
+			progress = float64(f.position) / float64(f.length)
+		}
+
+		// effects.Gain умножает сэмплы на 1+Gain
+		if f.in {
+			f.gain.Gain = progress - 1
+		} else {
+			f.gain.Gain = -progress
+		}
+
+		sn, sok := f.gain.Stream(block)
+		n += sn
+		f.position += sn
+		samples = samples[sn:]
+		if !sok || sn < len(block) {
+			return n, sok || n > 0
+		}
+	}
+	return n, true
+}
+
+// Err возвращает ошибку потока
+func (f *fade) Err() error {
+	return f.gain.Err()
+}
+
+// crossfader воспроизводит текущий трек, а начиная с позиции start
+// смешивает его затухание с нарастанием следующего трека
+type crossfader struct {
+	from    beep.Streamer  // Поток текущего трека (с частотой вывода)
+	current *trackedStream // Текущий трек, по нему определяется начало перехода
+	start   int            // Позиция текущего трека (в его сэмплах), с которой начинается переход
+	ratio   float64        // Отношение частоты вывода к частоте текущего трека
+	length  int            // Длительность перехода в сэмплах вывода
+	to      *fade          // Нарастание следующего трека
+	mixed   beep.Streamer  // Смесь треков (nil, пока переход не начался)
+}
+
+// newCrossfader создает переход от потока from к потоку to длиной length
+// сэмплов, который начнется на позиции start трека current. Трек может
+// звучать с передискретизацией: ratio - отношение частоты вывода к его
+// частоте.
+func newCrossfader(from beep.Streamer, current *trackedStream, to beep.Streamer, start int, ratio float64, length int) *crossfader {
+	return &crossfader{
+		from:    from,
+		current: current,
+		start:   start,
+		ratio:   ratio,
+		length:  length,
+		to:      newFade(to, length, true),
+	}
+}
+
+// Stream реализует интерфейс beep.Streamer
+func (c *crossfader) Stream(samples [][2]float64) (n int, ok bool) {
+	// До начала перехода читаем только текущий трек и не дальше позиции start.
+	// Позиция трека считается в его сэмплах, а читается поток вывода.
+	for c.mixed == nil && len(samples) > 0 {
+		remaining := int(math.Round(float64(c.start-c.current.lastPosition()) * c.ratio))
+		if remaining <= 0 {
+			break
+		}
+		sn, sok := c.from.Stream(samples[:min(len(samples), remaining)])
+		n += sn
+		samples = samples[sn:]
+		if !sok || sn == 0 {
+			break // Трек закончился раньше: следующий просто плавно нарастает
+		}
+	}
+	if len(samples) == 0 {
+		return n, true
+	}
+
+	if c.mixed == nil {
+		c.mixed = beep.Mix(newFade(c.from, c.length, false), c.to)
+	}
+	sn, sok := c.mixed.Stream(samples)
+	return n + sn, sok || n > 0
+}
+
+// Err возвращает ошибку потока
+func (c *crossfader) Err() error {
+	return nil
+}
@@ -0,0 +1,292 @@
+package player
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/speaker"
+	"github.com/gopxl/beep/wav"
+)
+
+// Output представляет устройство вывода звука. Плеер вызывает Init один раз
+// перед первым воспроизведением, а потоки читаются выводом под его
+// блокировкой: изменять их можно только между Lock и Unlock.
+type Output interface {
+	// Init готовит вывод к воспроизведению с указанной частотой дискретизации
+	Init(sampleRate beep.SampleRate) error
+	// Play добавляет поток к воспроизводимым
+	Play(streamer beep.Streamer)
+	// Clear прекращает воспроизведение всех потоков
+	Clear()
+	// Lock блокирует чтение потоков выводом
+	Lock()
+	// Unlock снимает блокировку чтения потоков
+	Unlock()
+	// Close освобождает устройство вывода
+	Close() error
+}
+
+// NewOutput создает вывод по описанию из командной строки:
+//   - speaker - динамики (по умолчанию);
+//   - null - вывод в никуда в реальном времени;
+//   - null:fast - вывод в никуда с максимальной скоростью;
+//   - wav:ПУТЬ - запись в WAV файл с максимальной скоростью.
+func NewOutput(spec string) (Output, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "speaker":
+		return NewSpeakerOutput(), nil
+	case "null":
+		switch arg {
+		case "":
+			return NewNullOutput(true), nil
+		case "fast":
+			return NewNullOutput(false), nil
+		}
+	case "wav":
+		if arg == "" {
+			return nil, fmt.Errorf("не указан путь к WAV файлу (пример: wav:/tmp/out.wav)")
+		}
+		return NewWAVOutput(arg)
+	}
+	return nil, fmt.Errorf("неизвестный вывод звука: %s (допустимо: speaker, null, null:fast, wav:ПУТЬ)", spec)
+}
+
+// speakerOutput выводит звук в динамики через пакет speaker
+type speakerOutput struct{}
+
+// NewSpeakerOutput создает вывод в динамики
+func NewSpeakerOutput() Output {
+	return speakerOutput{}
+}
+
+func (speakerOutput) Init(sampleRate beep.SampleRate) error {
+	return speaker.Init(sampleRate, sampleRate.N(time.Second/5))
+}
+
+func (speakerOutput) Play(streamer beep.Streamer) {
+	speaker.Play(streamer)
+}
+
+func (speakerOutput) Clear() {
+	speaker.Clear()
+}
+
+func (speakerOutput) Lock() {
+	speaker.Lock()
+}
+
+func (speakerOutput) Unlock() {
+	speaker.Unlock()
+}
+
+// Close ничего не делает: пакет speaker не поддерживает повторную инициализацию
+func (speakerOutput) Close() error {
+	return nil
+}
+
+// sinkBufferSize - размер блока сэмплов, которыми sink читает потоки
+const sinkBufferSize = 512
+
+// sink читает воспроизводимые потоки без звуковой карты. Пока потоков
+// нет, чтение ждет следующего Play, поэтому тишина между треками не
+// выводится. В режиме реального времени чтение идет со скоростью
+// воспроизведения, иначе с максимальной скоростью.
+type sink struct {
+	mutex      sync.Mutex
+	streamers  []beep.Streamer
+	buffer     [][2]float64
+	sampleRate beep.SampleRate
+	realtime   bool
+	wake       chan struct{} // Сигнал о новом потоке
+	done       chan struct{} // Закрывается в Close
+	finished   chan error    // Результат горутины чтения
+	closeOnce  sync.Once
+}
+
+func newSink(realtime bool) *sink {
+	return &sink{
+		buffer:   make([][2]float64, sinkBufferSize),
+		realtime: realtime,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// start запускает горутину, которая читает потоки функцией consume
+func (s *sink) start(sampleRate beep.SampleRate, consume func() error) {
+	s.sampleRate = sampleRate
+	s.finished = make(chan error, 1)
+	go func() {
+		s.finished <- consume()
+	}()
+}
+
+func (s *sink) Play(streamer beep.Streamer) {
+	s.mutex.Lock()
+	s.streamers = append(s.streamers, streamer)
+	s.mutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *sink) Clear() {
+	s.mutex.Lock()
+	s.streamers = nil
+	s.mutex.Unlock()
+}
+
+func (s *sink) Lock() {
+	s.mutex.Lock()
+}
+
+func (s *sink) Unlock() {
+	s.mutex.Unlock()
+}
+
+// Stream реализует интерфейс beep.Streamer: возвращает смесь
+// воспроизводимых потоков и false после закрытия вывода
+func (s *sink) Stream(samples [][2]float64) (int, bool) {
+	for {
+		s.mutex.Lock()
+		select {
+		case <-s.done:
+			s.mutex.Unlock()
+			return 0, false
+		default:
+		}
+
+		if n := s.mix(samples); n > 0 {
+			s.mutex.Unlock()
+			if s.realtime {
+				time.Sleep(s.sampleRate.D(n))
+			}
+			return n, true
+		}
+		s.mutex.Unlock()
+
+		// Ждем, пока появится что воспроизводить
+		select {
+		case <-s.wake:
+		case <-s.done:
+		}
+	}
+}
+
+// mix смешивает потоки в samples и возвращает количество прочитанных
+// сэмплов. В отличие от beep.Mixer, конец последнего потока не
+// дополняется тишиной. Доигранные потоки удаляются. Должен вызываться
+// под мьютексом.
+func (s *sink) mix(samples [][2]float64) int {
+	samples = samples[:min(len(samples), len(s.buffer))]
+	clear(samples)
+
+	n := 0
+	alive := s.streamers[:0]
+	for _, streamer := range s.streamers {
+		sn, ok := streamer.Stream(s.buffer[:len(samples)])
+		for i := range s.buffer[:sn] {
+			samples[i][0] += s.buffer[i][0]
+			samples[i][1] += s.buffer[i][1]
+		}
+		n = max(n, sn)
+		if ok {
+			alive = append(alive, streamer)
+		}
+	}
+	clear(s.streamers[len(alive):])
+	s.streamers = alive
+
+	return n
+}
+
+// Err реализует интерфейс beep.Streamer
+func (s *sink) Err() error {
+	return nil
+}
+
+// shutdown останавливает чтение и возвращает результат горутины
+func (s *sink) shutdown() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	if s.finished == nil {
+		return nil // Вывод не был инициализирован
+	}
+	return <-s.finished
+}
+
+// NullOutput читает потоки и отбрасывает сэмплы. Подходит для работы
+// без звуковой карты и для тестов.
+type NullOutput struct {
+	*sink
+}
+
+// NewNullOutput создает вывод в никуда. При realtime == true потоки
+// читаются со скоростью воспроизведения, иначе с максимальной скоростью.
+func NewNullOutput(realtime bool) *NullOutput {
+	return &NullOutput{sink: newSink(realtime)}
+}
+
+// Init запускает чтение потоков
+func (o *NullOutput) Init(sampleRate beep.SampleRate) error {
+	o.start(sampleRate, func() error {
+		samples := make([][2]float64, sinkBufferSize)
+		for {
+			if _, ok := o.Stream(samples); !ok {
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+// Close останавливает чтение потоков
+func (o *NullOutput) Close() error {
+	return o.shutdown()
+}
+
+// WAVOutput записывает воспроизводимый звук в WAV файл (16 бит, стерео)
+// с максимальной скоростью
+type WAVOutput struct {
+	*sink
+	file *os.File
+}
+
+// NewWAVOutput создает файл и вывод, который записывает в него звук
+func NewWAVOutput(path string) (*WAVOutput, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания WAV файла: %w", err)
+	}
+	return &WAVOutput{sink: newSink(false), file: file}, nil
+}
+
+// Init начинает запись звука в файл
+func (o *WAVOutput) Init(sampleRate beep.SampleRate) error {
+	format := beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2}
+	o.start(sampleRate, func() error {
+		// Encode пишет сэмплы, пока вывод не закрыт, и затем дописывает размеры в заголовок
+		return wav.Encode(o.file, o.sink, format)
+	})
+	return nil
+}
+
+// Close завершает запись и закрывает файл
+func (o *WAVOutput) Close() error {
+	err := o.shutdown()
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка записи WAV файла: %w", err)
+	}
+	return nil
+}
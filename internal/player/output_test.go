@@ -0,0 +1,95 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/wav"
+)
+
+func TestNewOutput(t *testing.T) {
+	valid := []string{"", "speaker", "null", "null:fast", "wav:" + filepath.Join(t.TempDir(), "out.wav")}
+	for _, spec := range valid {
+		output, err := NewOutput(spec)
+		if err != nil {
+			t.Errorf("%q: неожиданная ошибка: %v", spec, err)
+			continue
+		}
+		output.Close()
+	}
+
+	for _, spec := range []string{"alsa", "null:slow", "wav", "wav:"} {
+		if _, err := NewOutput(spec); err == nil {
+			t.Errorf("%q: ожидалась ошибка", spec)
+		}
+	}
+}
+
+func TestNullOutputConsumesStreams(t *testing.T) {
+	output := NewNullOutput(false)
+	if err := output.Init(testFormat.SampleRate); err != nil {
+		t.Fatalf("Ошибка инициализации вывода: %v", err)
+	}
+	defer output.Close()
+
+	buffer := constantBuffer(2000, 0.5)
+	done := make(chan struct{})
+	output.Play(beep.Seq(buffer.Streamer(0, buffer.Len()), beep.Callback(func() {
+		close(done)
+	})))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Вывод без реального времени должен дочитать поток сразу")
+	}
+}
+
+func TestWAVOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	output, err := NewWAVOutput(path)
+	if err != nil {
+		t.Fatalf("Ошибка создания вывода: %v", err)
+	}
+	if err := output.Init(testFormat.SampleRate); err != nil {
+		t.Fatalf("Ошибка инициализации вывода: %v", err)
+	}
+
+	buffer := constantBuffer(1000, 0.5)
+	done := make(chan struct{})
+	output.Play(beep.Seq(buffer.Streamer(0, buffer.Len()), beep.Callback(func() {
+		close(done)
+	})))
+	<-done
+
+	if err := output.Close(); err != nil {
+		t.Fatalf("Ошибка закрытия вывода: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия WAV файла: %v", err)
+	}
+	defer file.Close()
+
+	decoded, format, err := wav.Decode(file)
+	if err != nil {
+		t.Fatalf("Записан некорректный WAV файл: %v", err)
+	}
+	if format.SampleRate != testFormat.SampleRate {
+		t.Errorf("Ожидалась частота %d, получено %d", testFormat.SampleRate, format.SampleRate)
+	}
+
+	// Конец потока не дополняется тишиной
+	if decoded.Len() != 1000 {
+		t.Errorf("Ожидалось 1000 сэмплов, записано %d", decoded.Len())
+	}
+	samples := make([][2]float64, 1)
+	decoded.Stream(samples)
+	if samples[0][0] <= 0 {
+		t.Errorf("Ожидался записанный звук, прочитано %v", samples[0][0])
+	}
+}
@@ -8,13 +8,70 @@ import (
 	"time"
 
 	"github.com/gopxl/beep"
-	"github.com/gopxl/beep/mp3"
-	"github.com/gopxl/beep/speaker"
 
+	"github.com/hazadus/go-snatcher/internal/audio"
+	"github.com/hazadus/go-snatcher/internal/cache"
 	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/player/streaming"
 )
 
+// Шаги перемотки для элементов управления
+const (
+	SkipStep     = 10 * time.Second // Короткий шаг (стрелки)
+	LongSkipStep = time.Minute      // Длинный шаг ([ и ])
+)
+
+// Параметры громкости
+const (
+	DefaultVolume = 100 // Громкость по умолчанию в процентах
+	MaxVolume     = 100 // Максимальная громкость в процентах
+	VolumeStep    = 5   // Шаг изменения громкости в процентах
+
+	// volumeRange задает диапазон экспоненты громкости, на который
+	// отображается шкала 0–100%: громкость воспринимается логарифмически,
+	// поэтому линейная шкала экспоненты звучит естественнее линейного усиления
+	volumeRange = 5.0
+)
+
+// ChapterRestart - если текущая глава играет дольше этого времени, переход
+// к предыдущей главе перематывает к началу текущей
+const ChapterRestart = 3 * time.Second
+
+// PrefetchLead - за сколько до конца трека начинается открытие следующего
+// трека очереди, чтобы переход между ними прошел без паузы
+const PrefetchLead = 20 * time.Second
+
+// Параметры кроссфейда между треками очереди
+const (
+	MaxCrossfade     = 15 * time.Second // Максимальная длительность кроссфейда
+	DefaultCrossfade = 5 * time.Second  // Длительность при включении, если она не настроена
+
+	// resampleQuality - качество передискретизации для beep.Resample
+	resampleQuality = 4
+)
+
+// MinResumePosition - минимальная позиция, которую имеет смысл запоминать
+// для возобновления. Трек, остановленный ближе к концу, чем на это же
+// время, считается дослушанным.
+const MinResumePosition = 30 * time.Second
+
+// ResumePosition возвращает позицию, с которой стоит возобновить трек,
+// остановленный на position, или 0, если позицию запоминать не нужно
+func ResumePosition(position, total time.Duration) time.Duration {
+	if position < MinResumePosition {
+		return 0
+	}
+	if total > 0 && position > total-MinResumePosition {
+		return 0
+	}
+	return position
+}
+
+// PositionHandler получает позицию трека, на которой он был остановлен
+// или поставлен на паузу. Обработчик вызывается под мьютексом плеера,
+// поэтому не должен вызывать методы плеера.
+type PositionHandler func(track data.TrackMetadata, position, total time.Duration)
+
 // Status представляет текущий статус плеера
 type Status struct {
 	Current    time.Duration // Текущая позиция
@@ -22,6 +79,15 @@ type Status struct {
 	IsPlaying  bool          // Воспроизводится ли трек
 	Speed      float64       // Скорость воспроизведения (для диагностики)
 	StuckCount int           // Счетчик зависших состояний
+	Volume     int           // Громкость в процентах
+	Muted      bool          // Выключен ли звук
+	// Номер попытки переподключения после обрыва соединения (0 - соединение в порядке)
+	ReconnectAttempt int
+}
+
+// Reconnecting возвращает true, если поток переподключается после обрыва соединения
+func (s Status) Reconnecting() bool {
+	return s.ReconnectAttempt > 0
 }
 
 // Player управляет воспроизведением треков
@@ -29,6 +95,7 @@ type Player struct {
 	// Каналы для обратной связи
 	progressChan chan Status
 	doneChan     chan bool
+	trackChan    chan data.TrackMetadata
 
 	// Внутреннее состояние
 	ctx           context.Context
@@ -37,21 +104,62 @@ type Player struct {
 	isInitialized bool
 	isPaused      bool
 	currentTrack  *data.TrackMetadata
+	format        beep.Format
+	seekCount     int // Счетчик перемоток, чтобы мониторинг не считал скачок позиции скоростью
+	volumeLevel   int // Громкость в процентах
+	isMuted       bool
+	queue         *Queue
+	cache         *cache.Cache    // Локальный кэш треков (nil, если не используется)
+	offline       bool            // Воспроизводить только треки из кэша
+	sampleRate    beep.SampleRate // Частота дискретизации вывода
+	crossfade     time.Duration   // Настроенная длительность кроссфейда
+	crossfadeOn   bool            // Включен ли кроссфейд
+
+	positionHandler PositionHandler
 
 	// Компоненты для воспроизведения
-	streamer     beep.StreamSeekCloser
-	ctrl         *beep.Ctrl
-	streamReader *streaming.Reader
+	output   Output
+	streamer *trackedStream
+	ctrl     *controls // Пауза и громкость
+	source   cache.Source
+	stream   *streaming.Reader // Сетевой поток текущего трека (nil при воспроизведении из кэша)
+	next     *preparedTrack    // Следующий трек очереди, открытый заранее (nil, если не подготовлен)
+}
+
+// preparedTrack - трек, источник которого открыт, а декодер запущен
+type preparedTrack struct {
+	track    data.TrackMetadata
+	source   cache.Source
+	stream   *streaming.Reader
+	streamer *trackedStream
+	format   beep.Format
+	segment  beep.Streamer // Поток трека с уведомлением о его завершении
+	output   beep.Streamer // Поток, который звучит после перехода к треку
+	spliced  bool          // Трек уже подклеен к текущему и зазвучит сразу после него
 }
 
-// NewPlayer создает новый экземпляр плеера
-func NewPlayer() *Player {
+// close освобождает ресурсы подготовленного трека
+func (t *preparedTrack) close() {
+	if t.stream != nil {
+		t.stream.Cancel()
+	}
+	t.streamer.Close()
+	t.source.Close()
+}
+
+// NewPlayer создает новый экземпляр плеера, который выводит звук в output.
+// Плеер владеет выводом и закрывает его в Close.
+func NewPlayer(output Output) *Player {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Player{
 		progressChan: make(chan Status, 1),
 		doneChan:     make(chan bool, 1),
+		trackChan:    make(chan data.TrackMetadata, 1),
 		ctx:          ctx,
 		cancel:       cancel,
+		volumeLevel:  DefaultVolume,
+		queue:        NewQueue(),
+		output:       output,
 	}
 }
 
@@ -60,82 +168,606 @@ func (p *Player) Progress() <-chan Status {
 	return p.progressChan
 }
 
-// Done возвращает канал, который закрывается при завершении воспроизведения
+// Done возвращает канал, который получает значение, когда воспроизведение
+// завершено и в очереди не осталось треков
 func (p *Player) Done() <-chan bool {
 	return p.doneChan
 }
 
-// Play начинает воспроизведение трека
+// TrackChanged возвращает канал, в который отправляется трек при
+// автоматическом или ручном переходе по очереди
+func (p *Player) TrackChanged() <-chan data.TrackMetadata {
+	return p.trackChan
+}
+
+// Queue возвращает очередь воспроизведения плеера
+func (p *Player) Queue() *Queue {
+	return p.queue
+}
+
+// SetPositionHandler устанавливает обработчик позиции, который вызывается,
+// когда трек перестает играть (остановка, переход к другому треку, выход)
+// или ставится на паузу
+func (p *Player) SetPositionHandler(handler PositionHandler) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.positionHandler = handler
+}
+
+// SetCache подключает локальный кэш: треки из кэша воспроизводятся с диска,
+// а остальные записываются в кэш во время воспроизведения. В режиме offline
+// воспроизводятся только треки из кэша.
+func (p *Player) SetCache(c *cache.Cache, offline bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.cache = c
+	p.offline = offline
+}
+
+// SetCrossfade устанавливает длительность кроссфейда между треками очереди
+// (0–15 секунд). Нулевая длительность выключает кроссфейд.
+func (p *Player) SetCrossfade(duration time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if duration < 0 {
+		duration = 0
+	}
+	if duration > MaxCrossfade {
+		duration = MaxCrossfade
+	}
+	p.crossfade = duration
+	p.crossfadeOn = duration > 0
+}
+
+// ToggleCrossfade включает или выключает кроссфейд и возвращает его
+// длительность (0, если кроссфейд выключен)
+func (p *Player) ToggleCrossfade() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.crossfadeOn = !p.crossfadeOn
+	if p.crossfadeOn && p.crossfade == 0 {
+		p.crossfade = DefaultCrossfade
+	}
+	return p.crossfadeDuration()
+}
+
+// Crossfade возвращает длительность кроссфейда (0, если кроссфейд выключен)
+func (p *Player) Crossfade() time.Duration {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.crossfadeDuration()
+}
+
+// crossfadeDuration возвращает действующую длительность кроссфейда (должен вызываться под мьютексом)
+func (p *Player) crossfadeDuration() time.Duration {
+	if !p.crossfadeOn {
+		return 0
+	}
+	return p.crossfade
+}
+
+// Play начинает воспроизведение трека. Если трек есть в очереди, очередь
+// продолжится с него; иначе после трека начнется следующий трек очереди.
 func (p *Player) Play(track *data.TrackMetadata) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	p.queue.Jump(track.ID)
+	return p.playInternal(track)
+}
+
+// PlayQueue начинает воспроизведение очереди с первого трека
+func (p *Player) PlayQueue() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.queue.Rewind()
+	track, ok := p.queue.Next()
+	if !ok {
+		return fmt.Errorf("очередь пуста")
+	}
+	p.notifyTrackChanged(track)
+	return p.playInternal(&track)
+}
+
+// Next переходит к следующему треку очереди
+func (p *Player) Next() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	track, ok := p.queue.Next()
+	if !ok {
+		return fmt.Errorf("в очереди больше нет треков")
+	}
+	p.notifyTrackChanged(track)
+
+	// Ближе к концу трека следующий уже открыт заранее
+	if p.next != nil && p.next.track.ID == track.ID {
+		return p.switchToNext()
+	}
+	return p.playInternal(&track)
+}
+
+// Previous переходит к предыдущему треку очереди
+func (p *Player) Previous() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	track, ok := p.queue.Previous()
+	if !ok {
+		return fmt.Errorf("это первый трек в очереди")
+	}
+	p.notifyTrackChanged(track)
+	return p.playInternal(&track)
+}
+
+// playInternal запускает воспроизведение трека (должен вызываться под мьютексом)
+func (p *Player) playInternal(track *data.TrackMetadata) error {
 	// Останавливаем текущее воспроизведение, если есть
 	p.stopInternal()
 
 	// Сохраняем информацию о треке
 	p.currentTrack = track
 
-	// Создаем потоковый ридер
-	const bufferSize = 256 * 1024 // 256KB буфер
-	streamReader, err := streaming.NewReader(p.ctx, track.URL, bufferSize)
+	prepared, err := p.prepare(*track, p.cache, p.offline)
 	if err != nil {
-		return fmt.Errorf("ошибка создания потокового ридера: %w", err)
+		return err
+	}
+	return p.start(prepared)
+}
+
+// prepare открывает источник трека и запускает декодер. Метод не
+// обращается к состоянию плеера (кэш и режим offline передаются
+// аргументами), поэтому следующий трек очереди готовится им заранее без
+// мьютекса.
+func (p *Player) prepare(track data.TrackMetadata, c *cache.Cache, offline bool) (*preparedTrack, error) {
+	// Открываем файл из кэша или потоковый ридер
+	source, stream, err := openSource(p.ctx, &track, c, offline)
+	if err != nil {
+		return nil, err
 	}
-	p.streamReader = streamReader
 
-	// Декодируем MP3
-	streamer, format, err := mp3.Decode(streamReader)
+	audioFormat := detectFormat(&track, source, stream)
+	if track.Length <= 0 && audioFormat == audio.FormatMP3 {
+		track.Length = int(probeDuration(p.ctx, &track, source).Seconds())
+	}
+
+	// Декодируем трек с поддержкой перемотки
+	duration := time.Duration(track.Length) * time.Second
+	decoded, format, err := openStream(source, audioFormat, duration)
 	if err != nil {
-		streamReader.Close()
-		return fmt.Errorf("ошибка декодирования MP3: %w", err)
+		source.Close()
+		return nil, err
 	}
-	p.streamer = streamer
+	streamer := newTrackedStream(decoded)
+
+	// Колбэк вызывается под блокировкой вывода, поэтому переход
+	// к следующему треку выполняем в горутине
+	segment := beep.Seq(streamer, beep.Callback(func() {
+		streamer.finished.Store(true)
+		go p.onTrackFinished(streamer)
+	}))
+
+	return &preparedTrack{
+		track:    track,
+		source:   source,
+		stream:   stream,
+		streamer: streamer,
+		format:   format,
+		segment:  segment,
+		output:   segment,
+	}, nil
+}
+
+// start запускает подготовленный трек с начала цепочки вывода (должен вызываться под мьютексом)
+func (p *Player) start(prepared *preparedTrack) error {
+	p.currentTrack = &prepared.track
+	p.source = prepared.source
+	p.stream = prepared.stream
+	p.streamer = prepared.streamer
+	p.format = prepared.format
+	format := prepared.format
 
-	// Инициализируем speaker (только один раз)
+	// Инициализируем вывод звука (только один раз)
 	if !p.isInitialized {
-		err = speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/5))
+		err := p.output.Init(format.SampleRate)
 		if err != nil {
-			streamer.Close()
-			streamReader.Close()
-			return fmt.Errorf("ошибка инициализации динамиков: %w", err)
+			prepared.streamer.Close()
+			return fmt.Errorf("ошибка инициализации вывода звука: %w", err)
 		}
 		p.isInitialized = true
+		p.sampleRate = format.SampleRate
 	}
 
-	// Создаем контроллер паузы
-	p.ctrl = &beep.Ctrl{
-		Streamer: streamer,
-		Paused:   false,
-	}
+	// Пауза и громкость регулируются в начале цепочки вывода
+	prepared.output = p.resampled(prepared)
+	p.ctrl = &controls{Streamer: prepared.output}
 	p.isPaused = false
+	p.applyVolume()
 
-	// Запускаем воспроизведение
-	speaker.Play(beep.Seq(p.ctrl, beep.Callback(func() {
-		// Уведомляем о завершении воспроизведения
-		select {
-		case p.doneChan <- true:
-		default:
-		}
-	})))
+	p.output.Play(p.ctrl)
 
 	// Запускаем мониторинг прогресса в отдельной горутине
-	go p.monitorProgress(format)
+	go p.monitorProgress(format, prepared.streamer)
 
 	return nil
 }
 
+// prefetch заранее открывает следующий трек очереди и подклеивает его
+// к текущему, чтобы переход между треками прошел без паузы или с кроссфейдом
+func (p *Player) prefetch(current *trackedStream) {
+	p.mutex.RLock()
+	track, ok := p.queue.Peek()
+	active := p.streamer == current && p.next == nil
+	c, offline := p.cache, p.offline
+	p.mutex.RUnlock()
+	if !ok || !active {
+		return
+	}
+
+	// HTTP-запрос и разбор заголовков MP3 выполняются без мьютекса,
+	// чтобы не блокировать управление текущим треком
+	prepared, err := p.prepare(track, c, offline)
+	if err != nil {
+		return // Ошибку покажет обычный переход к треку
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Пока трек открывался, воспроизведение могло смениться или очередь измениться
+	upcoming, ok := p.queue.Peek()
+	if p.streamer != current || p.next != nil || !ok || upcoming.ID != track.ID {
+		prepared.close()
+		return
+	}
+
+	p.next = prepared
+	if p.ctrl != nil {
+		p.output.Lock()
+		prepared.spliced = p.splice(current, prepared)
+		p.output.Unlock()
+	}
+}
+
+// splice подклеивает подготовленный трек к текущему: встык через beep.Seq
+// или с кроссфейдом, если он включен и длина текущего трека известна.
+// Если текущий трек уже доигран, подклеивать поздно и splice возвращает
+// false. Должен вызываться под мьютексом и блокировкой вывода.
+func (p *Player) splice(current *trackedStream, next *preparedTrack) bool {
+	if current.finished.Load() {
+		return false
+	}
+
+	output := p.resampled(next)
+
+	// Позиции текущего трека считаются в сэмплах его частоты, а длина
+	// перехода - в сэмплах частоты вывода
+	crossfade := p.crossfadeDuration()
+	start := current.lastLen() - p.format.SampleRate.N(crossfade)
+	if crossfade <= 0 || current.lastLen() <= 0 || start <= current.lastPosition() {
+		p.ctrl.Streamer = beep.Seq(p.ctrl.Streamer, output)
+		next.output = output
+		return true
+	}
+
+	ratio := float64(p.sampleRate) / float64(p.format.SampleRate)
+	fader := newCrossfader(p.ctrl.Streamer, current, output, start, ratio, p.sampleRate.N(crossfade))
+	p.ctrl.Streamer = fader
+	next.output = fader.to
+	return true
+}
+
+// resampled возвращает поток трека, приведенный к частоте вывода. Вывод
+// инициализируется один раз с частотой первого трека, и без
+// передискретизации трек с другой частотой звучал бы выше или ниже и
+// играл быстрее или медленнее.
+func (p *Player) resampled(prepared *preparedTrack) beep.Streamer {
+	if prepared.format.SampleRate == p.sampleRate {
+		return prepared.segment
+	}
+	return beep.Resample(resampleQuality, prepared.format.SampleRate, p.sampleRate, prepared.segment)
+}
+
+// switchToNext делает текущим заранее подготовленный трек (должен вызываться
+// под мьютексом). Подклеенный трек уже звучит, поэтому меняется только
+// состояние плеера; иначе трек запускается обычным образом.
+func (p *Player) switchToNext() error {
+	prepared := p.next
+	p.next = nil
+
+	if !prepared.spliced || p.ctrl == nil {
+		p.stopInternal()
+		return p.start(prepared)
+	}
+
+	p.reportPosition()
+
+	// Отбрасываем доигранный трек из цепочки: она уже перешла к следующему
+	p.output.Lock()
+	p.ctrl.Streamer = prepared.output
+	p.output.Unlock()
+
+	if p.stream != nil {
+		p.stream.Cancel()
+	}
+	p.streamer.Close()
+	p.source.Close()
+
+	p.currentTrack = &prepared.track
+	p.source = prepared.source
+	p.stream = prepared.stream
+	p.streamer = prepared.streamer
+	p.format = prepared.format
+
+	go p.monitorProgress(p.format, p.streamer)
+
+	return nil
+}
+
+// openSource открывает источник данных трека: файл из кэша c, если трек
+// уже скачан, или потоковый ридер, который по ходу воспроизведения
+// записывает трек в кэш. Вторым значением возвращается сетевой поток,
+// если трек воспроизводится не из кэша.
+func openSource(ctx context.Context, track *data.TrackMetadata, c *cache.Cache, offline bool) (cache.Source, *streaming.Reader, error) {
+	if c != nil {
+		if file, err := c.Open(track.URL); err == nil {
+			return file, nil, nil
+		}
+	}
+	if offline {
+		return nil, nil, fmt.Errorf("трек %d отсутствует в кэше, а воспроизведение возможно только офлайн", track.ID)
+	}
+
+	const bufferSize = 256 * 1024 // 256KB буфер
+	streamReader, err := streaming.NewReader(ctx, track.URL, bufferSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка создания потокового ридера: %w", err)
+	}
+
+	if c != nil {
+		// Без записи в кэш трек все равно можно воспроизвести
+		if recorder, err := c.Record(track.URL, streamReader); err == nil {
+			return recorder, streamReader, nil
+		}
+	}
+	return streamReader, streamReader, nil
+}
+
+// onTrackFinished запускает следующий трек очереди после завершения текущего
+func (p *Player) onTrackFinished(finished *trackedStream) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Трек уже остановлен или заменен другим
+	if p.streamer != finished {
+		return
+	}
+
+	// Пропускаем треки, которые не удалось запустить, но не больше одного круга
+	finishedTrack := p.currentTrack
+	for attempt := 0; attempt <= p.queue.Len(); attempt++ {
+		var next data.TrackMetadata
+		var ok bool
+		if p.queue.Repeat() == RepeatOne && finishedTrack != nil {
+			next, ok = *finishedTrack, true
+		} else {
+			next, ok = p.queue.Advance()
+		}
+		if !ok {
+			break
+		}
+
+		p.notifyTrackChanged(next)
+
+		var err error
+		if p.next != nil && p.next.track.ID == next.ID {
+			err = p.switchToNext()
+		} else {
+			err = p.playInternal(&next)
+		}
+		if err == nil {
+			return
+		}
+		finishedTrack = nil
+	}
+
+	// Уведомляем о завершении воспроизведения
+	p.stopInternal()
+	select {
+	case p.doneChan <- true:
+	default:
+	}
+}
+
+// notifyTrackChanged сообщает о смене трека, заменяя непрочитанное уведомление
+func (p *Player) notifyTrackChanged(track data.TrackMetadata) {
+	select {
+	case <-p.trackChan:
+	default:
+	}
+	select {
+	case p.trackChan <- track:
+	default:
+	}
+}
+
 // Pause приостанавливает или возобновляет воспроизведение
 func (p *Player) Pause() {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
 	if p.ctrl != nil {
-		speaker.Lock()
 		p.isPaused = !p.isPaused
-		p.ctrl.Paused = p.isPaused
-		speaker.Unlock()
+		p.ctrl.setPaused(p.isPaused)
+
+		// Пауза может затянуться, поэтому сообщаем позицию сразу
+		if p.isPaused {
+			p.reportPosition()
+		}
+	}
+}
+
+// reportPosition передает обработчику позицию текущего трека (должен вызываться под мьютексом)
+func (p *Player) reportPosition() {
+	if p.positionHandler == nil || p.currentTrack == nil || p.streamer == nil {
+		return
+	}
+
+	position := p.format.SampleRate.D(p.streamer.lastPosition())
+	total := p.format.SampleRate.D(p.streamer.lastLen())
+	if p.currentTrack.Length > 0 {
+		total = time.Duration(p.currentTrack.Length) * time.Second
 	}
+	p.positionHandler(*p.currentTrack, position, total)
+}
+
+// SetVolume устанавливает громкость в процентах (0–100)
+func (p *Player) SetVolume(level int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if level < 0 {
+		level = 0
+	}
+	if level > MaxVolume {
+		level = MaxVolume
+	}
+	p.volumeLevel = level
+	p.applyVolume()
+}
+
+// Volume возвращает текущую громкость в процентах
+func (p *Player) Volume() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.volumeLevel
+}
+
+// ToggleMute выключает или включает звук и возвращает новое состояние
+func (p *Player) ToggleMute() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.isMuted = !p.isMuted
+	p.applyVolume()
+	return p.isMuted
+}
+
+// IsMuted возвращает true, если звук выключен
+func (p *Player) IsMuted() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.isMuted
+}
+
+// applyVolume переносит громкость в регулятор (должен вызываться под мьютексом)
+func (p *Player) applyVolume() {
+	if p.ctrl == nil {
+		return
+	}
+	p.ctrl.setVolume((float64(p.volumeLevel)/MaxVolume-1)*volumeRange, p.isMuted || p.volumeLevel == 0)
+}
+
+// Seek перематывает текущий трек на указанную позицию
+func (p *Player) Seek(position time.Duration) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.streamer == nil || p.ctrl == nil {
+		return fmt.Errorf("нет активного воспроизведения")
+	}
+	return p.seekInternal(position)
+}
+
+// Skip перематывает текущий трек на delta относительно текущей позиции.
+// Отрицательное значение delta перематывает назад.
+func (p *Player) Skip(delta time.Duration) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.streamer == nil || p.ctrl == nil {
+		return fmt.Errorf("нет активного воспроизведения")
+	}
+
+	current := p.format.SampleRate.D(p.streamer.lastPosition())
+	return p.seekInternal(current + delta)
+}
+
+// NextChapter перематывает текущий трек к началу следующей главы и
+// возвращает ее
+func (p *Player) NextChapter() (data.Chapter, error) {
+	return p.jumpChapter(1)
+}
+
+// PreviousChapter перематывает текущий трек к началу текущей главы, а если
+// она началась меньше ChapterRestart назад - к началу предыдущей
+func (p *Player) PreviousChapter() (data.Chapter, error) {
+	return p.jumpChapter(-1)
+}
+
+// jumpChapter перематывает трек к соседней главе в направлении direction
+func (p *Player) jumpChapter(direction int) (data.Chapter, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.streamer == nil || p.ctrl == nil || p.currentTrack == nil {
+		return data.Chapter{}, fmt.Errorf("нет активного воспроизведения")
+	}
+	chapters := p.currentTrack.Chapters
+	if len(chapters) == 0 {
+		return data.Chapter{}, fmt.Errorf("у трека нет глав")
+	}
+
+	current := p.format.SampleRate.D(p.streamer.lastPosition())
+	index := p.currentTrack.ChapterAt(current)
+	if direction > 0 {
+		index++
+		if index >= len(chapters) {
+			return data.Chapter{}, fmt.Errorf("это последняя глава")
+		}
+	} else if index < 0 || current-chapterStart(chapters[index]) < ChapterRestart {
+		index--
+		if index < 0 {
+			return data.Chapter{}, fmt.Errorf("это первая глава")
+		}
+	}
+
+	chapter := chapters[index]
+	if err := p.seekInternal(chapterStart(chapter)); err != nil {
+		return data.Chapter{}, err
+	}
+	return chapter, nil
+}
+
+// chapterStart возвращает позицию начала главы
+func chapterStart(chapter data.Chapter) time.Duration {
+	return time.Duration(chapter.Start) * time.Second
+}
+
+// seekInternal внутренний метод перемотки (должен вызываться под мьютексом).
+// Декодер перематывается выводом перед следующим чтением трека, поэтому
+// перемотка не ждет блокировку вывода, пока декодер переподключается.
+func (p *Player) seekInternal(position time.Duration) error {
+	if err := p.streamer.checkSeek(); err != nil {
+		return fmt.Errorf("ошибка перемотки: %w", err)
+	}
+
+	// Не даем перемотать в самый конец, иначе декодеру нечего будет читать
+	total := p.format.SampleRate.D(p.streamer.lastLen())
+	if position > total-time.Second {
+		position = total - time.Second
+	}
+	if position < 0 {
+		position = 0
+	}
+
+	p.streamer.requestSeek(p.format.SampleRate.N(position))
+	p.seekCount++
+
+	return nil
 }
 
 // Stop останавливает воспроизведение
@@ -147,19 +779,34 @@ func (p *Player) Stop() {
 
 // stopInternal внутренний метод остановки (должен вызываться под мьютексом)
 func (p *Player) stopInternal() {
+	// Прерываем ожидание переподключения: пока поток ждет внутри Read,
+	// вывод заблокирован и остановить воспроизведение нельзя
+	if p.stream != nil {
+		p.stream.Cancel()
+		p.stream = nil
+	}
+
+	p.reportPosition()
+
 	if p.ctrl != nil {
-		speaker.Clear()
+		p.output.Clear()
 		p.ctrl = nil
 	}
 
+	// Заранее открытый трек больше не понадобится
+	if p.next != nil {
+		p.next.close()
+		p.next = nil
+	}
+
 	if p.streamer != nil {
 		p.streamer.Close()
 		p.streamer = nil
 	}
 
-	if p.streamReader != nil {
-		p.streamReader.Close()
-		p.streamReader = nil
+	if p.source != nil {
+		p.source.Close()
+		p.source = nil
 	}
 
 	p.currentTrack = nil
@@ -170,9 +817,12 @@ func (p *Player) stopInternal() {
 func (p *Player) Close() error {
 	p.cancel()
 	p.Stop()
+	p.mutex.Lock()
 	close(p.progressChan)
 	close(p.doneChan)
-	return nil
+	close(p.trackChan)
+	p.mutex.Unlock()
+	return p.output.Close()
 }
 
 // IsPlaying возвращает true, если трек воспроизводится
@@ -190,59 +840,68 @@ func (p *Player) CurrentTrack() *data.TrackMetadata {
 }
 
 // monitorProgress мониторит прогресс воспроизведения и отправляет обновления
-func (p *Player) monitorProgress(format beep.Format) {
+func (p *Player) monitorProgress(format beep.Format, streamer *trackedStream) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	lastPosition := int64(0)
+	lastPosition := time.Duration(0)
+	lastTick := time.Now()
+	lastSeekCount := 0
 	stuckCount := 0
-	startTime := time.Now()
-	pausedTime := time.Duration(0)
-	lastPausedState := false
+	speed := float64(0)
+	prefetchStarted := false
 
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
-		case <-ticker.C:
+		case now := <-ticker.C:
 			p.mutex.RLock()
 
-			if p.streamer == nil || p.ctrl == nil {
+			// Трек остановлен или заменен другим: его мониторинг больше не нужен
+			if p.streamer != streamer || p.ctrl == nil {
 				p.mutex.RUnlock()
 				return
 			}
 
-			speaker.Lock()
-			currentPos := format.SampleRate.D(p.streamer.Position())
-			totalLen := format.SampleRate.D(p.streamer.Len())
+			// Позицию берем из последнего чтения, не блокируя вывод:
+			// во время переподключения декодер ждет данных под их блокировкой
+			currentPos := format.SampleRate.D(streamer.lastPosition())
+			totalLen := format.SampleRate.D(streamer.lastLen())
 			currentPauseState := p.isPaused
-			speaker.Unlock()
-
-			// Учитываем время паузы
-			if currentPauseState && !lastPausedState {
-				pausedTime = time.Since(startTime) - currentPos
+			seekCount := p.seekCount
+			volumeLevel := p.volumeLevel
+			isMuted := p.isMuted
+			crossfade := p.crossfadeDuration()
+
+			reconnectAttempt := 0
+			if p.stream != nil {
+				reconnectAttempt = p.stream.ReconnectAttempt()
 			}
-			lastPausedState = currentPauseState
 
-			// Проверяем, не застрял ли поток
-			currentPosInt := int64(currentPos)
-			if !currentPauseState {
-				if currentPosInt == lastPosition {
+			// Позиция берется из декодера, поэтому она остается верной
+			// после пауз и перемоток. Скорость считаем по приросту позиции
+			// за интервал между тиками, пропуская интервалы с перемоткой.
+			seeked := seekCount != lastSeekCount
+			if currentPauseState {
+				stuckCount = 0
+				speed = 0
+			} else if !seeked {
+				// Проверяем, не застрял ли поток
+				if currentPos == lastPosition {
 					stuckCount++
 				} else {
 					stuckCount = 0
 				}
-			} else {
-				stuckCount = 0
-			}
-			lastPosition = currentPosInt
 
-			// Вычисляем скорость воспроизведения
-			elapsed := time.Since(startTime) - pausedTime
-			var speed float64
-			if elapsed > 0 && !currentPauseState {
-				speed = float64(currentPos) / float64(elapsed)
+				// Вычисляем скорость воспроизведения
+				if elapsed := now.Sub(lastTick); elapsed > 0 {
+					speed = float64(currentPos-lastPosition) / float64(elapsed)
+				}
 			}
+			lastPosition = currentPos
+			lastTick = now
+			lastSeekCount = seekCount
 
 			// Определяем общую продолжительность
 			var duration time.Duration
@@ -252,15 +911,22 @@ func (p *Player) monitorProgress(format beep.Format) {
 				duration = totalLen
 			}
 
-			p.mutex.RUnlock()
+			// Ближе к концу трека заранее открываем следующий трек очереди
+			if !prefetchStarted && duration > 0 && duration-currentPos <= PrefetchLead+crossfade {
+				prefetchStarted = true
+				go p.prefetch(streamer)
+			}
 
-			// Отправляем обновление статуса
+			// Отправляем обновление статуса под мьютексом, чтобы Close не закрыл канал во время отправки
 			status := Status{
-				Current:    currentPos,
-				Total:      duration,
-				IsPlaying:  !currentPauseState,
-				Speed:      speed,
-				StuckCount: stuckCount,
+				Current:          currentPos,
+				Total:            duration,
+				IsPlaying:        !currentPauseState,
+				Speed:            speed,
+				StuckCount:       stuckCount,
+				Volume:           volumeLevel,
+				Muted:            isMuted,
+				ReconnectAttempt: reconnectAttempt,
 			}
 
 			select {
@@ -268,6 +934,8 @@ func (p *Player) monitorProgress(format beep.Format) {
 			default:
 				// Если канал заблокирован, пропускаем обновление
 			}
+
+			p.mutex.RUnlock()
 		}
 	}
 }
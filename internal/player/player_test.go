@@ -1,14 +1,24 @@
 package player
 
 import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/generators"
+	"github.com/gopxl/beep/wav"
+
+	"github.com/hazadus/go-snatcher/internal/cache"
 	"github.com/hazadus/go-snatcher/internal/data"
 )
 
 func TestPlay(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Создаем тестовый трек
@@ -39,7 +49,7 @@ func TestPlay(t *testing.T) {
 }
 
 func TestPauseResume(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Создаем тестовый трек
@@ -77,7 +87,7 @@ func TestPauseResume(t *testing.T) {
 }
 
 func TestStop(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Создаем тестовый трек
@@ -108,7 +118,7 @@ func TestStop(t *testing.T) {
 }
 
 func TestPlayNonExistentFile(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Создаем трек с несуществующим URL
@@ -140,7 +150,7 @@ func TestPlayNonExistentFile(t *testing.T) {
 }
 
 func TestPlayerChannels(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Проверяем, что каналы созданы
@@ -171,7 +181,7 @@ func TestPlayerChannels(t *testing.T) {
 }
 
 func TestPlayerStateManagement(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Проверяем начальное состояние
@@ -217,7 +227,7 @@ func TestPlayerStateManagement(t *testing.T) {
 }
 
 func TestPlayerConcurrentAccess(t *testing.T) {
-	player := NewPlayer()
+	player := NewPlayer(NewNullOutput(false))
 	defer player.Close()
 
 	// Создаем тестовый трек
@@ -284,3 +294,430 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSeekWithoutPlayback(t *testing.T) {
+	player := NewPlayer(NewNullOutput(false))
+	defer player.Close()
+
+	// Перемотка без активного воспроизведения должна возвращать ошибку
+	if err := player.Seek(30 * time.Second); err == nil {
+		t.Error("Ожидалась ошибка при перемотке без активного воспроизведения")
+	}
+
+	if err := player.Skip(-SkipStep); err == nil {
+		t.Error("Ожидалась ошибка при относительной перемотке без активного воспроизведения")
+	}
+
+	if _, err := player.NextChapter(); err == nil {
+		t.Error("Ожидалась ошибка при переходе к главе без активного воспроизведения")
+	}
+}
+
+func TestVolume(t *testing.T) {
+	player := NewPlayer(NewNullOutput(false))
+	defer player.Close()
+
+	if player.Volume() != DefaultVolume {
+		t.Errorf("Ожидалась громкость по умолчанию %d, получено %d", DefaultVolume, player.Volume())
+	}
+
+	// Громкость ограничивается диапазоном 0–100
+	tests := []struct {
+		level    int
+		expected int
+	}{
+		{50, 50},
+		{-10, 0},
+		{150, MaxVolume},
+	}
+
+	for _, test := range tests {
+		player.SetVolume(test.level)
+		if player.Volume() != test.expected {
+			t.Errorf("SetVolume(%d): ожидалось %d, получено %d", test.level, test.expected, player.Volume())
+		}
+	}
+
+	// Выключение звука не меняет сохраненный уровень громкости
+	player.SetVolume(70)
+	if !player.ToggleMute() || !player.IsMuted() {
+		t.Error("Звук должен быть выключен после первого ToggleMute")
+	}
+	if player.Volume() != 70 {
+		t.Errorf("Громкость не должна меняться при выключении звука, получено %d", player.Volume())
+	}
+	if player.ToggleMute() || player.IsMuted() {
+		t.Error("Звук должен быть включен после второго ToggleMute")
+	}
+}
+
+func TestResumePosition(t *testing.T) {
+	total := 90 * time.Minute
+
+	tests := []struct {
+		name     string
+		position time.Duration
+		total    time.Duration
+		expected time.Duration
+	}{
+		{"начало трека", 10 * time.Second, total, 0},
+		{"середина трека", 47*time.Minute + 12*time.Second, total, 47*time.Minute + 12*time.Second},
+		{"трек дослушан", total - 5*time.Second, total, 0},
+		{"длина неизвестна", 5 * time.Minute, 0, 5 * time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := ResumePosition(test.position, test.total); got != test.expected {
+			t.Errorf("%s: ожидалось %v, получено %v", test.name, test.expected, got)
+		}
+	}
+}
+
+// nopStreamCloser добавляет пустой Close к beep.StreamSeeker
+type nopStreamCloser struct {
+	beep.StreamSeeker
+}
+
+func (nopStreamCloser) Close() error {
+	return nil
+}
+
+// testFormat - формат тестовых потоков: 100 сэмплов в секунду
+var testFormat = beep.Format{SampleRate: 100, NumChannels: 2, Precision: 2}
+
+// constantBuffer возвращает буфер из n сэмплов с одинаковым значением
+func constantBuffer(n int, value float64) *beep.Buffer {
+	buffer := beep.NewBuffer(testFormat)
+	samples := make([][2]float64, n)
+	for i := range samples {
+		samples[i] = [2]float64{value, value}
+	}
+	buffer.Append(beep.StreamerFunc(func(out [][2]float64) (int, bool) {
+		if len(samples) == 0 {
+			return 0, false
+		}
+		copied := copy(out, samples)
+		samples = samples[copied:]
+		return copied, true
+	}))
+	return buffer
+}
+
+// newSpliceTest создает плеер, который играет первый буфер, и
+// подготовленный трек из второго буфера
+func newSpliceTest(first, second *beep.Buffer) (*Player, *trackedStream, *preparedTrack) {
+	current := newTrackedStream(nopStreamCloser{first.Streamer(0, first.Len())})
+
+	p := NewPlayer(NewNullOutput(false))
+	p.format = testFormat
+	p.sampleRate = testFormat.SampleRate
+	p.ctrl = &controls{Streamer: beep.Seq(current, beep.Callback(func() {
+		current.finished.Store(true)
+	}))}
+
+	segment := second.Streamer(0, second.Len())
+	next := &preparedTrack{format: testFormat, segment: segment, output: segment}
+	return p, current, next
+}
+
+func TestSpliceIsGapless(t *testing.T) {
+	p, current, next := newSpliceTest(constantBuffer(100, 0.5), constantBuffer(100, -0.5))
+	defer p.Close()
+
+	if !p.splice(current, next) {
+		t.Fatal("Недоигранный трек должен подклеиваться")
+	}
+
+	// Оба трека читаются за один вызов без тишины на стыке
+	samples := make([][2]float64, 250)
+	n, _ := p.ctrl.Stream(samples)
+	if n != 200 {
+		t.Fatalf("Ожидалось 200 сэмплов, получено %d", n)
+	}
+	if samples[99][0] <= 0 || samples[100][0] >= 0 {
+		t.Errorf("На стыке ожидался конец первого трека и начало второго, получены %v и %v", samples[99][0], samples[100][0])
+	}
+
+	if p.splice(current, next) {
+		t.Error("К доигранному треку подклеивать поздно")
+	}
+}
+
+func TestSpliceWithCrossfade(t *testing.T) {
+	p, current, next := newSpliceTest(constantBuffer(300, 0.5), constantBuffer(300, -0.5))
+	defer p.Close()
+	p.SetCrossfade(time.Second) // 100 сэмплов
+
+	if !p.splice(current, next) {
+		t.Fatal("Недоигранный трек должен подклеиваться")
+	}
+	if next.output == next.segment {
+		t.Error("После перехода должен звучать поток с нарастанием громкости")
+	}
+
+	// Переход начинается за секунду до конца первого трека, треки перекрываются
+	samples := make([][2]float64, 600)
+	n, _ := p.ctrl.Stream(samples)
+	if n != 500 {
+		t.Fatalf("Ожидалось 500 сэмплов (треки перекрываются на 100), получено %d", n)
+	}
+
+	tests := []struct {
+		index    int
+		expected float64
+	}{
+		{150, 0.5},  // До перехода звучит только первый трек
+		{250, 0},    // Середина перехода: треки звучат поровну
+		{450, -0.5}, // После перехода звучит только второй трек
+	}
+	for _, test := range tests {
+		if got := samples[test.index][0]; got < test.expected-0.02 || got > test.expected+0.02 {
+			t.Errorf("Сэмпл %d: ожидалось около %v, получено %v", test.index, test.expected, got)
+		}
+	}
+}
+
+func TestSpliceWithCrossfadeResampled(t *testing.T) {
+	// Текущий трек длиной 3 секунды с частотой 200 Гц звучит на выводе 100 Гц
+	p, current, next := newSpliceTest(constantBuffer(600, 0.5), constantBuffer(300, -0.5))
+	defer p.Close()
+	p.format.SampleRate = 200
+	p.ctrl.Streamer = beep.Resample(resampleQuality, p.format.SampleRate, p.sampleRate, p.ctrl.Streamer)
+	p.SetCrossfade(time.Second)
+
+	if !p.splice(current, next) {
+		t.Fatal("Недоигранный трек должен подклеиваться")
+	}
+
+	// Переход начинается за секунду до конца трека: на 200-м сэмпле вывода, а не на 400-м
+	samples := make([][2]float64, 700)
+	n, _ := p.ctrl.Stream(samples)
+	if n < 495 || n > 505 {
+		t.Fatalf("Ожидалось около 500 сэмплов (треки перекрываются на 100), получено %d", n)
+	}
+
+	tests := []struct {
+		index    int
+		expected float64
+	}{
+		{150, 0.5},  // До перехода звучит только первый трек
+		{250, 0},    // Середина перехода: треки звучат поровну
+		{450, -0.5}, // После перехода звучит только второй трек
+	}
+	for _, test := range tests {
+		if got := samples[test.index][0]; got < test.expected-0.05 || got > test.expected+0.05 {
+			t.Errorf("Сэмпл %d: ожидалось около %v, получено %v", test.index, test.expected, got)
+		}
+	}
+}
+
+// TestControlsWhileOutputLocked проверяет, что пауза, громкость и перемотка
+// не ждут блокировку вывода, которую декодер держит во время переподключения
+func TestControlsWhileOutputLocked(t *testing.T) {
+	p, current, _ := newSpliceTest(constantBuffer(1000, 0.5), constantBuffer(100, -0.5))
+	defer p.Close()
+	p.streamer = current
+	p.currentTrack = &data.TrackMetadata{ID: 1}
+
+	p.output.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Pause()
+		p.SetVolume(50)
+		p.ToggleMute()
+		if err := p.Skip(5 * time.Second); err != nil {
+			t.Errorf("Ошибка перемотки: %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Управление плеером ждет блокировку вывода")
+	}
+	p.output.Unlock()
+
+	// Позиция перемотки видна сразу, а декодер перематывается при чтении
+	if position := current.lastPosition(); position != 500 {
+		t.Errorf("Ожидалась позиция 500, получено %d", position)
+	}
+
+	// На паузе звучит тишина, и поток не читается
+	samples := make([][2]float64, 10)
+	if n, ok := p.ctrl.Stream(samples); n != 10 || !ok || samples[0][0] != 0 || current.Position() != 0 {
+		t.Errorf("На паузе ожидалась тишина без чтения трека: %d, %v, %v", n, ok, samples[0])
+	}
+
+	// После паузы звук выключен, а поток читается с новой позиции
+	p.Pause()
+	if n, _ := p.ctrl.Stream(samples); n != 10 || samples[0][0] != 0 || current.Position() != 510 {
+		t.Errorf("Ожидалась тишина с позиции 500: %d, %v, позиция %d", n, samples[0], current.Position())
+	}
+
+	// Громкость 50% применяется после включения звука
+	p.ToggleMute()
+	p.ctrl.Stream(samples)
+	expected := 0.5 * math.Pow(2, -0.5*volumeRange)
+	if math.Abs(samples[0][0]-expected) > 1e-4 {
+		t.Errorf("Ожидался сэмпл %v, получено %v", expected, samples[0][0])
+	}
+}
+
+func TestCrossfadeSettings(t *testing.T) {
+	p := NewPlayer(NewNullOutput(false))
+	defer p.Close()
+
+	if p.Crossfade() != 0 {
+		t.Errorf("По умолчанию кроссфейд должен быть выключен, получено %v", p.Crossfade())
+	}
+
+	p.SetCrossfade(time.Minute)
+	if p.Crossfade() != MaxCrossfade {
+		t.Errorf("Длительность должна ограничиваться %v, получено %v", MaxCrossfade, p.Crossfade())
+	}
+
+	if p.ToggleCrossfade() != 0 || p.ToggleCrossfade() != MaxCrossfade {
+		t.Error("Переключение должно выключать и снова включать кроссфейд с прежней длительностью")
+	}
+
+	p.SetCrossfade(0)
+	if got := p.ToggleCrossfade(); got != DefaultCrossfade {
+		t.Errorf("Без настроенной длительности ожидалось %v, получено %v", DefaultCrossfade, got)
+	}
+}
+
+// countingOutput считает сэмплы, прочитанные выводом с максимальной скоростью
+type countingOutput struct {
+	*sink
+	samples atomic.Int64
+}
+
+func newCountingOutput() *countingOutput {
+	return &countingOutput{sink: newSink(false)}
+}
+
+func (o *countingOutput) Init(sampleRate beep.SampleRate) error {
+	o.start(sampleRate, func() error {
+		samples := make([][2]float64, sinkBufferSize)
+		for {
+			n, ok := o.Stream(samples)
+			o.samples.Add(int64(n))
+			if !ok {
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+func (o *countingOutput) Close() error {
+	return o.shutdown()
+}
+
+// cacheTone записывает в кэш WAV файл с секундным тоном указанной частоты
+// дискретизации и возвращает трек для него
+func cacheTone(t *testing.T, c *cache.Cache, id int, sampleRate beep.SampleRate) *data.TrackMetadata {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Ошибка создания файла: %v", err)
+	}
+	tone, err := generators.SineTone(sampleRate, 440)
+	if err != nil {
+		t.Fatalf("Ошибка создания тона: %v", err)
+	}
+	format := beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2}
+	if err := wav.Encode(file, beep.Take(sampleRate.N(time.Second), tone), format); err != nil {
+		t.Fatalf("Ошибка записи WAV: %v", err)
+	}
+	file.Close()
+
+	track := &data.TrackMetadata{ID: id, Length: 1, URL: fmt.Sprintf("https://example.com/tone-%d.wav", id), Format: "wav"}
+	file, err = os.Open(path)
+	if err != nil {
+		t.Fatalf("Ошибка открытия файла: %v", err)
+	}
+	defer file.Close()
+	if err := c.Store(track.URL, file); err != nil {
+		t.Fatalf("Ошибка записи в кэш: %v", err)
+	}
+	return track
+}
+
+func TestPlayResamplesToOutputRate(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 1<<30)
+	if err != nil {
+		t.Fatalf("Ошибка создания кэша: %v", err)
+	}
+	first := cacheTone(t, c, 1, 44100)
+	second := cacheTone(t, c, 2, 48000)
+
+	output := newCountingOutput()
+	p := NewPlayer(output)
+	defer p.Close()
+	p.SetCache(c, true)
+
+	// Первый трек задает частоту вывода
+	if err := p.Play(first); err != nil {
+		t.Fatalf("Ошибка воспроизведения: %v", err)
+	}
+	waitDone(t, p)
+	played := output.samples.Load()
+
+	if err := p.Play(second); err != nil {
+		t.Fatalf("Ошибка воспроизведения: %v", err)
+	}
+	waitDone(t, p)
+
+	// Секунда трека 48 кГц должна занять секунду вывода 44.1 кГц, а не 48000 сэмплов
+	got := output.samples.Load() - played
+	if got < 44100-sinkBufferSize || got > 44100+sinkBufferSize {
+		t.Errorf("Ожидалось около 44100 сэмплов вывода, получено %d", got)
+	}
+}
+
+// TestPrefetchWhileSetCache проверяет, что подготовка следующего трека без
+// мьютекса не читает кэш плеера одновременно с SetCache
+func TestPrefetchWhileSetCache(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 1<<30)
+	if err != nil {
+		t.Fatalf("Ошибка создания кэша: %v", err)
+	}
+	p := NewPlayer(NewNullOutput(false))
+	defer p.Close()
+	p.SetCache(c, true)
+	p.Queue().Add(data.TrackMetadata{ID: 1, URL: "https://example.com/missing.mp3"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			p.prefetch(nil)
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			p.SetCache(c, true)
+		}
+	}
+
+	// Офлайн трек без кэша не открывается
+	if p.next != nil {
+		t.Error("Трек, которого нет в кэше, не должен готовиться офлайн")
+	}
+}
+
+// waitDone ждет окончания воспроизведения
+func waitDone(t *testing.T, p *Player) {
+	t.Helper()
+	select {
+	case <-p.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("Воспроизведение не завершилось")
+	}
+}
@@ -0,0 +1,314 @@
+package player
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+
+	"github.com/hazadus/go-snatcher/internal/data"
+)
+
+// RepeatMode определяет режим повтора очереди
+type RepeatMode int
+
+// Режимы повтора
+const (
+	// RepeatOff - очередь проигрывается один раз
+	RepeatOff RepeatMode = iota
+	// RepeatOne - текущий трек повторяется
+	RepeatOne
+	// RepeatAll - после последнего трека очередь начинается заново
+	RepeatAll
+)
+
+// String возвращает название режима повтора
+func (m RepeatMode) String() string {
+	switch m {
+	case RepeatOne:
+		return "один трек"
+	case RepeatAll:
+		return "вся очередь"
+	default:
+		return "выключен"
+	}
+}
+
+// ParseRepeatMode разбирает режим повтора из строки (off, one, all)
+func ParseRepeatMode(s string) (RepeatMode, error) {
+	switch s {
+	case "", "off":
+		return RepeatOff, nil
+	case "one":
+		return RepeatOne, nil
+	case "all":
+		return RepeatAll, nil
+	default:
+		return RepeatOff, fmt.Errorf("неизвестный режим повтора: %s (допустимо: off, one, all)", s)
+	}
+}
+
+// Queue представляет очередь воспроизведения. Порядок воспроизведения
+// хранится отдельно от списка треков, поэтому перемешивание с одним и
+// тем же зерном всегда дает одинаковый порядок.
+type Queue struct {
+	mutex   sync.Mutex
+	tracks  []data.TrackMetadata
+	order   []int // Порядок воспроизведения (индексы в tracks)
+	cursor  int   // Позиция в order (-1, если воспроизведение не начато)
+	shuffle bool
+	seed    int64
+	rng     *rand.Rand // Выбирает места треков, добавленных при перемешивании
+	repeat  RepeatMode
+}
+
+// NewQueue создает очередь из указанных треков
+func NewQueue(tracks ...data.TrackMetadata) *Queue {
+	q := &Queue{cursor: -1}
+	q.Add(tracks...)
+	return q
+}
+
+// Add добавляет треки в конец очереди. При включенном перемешивании
+// каждый трек встает на случайное место среди еще не сыгранных треков.
+func (q *Queue) Add(tracks ...data.TrackMetadata) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, track := range tracks {
+		q.tracks = append(q.tracks, track)
+		index := len(q.tracks) - 1
+		if !q.shuffle {
+			q.order = append(q.order, index)
+			continue
+		}
+		position := q.cursor + 1 + q.rng.Intn(len(q.order)-q.cursor)
+		q.order = slices.Insert(q.order, position, index)
+	}
+}
+
+// Clear очищает очередь, сохраняя режимы повтора и перемешивания
+func (q *Queue) Clear() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.tracks = nil
+	q.order = nil
+	q.cursor = -1
+}
+
+// Rewind возвращает очередь в начальное состояние: следующим будет первый трек
+func (q *Queue) Rewind() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.cursor = -1
+}
+
+// Len возвращает количество треков в очереди
+func (q *Queue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.tracks)
+}
+
+// Position возвращает номер текущего трека в порядке воспроизведения
+// (начиная с 1) или 0, если воспроизведение очереди не начато
+func (q *Queue) Position() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.cursor + 1
+}
+
+// Tracks возвращает треки в порядке воспроизведения
+func (q *Queue) Tracks() []data.TrackMetadata {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	tracks := make([]data.TrackMetadata, len(q.order))
+	for i, index := range q.order {
+		tracks[i] = q.tracks[index]
+	}
+	return tracks
+}
+
+// Current возвращает текущий трек очереди
+func (q *Queue) Current() (data.TrackMetadata, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.cursor < 0 || q.cursor >= len(q.order) {
+		return data.TrackMetadata{}, false
+	}
+	return q.tracks[q.order[q.cursor]], true
+}
+
+// Next переходит к следующему треку. После последнего трека очередь
+// начинается заново только в режиме RepeatAll.
+func (q *Queue) Next() (data.TrackMetadata, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.order) == 0 {
+		return data.TrackMetadata{}, false
+	}
+
+	if q.cursor+1 >= len(q.order) {
+		if q.repeat != RepeatAll {
+			return data.TrackMetadata{}, false
+		}
+		q.cursor = -1
+	}
+	q.cursor++
+
+	return q.tracks[q.order[q.cursor]], true
+}
+
+// Previous переходит к предыдущему треку. С первого трека переход на
+// последний возможен только в режиме RepeatAll.
+func (q *Queue) Previous() (data.TrackMetadata, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.order) == 0 {
+		return data.TrackMetadata{}, false
+	}
+
+	if q.cursor <= 0 {
+		if q.repeat != RepeatAll {
+			return data.TrackMetadata{}, false
+		}
+		q.cursor = len(q.order)
+	}
+	q.cursor--
+
+	return q.tracks[q.order[q.cursor]], true
+}
+
+// Advance выбирает трек после естественного завершения текущего:
+// в режиме RepeatOne это тот же трек, в остальных случаях - следующий
+func (q *Queue) Advance() (data.TrackMetadata, bool) {
+	q.mutex.Lock()
+	repeatCurrent := q.repeat == RepeatOne && q.cursor >= 0
+	q.mutex.Unlock()
+
+	if repeatCurrent {
+		return q.Current()
+	}
+	return q.Next()
+}
+
+// Peek возвращает трек, который выберет Advance, не меняя позицию очереди
+func (q *Queue) Peek() (data.TrackMetadata, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.order) == 0 {
+		return data.TrackMetadata{}, false
+	}
+	if q.repeat == RepeatOne && q.cursor >= 0 && q.cursor < len(q.order) {
+		return q.tracks[q.order[q.cursor]], true
+	}
+
+	next := q.cursor + 1
+	if next >= len(q.order) {
+		if q.repeat != RepeatAll {
+			return data.TrackMetadata{}, false
+		}
+		next = 0
+	}
+	return q.tracks[q.order[next]], true
+}
+
+// Jump делает текущим трек с указанным ID, если он есть в очереди
+func (q *Queue) Jump(trackID int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, index := range q.order {
+		if q.tracks[index].ID == trackID {
+			q.cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// SetRepeat устанавливает режим повтора
+func (q *Queue) SetRepeat(mode RepeatMode) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.repeat = mode
+}
+
+// Repeat возвращает текущий режим повтора
+func (q *Queue) Repeat() RepeatMode {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.repeat
+}
+
+// CycleRepeat переключает режим повтора по кругу и возвращает новый режим
+func (q *Queue) CycleRepeat() RepeatMode {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.repeat = (q.repeat + 1) % (RepeatAll + 1)
+	return q.repeat
+}
+
+// Shuffle возвращает true, если перемешивание включено
+func (q *Queue) Shuffle() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.shuffle
+}
+
+// Seed возвращает зерно перемешивания
+func (q *Queue) Seed() int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.seed
+}
+
+// SetShuffle включает или выключает перемешивание. Одно и то же зерно
+// дает одинаковый порядок. Текущий трек при этом остается текущим.
+func (q *Queue) SetShuffle(enabled bool, seed int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	current := -1
+	if q.cursor >= 0 && q.cursor < len(q.order) {
+		current = q.order[q.cursor]
+	}
+
+	q.shuffle = enabled
+	q.seed = seed
+
+	if enabled {
+		q.rng = rand.New(rand.NewSource(seed))
+		q.order = q.rng.Perm(len(q.tracks))
+	} else {
+		q.order = make([]int, len(q.tracks))
+		for i := range q.order {
+			q.order[i] = i
+		}
+	}
+
+	if current < 0 {
+		return
+	}
+
+	// При перемешивании текущий трек переносим в начало, чтобы дальше
+	// проигрывались все остальные треки; без перемешивания продолжаем
+	// с его места в исходном порядке
+	for i, index := range q.order {
+		if index == current {
+			if enabled {
+				q.order[0], q.order[i] = q.order[i], q.order[0]
+				q.cursor = 0
+			} else {
+				q.cursor = i
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package player
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hazadus/go-snatcher/internal/data"
+)
+
+// newTestQueue создает очередь из треков с указанными ID
+func newTestQueue(ids ...int) *Queue {
+	tracks := make([]data.TrackMetadata, len(ids))
+	for i, id := range ids {
+		tracks[i] = data.TrackMetadata{ID: id, Title: "Track"}
+	}
+	return NewQueue(tracks...)
+}
+
+// trackIDs возвращает ID треков в порядке воспроизведения
+func trackIDs(tracks []data.TrackMetadata) []int {
+	ids := make([]int, len(tracks))
+	for i, track := range tracks {
+		ids[i] = track.ID
+	}
+	return ids
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQueueNextPrevious(t *testing.T) {
+	q := newTestQueue(3, 7, 12)
+
+	if _, ok := q.Current(); ok {
+		t.Error("До начала воспроизведения текущего трека быть не должно")
+	}
+
+	for _, expected := range []int{3, 7, 12} {
+		track, ok := q.Next()
+		if !ok || track.ID != expected {
+			t.Fatalf("Ожидался трек %d, получен %d (ok=%v)", expected, track.ID, ok)
+		}
+	}
+
+	// Без повтора после последнего трека очередь заканчивается
+	if _, ok := q.Next(); ok {
+		t.Error("После последнего трека Next должен вернуть false")
+	}
+
+	track, ok := q.Previous()
+	if !ok || track.ID != 7 {
+		t.Errorf("Ожидался предыдущий трек 7, получен %d (ok=%v)", track.ID, ok)
+	}
+
+	q.Previous()
+	if _, ok := q.Previous(); ok {
+		t.Error("С первого трека Previous должен вернуть false")
+	}
+}
+
+func TestQueueRepeat(t *testing.T) {
+	q := newTestQueue(1, 2)
+
+	q.SetRepeat(RepeatAll)
+	var played []int
+	for i := 0; i < 5; i++ {
+		track, ok := q.Advance()
+		if !ok {
+			t.Fatal("В режиме RepeatAll очередь не должна заканчиваться")
+		}
+		played = append(played, track.ID)
+	}
+	if !equalIDs(played, []int{1, 2, 1, 2, 1}) {
+		t.Errorf("Неверный порядок в режиме RepeatAll: %v", played)
+	}
+
+	q.SetRepeat(RepeatOne)
+	for i := 0; i < 3; i++ {
+		track, _ := q.Advance()
+		if track.ID != 1 {
+			t.Errorf("В режиме RepeatOne ожидался трек 1, получен %d", track.ID)
+		}
+	}
+
+	// Ручной переход работает и в режиме RepeatOne
+	if track, _ := q.Next(); track.ID != 2 {
+		t.Errorf("Next в режиме RepeatOne должен перейти к треку 2, получен %d", track.ID)
+	}
+
+	if mode := q.CycleRepeat(); mode != RepeatAll {
+		t.Errorf("После RepeatOne ожидался RepeatAll, получен %v", mode)
+	}
+	if mode := q.CycleRepeat(); mode != RepeatOff {
+		t.Errorf("После RepeatAll ожидался RepeatOff, получен %v", mode)
+	}
+}
+
+func TestQueueShuffleReproducible(t *testing.T) {
+	ids := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+	q1 := newTestQueue(ids...)
+	q1.SetShuffle(true, 42)
+	q2 := newTestQueue(ids...)
+	q2.SetShuffle(true, 42)
+
+	order1 := trackIDs(q1.Tracks())
+	order2 := trackIDs(q2.Tracks())
+	if !equalIDs(order1, order2) {
+		t.Errorf("Одно зерно должно давать одинаковый порядок: %v и %v", order1, order2)
+	}
+
+	// Выключение перемешивания возвращает исходный порядок
+	q1.SetShuffle(false, 0)
+	if order := trackIDs(q1.Tracks()); !equalIDs(order, ids) {
+		t.Errorf("Ожидался исходный порядок, получен %v", order)
+	}
+}
+
+func TestQueueShuffleKeepsCurrent(t *testing.T) {
+	q := newTestQueue(1, 2, 3, 4, 5)
+	q.Next()
+	q.Next() // Текущий трек - 2
+
+	q.SetShuffle(true, 7)
+	current, ok := q.Current()
+	if !ok || current.ID != 2 {
+		t.Fatalf("Текущий трек должен сохраниться при перемешивании, получен %d", current.ID)
+	}
+
+	// Дальше проигрываются все остальные треки
+	seen := map[int]bool{}
+	for {
+		track, ok := q.Next()
+		if !ok {
+			break
+		}
+		seen[track.ID] = true
+	}
+	if len(seen) != 4 || seen[2] {
+		t.Errorf("После перемешивания должны проиграться остальные 4 трека, получено %v", seen)
+	}
+}
+
+func TestQueueAddWhileShuffled(t *testing.T) {
+	newQueue := func() *Queue {
+		q := newTestQueue(1, 2, 3, 4, 5)
+		q.SetShuffle(true, 7)
+		q.Next()
+		q.Next()
+		for id := 6; id <= 25; id++ {
+			q.Add(data.TrackMetadata{ID: id})
+		}
+		return q
+	}
+	q := newQueue()
+
+	before := newTestQueue(1, 2, 3, 4, 5)
+	before.SetShuffle(true, 7)
+	played := trackIDs(before.Tracks())[:2]
+	order := trackIDs(q.Tracks())
+	if len(order) != 25 || !equalIDs(order[:2], played) {
+		t.Fatalf("Сыгранные треки не должны сдвигаться: %v, ожидалось начало %v", order, played)
+	}
+
+	// Добавленные треки перемешиваются с оставшимися, а не встают в конец по порядку
+	var added []int
+	for _, id := range order[2:] {
+		if id > 5 {
+			added = append(added, id)
+		}
+	}
+	if len(added) != 20 || equalIDs(order[len(order)-20:], added) && slices.IsSorted(added) {
+		t.Errorf("Добавленные треки не перемешаны: %v", order)
+	}
+
+	// Одно зерно дает одинаковый порядок и с добавленными треками
+	if again := trackIDs(newQueue().Tracks()); !equalIDs(again, order) {
+		t.Errorf("Одно зерно должно давать одинаковый порядок: %v и %v", order, again)
+	}
+}
+
+func TestQueueJump(t *testing.T) {
+	q := newTestQueue(5, 6, 7)
+
+	if !q.Jump(6) {
+		t.Fatal("Трек 6 должен быть найден в очереди")
+	}
+	if track, _ := q.Next(); track.ID != 7 {
+		t.Errorf("После перехода к треку 6 следующим должен быть 7, получен %d", track.ID)
+	}
+	if q.Jump(100) {
+		t.Error("Отсутствующий трек не должен быть найден")
+	}
+}
+
+func TestParseRepeatMode(t *testing.T) {
+	tests := map[string]RepeatMode{"": RepeatOff, "off": RepeatOff, "one": RepeatOne, "all": RepeatAll}
+	for input, expected := range tests {
+		mode, err := ParseRepeatMode(input)
+		if err != nil || mode != expected {
+			t.Errorf("ParseRepeatMode(%q) = %v, %v; ожидалось %v", input, mode, err, expected)
+		}
+	}
+
+	if _, err := ParseRepeatMode("sometimes"); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного режима")
+	}
+}
+
+func TestQueuePeek(t *testing.T) {
+	q := newTestQueue(1, 2)
+
+	if track, ok := q.Peek(); !ok || track.ID != 1 {
+		t.Errorf("До начала воспроизведения Peek должен вернуть первый трек, получен %d (ok=%v)", track.ID, ok)
+	}
+
+	q.Next()
+	if track, ok := q.Peek(); !ok || track.ID != 2 {
+		t.Errorf("Ожидался следующий трек 2, получен %d (ok=%v)", track.ID, ok)
+	}
+	if track, _ := q.Current(); track.ID != 1 {
+		t.Errorf("Peek не должен менять текущий трек, текущий %d", track.ID)
+	}
+
+	q.SetRepeat(RepeatOne)
+	if track, ok := q.Peek(); !ok || track.ID != 1 {
+		t.Errorf("В режиме RepeatOne Peek должен вернуть текущий трек, получен %d", track.ID)
+	}
+
+	q.SetRepeat(RepeatOff)
+	q.Next()
+	if _, ok := q.Peek(); ok {
+		t.Error("После последнего трека без повтора Peek должен вернуть false")
+	}
+
+	q.SetRepeat(RepeatAll)
+	if track, ok := q.Peek(); !ok || track.ID != 1 {
+		t.Errorf("В режиме RepeatAll после последнего трека ожидался первый, получен %d", track.ID)
+	}
+}
@@ -0,0 +1,327 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/mp3"
+
+	"github.com/hazadus/go-snatcher/internal/audio"
+	"github.com/hazadus/go-snatcher/internal/cache"
+	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/metadata"
+	"github.com/hazadus/go-snatcher/internal/player/streaming"
+)
+
+// detectFormat определяет формат трека. Формат из библиотеки используется,
+// если он указан; для треков, добавленных до поддержки других форматов,
+// он определяется по сигнатуре начала файла, Content-Type ответа сервера
+// и расширению URL.
+func detectFormat(track *data.TrackMetadata, source cache.Source, stream *streaming.Reader) audio.Format {
+	if track.Format != "" {
+		if format, err := audio.Parse(track.Format); err == nil {
+			return format
+		}
+	}
+
+	var header []byte
+	var contentType string
+	if stream != nil {
+		// Peek не сдвигает позицию, поэтому запись в кэш не нарушается
+		header, _ = stream.Peek(audio.HeaderSize)
+		contentType = stream.ContentType()
+	} else if file, ok := source.(io.ReaderAt); ok {
+		buf := make([]byte, audio.HeaderSize)
+		n, _ := file.ReadAt(buf, 0)
+		header = buf[:n]
+	}
+	return audio.Detect(header, contentType, track.URL)
+}
+
+// probeDuration определяет длительность MP3, которая не указана в
+// библиотеке: без нее перемотка невозможна. Длительность читается из
+// заголовков файла в кэше или из начала удаленного файла, скачанного
+// отдельным запросом с заголовком Range. При ошибке возвращается 0.
+func probeDuration(ctx context.Context, track *data.TrackMetadata, source cache.Source) time.Duration {
+	var duration time.Duration
+	var err error
+	if file, ok := source.(io.ReaderAt); ok {
+		duration, err = metadata.MP3Duration(file, source.Size())
+	} else {
+		duration, err = metadata.NewExtractor().GetRemoteDuration(ctx, track.URL)
+	}
+	if err != nil {
+		return 0
+	}
+	return duration
+}
+
+// openStream создает декодер трека указанного формата. MP3 декодируется
+// через remoteStream, остальные декодеры перематывают источник сами.
+// При ошибке источник остается открытым.
+func openStream(source cache.Source, format audio.Format, duration time.Duration) (beep.StreamSeekCloser, beep.Format, error) {
+	if format == audio.FormatMP3 {
+		streamer, decoded, err := newRemoteStream(source, duration)
+		if err != nil {
+			return nil, beep.Format{}, fmt.Errorf("ошибка декодирования MP3: %w", err)
+		}
+		return streamer, decoded, nil
+	}
+
+	streamer, decoded, err := audio.Decode(format, seekableView{Source: source})
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	return &sourceStream{StreamSeekCloser: streamer, source: source}, decoded, nil
+}
+
+// seekableView передает источник декодеру, не позволяя ему закрыть источник
+type seekableView struct {
+	cache.Source
+}
+
+// Close ничего не делает: источником владеет sourceStream
+func (v seekableView) Close() error {
+	return nil
+}
+
+// sourceStream закрывает источник вместе с декодером
+type sourceStream struct {
+	beep.StreamSeekCloser
+	source cache.Source
+}
+
+// Close закрывает декодер и источник
+func (s *sourceStream) Close() error {
+	s.StreamSeekCloser.Close()
+	return s.source.Close()
+}
+
+// sourceView скрывает io.Seeker у потокового ридера. Если передать
+// декодеру MP3 ридер с поддержкой Seek, он просканирует весь файл ради
+// подсчета длины, то есть фактически скачает микс целиком до начала
+// воспроизведения.
+type sourceView struct {
+	reader io.Reader
+}
+
+func (v sourceView) Read(p []byte) (int, error) {
+	return v.reader.Read(p)
+}
+
+// Close ничего не делает: ридером владеет remoteStream
+func (v sourceView) Close() error {
+	return nil
+}
+
+// remoteStream декодирует MP3 из источника (потокового ридера или файла
+// из кэша) и реализует beep.StreamSeekCloser. Перемотка выполняется
+// переходом к байтовому смещению, пропорциональному позиции в треке,
+// и повторным запуском декодера.
+type remoteStream struct {
+	source    cache.Source
+	decoder   beep.StreamSeekCloser
+	format    beep.Format
+	length    int   // Общая длина в сэмплах (0, если неизвестна)
+	base      int   // Позиция в сэмплах, с которой запущен текущий декодер
+	dataStart int64 // Смещение начала аудиоданных (-1, пока не вычислено)
+	err       error
+}
+
+// newRemoteStream создает декодер для источника. Длительность трека
+// нужна для пересчета позиции в байтовое смещение при перемотке.
+func newRemoteStream(source cache.Source, duration time.Duration) (*remoteStream, beep.Format, error) {
+	decoder, format, err := mp3.Decode(sourceView{reader: source})
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+
+	s := &remoteStream{
+		source:    source,
+		decoder:   decoder,
+		format:    format,
+		dataStart: -1,
+	}
+	if duration > 0 {
+		s.length = format.SampleRate.N(duration)
+	}
+
+	return s, format, nil
+}
+
+// Stream реализует интерфейс beep.Streamer
+func (s *remoteStream) Stream(samples [][2]float64) (n int, ok bool) {
+	if s.err != nil {
+		return 0, false
+	}
+	return s.decoder.Stream(samples)
+}
+
+// Err возвращает ошибку потока
+func (s *remoteStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.decoder.Err()
+}
+
+// Len возвращает общую длину трека в сэмплах
+func (s *remoteStream) Len() int {
+	if pos := s.Position(); pos > s.length {
+		return pos
+	}
+	return s.length
+}
+
+// Position возвращает текущую позицию в сэмплах
+func (s *remoteStream) Position() int {
+	return s.base + s.decoder.Position()
+}
+
+// checkSeek проверяет, что поток можно перематывать. Длина и размер
+// источника не меняются после создания потока, поэтому проверка не
+// требует блокировки вывода.
+func (s *remoteStream) checkSeek() error {
+	if s.length <= 0 || s.source.Size() < 0 {
+		return fmt.Errorf("перемотка невозможна: длина потока неизвестна")
+	}
+	return nil
+}
+
+// Seek перематывает поток на позицию p (в сэмплах)
+func (s *remoteStream) Seek(p int) error {
+	if err := s.checkSeek(); err != nil {
+		return err
+	}
+	if p < 0 || p > s.length {
+		return fmt.Errorf("позиция %d вне диапазона [0, %d]", p, s.length)
+	}
+
+	if s.dataStart < 0 {
+		dataStart, err := s.findDataStart()
+		if err != nil {
+			return err
+		}
+		s.dataStart = dataStart
+	}
+
+	// Считаем битрейт постоянным и пересчитываем позицию пропорционально
+	dataSize := s.source.Size() - s.dataStart
+	offset := s.dataStart + int64(float64(dataSize)*float64(p)/float64(s.length))
+
+	if _, err := s.source.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("ошибка перемотки потока: %w", err)
+	}
+
+	// Декодер сам найдет ближайший заголовок фрейма после смещения
+	decoder, _, err := mp3.Decode(sourceView{reader: s.source})
+	if err != nil {
+		s.err = fmt.Errorf("ошибка декодирования MP3 после перемотки: %w", err)
+		return s.err
+	}
+
+	s.decoder.Close()
+	s.decoder = decoder
+	s.base = p
+	s.err = nil
+
+	return nil
+}
+
+// Close закрывает декодер и источник
+func (s *remoteStream) Close() error {
+	s.decoder.Close()
+	return s.source.Close()
+}
+
+// findDataStart определяет смещение начала аудиоданных, пропуская тег ID3v2
+func (s *remoteStream) findDataStart() (int64, error) {
+	if _, err := s.source.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("ошибка перемотки потока: %w", err)
+	}
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(s.source, header); err != nil {
+		return 0, fmt.Errorf("ошибка чтения заголовка: %w", err)
+	}
+
+	return metadata.ID3v2Size(header), nil
+}
+
+// seekChecker реализуют потоки, которые можно перематывать не всегда
+type seekChecker interface {
+	checkSeek() error
+}
+
+// trackedStream запоминает позицию и длину потока после каждого чтения и
+// перемотки. Вывод звука читает поток под своей блокировкой, и если декодер
+// ждет данных из сети, блокировка держится долго; trackedStream позволяет
+// узнать позицию и запросить перемотку, не дожидаясь ее.
+type trackedStream struct {
+	beep.StreamSeekCloser
+	position    atomic.Int64
+	length      atomic.Int64
+	pendingSeek atomic.Int64 // Позиция запрошенной перемотки (-1, если ее нет)
+	finished    atomic.Bool  // Поток доигран до конца и вывод перешел к следующему
+}
+
+// newTrackedStream оборачивает поток и запоминает его начальное состояние
+func newTrackedStream(streamer beep.StreamSeekCloser) *trackedStream {
+	t := &trackedStream{StreamSeekCloser: streamer}
+	t.pendingSeek.Store(-1)
+	t.update()
+	return t
+}
+
+// Stream реализует интерфейс beep.Streamer. Запрошенная перемотка
+// выполняется перед чтением.
+func (t *trackedStream) Stream(samples [][2]float64) (int, bool) {
+	if p := t.pendingSeek.Swap(-1); p >= 0 {
+		// При ошибке поток продолжает играть с прежней позиции
+		_ = t.Seek(int(p))
+	}
+	n, ok := t.StreamSeekCloser.Stream(samples)
+	t.update()
+	return n, ok
+}
+
+// Seek перематывает поток на позицию p (в сэмплах)
+func (t *trackedStream) Seek(p int) error {
+	err := t.StreamSeekCloser.Seek(p)
+	t.update()
+	return err
+}
+
+// checkSeek проверяет, что поток можно перематывать
+func (t *trackedStream) checkSeek() error {
+	if checker, ok := t.StreamSeekCloser.(seekChecker); ok {
+		return checker.checkSeek()
+	}
+	return nil
+}
+
+// requestSeek запрашивает перемотку на позицию p (в сэмплах). Поток
+// перематывается при следующем чтении, а позиция сразу считается новой.
+func (t *trackedStream) requestSeek(p int) {
+	t.pendingSeek.Store(int64(p))
+	t.position.Store(int64(p))
+}
+
+// lastPosition возвращает позицию после последнего чтения (в сэмплах)
+func (t *trackedStream) lastPosition() int {
+	return int(t.position.Load())
+}
+
+// lastLen возвращает длину потока после последнего чтения (в сэмплах)
+func (t *trackedStream) lastLen() int {
+	return int(t.length.Load())
+}
+
+func (t *trackedStream) update() {
+	t.position.Store(int64(t.StreamSeekCloser.Position()))
+	t.length.Store(int64(t.StreamSeekCloser.Len()))
+}
@@ -4,23 +4,101 @@ package streaming
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Reader представляет буферизованный поток для чтения данных порциями
+// ErrClosed возвращается при обращении к закрытому ридеру
+var ErrClosed = errors.New("потоковый ридер закрыт")
+
+// errRangeUnsupported возвращается, если сервер игнорирует заголовок Range
+var errRangeUnsupported = errors.New("сервер не поддерживает запросы с диапазоном байт")
+
+// errPrematureEOF возвращается, если соединение закрылось раньше конца ресурса
+var errPrematureEOF = errors.New("соединение закрыто до конца потока")
+
+// Параметры переподключения по умолчанию
+const (
+	DefaultMaxReconnects  = 8 // Количество попыток переподключения подряд
+	defaultReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay     = 30 * time.Second // Предел экспоненциальной задержки
+	defaultReadTimeout    = 20 * time.Second // Чтение дольше этого считается зависшим
+)
+
+// statusError описывает неуспешный HTTP ответ
+type statusError struct {
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("ошибка HTTP: %s", e.status)
+}
+
+// Reader представляет буферизованный поток для чтения данных порциями.
+// Reader реализует io.ReadSeeker: при перемотке соединение переоткрывается
+// запросом с заголовком Range, начиная с новой позиции. Если соединение
+// обрывается или зависает посреди потока, Reader переподключается с той же
+// позиции с экспоненциально растущей задержкой между попытками.
 type Reader struct {
+	ctx        context.Context
+	client     *http.Client
+	url        string
 	reader     *bufio.Reader
 	resp       *http.Response
 	bufferSize int
+	size       int64  // Общий размер ресурса в байтах (-1, если неизвестен)
+	mediaType  string // Значение заголовка Content-Type ответа
+	offset     int64  // Текущая позиция чтения в байтах
+	closed     bool
+
+	// Переподключение
+	maxReconnects  int
+	reconnectDelay time.Duration // Задержка перед первой попыткой, дальше она удваивается
+	readTimeout    time.Duration
+	attempt        atomic.Int32  // Номер текущей попытки переподключения (0 - соединение в порядке)
+	respMutex      sync.Mutex    // Защищает resp от закрытия из таймера и Cancel
+	done           chan struct{} // Закрывается в Cancel, прерывает ожидание переподключения
+	cancelOnce     sync.Once
 }
 
 // NewReader создает новый потоковый ридер
 func NewReader(ctx context.Context, url string, bufferSize int) (*Reader, error) {
-	// Создаем HTTP клиент без таймаута для длительного потокового чтения
-	client := &http.Client{
+	sr := &Reader{
+		ctx:        ctx,
+		client:     newHTTPClient(),
+		url:        url,
+		bufferSize: bufferSize,
+		size:       -1,
+
+		maxReconnects:  DefaultMaxReconnects,
+		reconnectDelay: defaultReconnectDelay,
+		readTimeout:    defaultReadTimeout,
+		done:           make(chan struct{}),
+	}
+
+	// Узнаем размер ресурса заранее, чтобы поддерживать перемотку от конца
+	sr.size = sr.fetchContentLength()
+
+	// Открываем поток с начала, чтобы ошибки доступа обнаруживались сразу
+	if err := sr.open(0); err != nil {
+		return nil, err
+	}
+
+	return sr, nil
+}
+
+// newHTTPClient создает HTTP клиент без таймаута для длительного потокового чтения
+func newHTTPClient() *http.Client {
+	return &http.Client{
 		// Убираем общий таймаут, оставляем только таймауты соединения
 		Transport: &http.Transport{
 			// Настройки для оптимального потокового чтения
@@ -41,45 +119,343 @@ func NewReader(ctx context.Context, url string, bufferSize int) (*Reader, error)
 			ExpectContinueTimeout: 1 * time.Second,
 		},
 	}
+}
+
+// fetchContentLength определяет размер ресурса с помощью HEAD-запроса.
+// Возвращает -1, если размер узнать не удалось.
+func (sr *Reader) fetchContentLength() int64 {
+	req, err := http.NewRequestWithContext(sr.ctx, http.MethodHead, sr.url, nil)
+	if err != nil {
+		return -1
+	}
+	req.Header.Set("Accept-Encoding", "identity")
+	req.Header.Set("User-Agent", "go-snatcher/1.0")
+
+	resp, err := sr.client.Do(req)
+	if err != nil {
+		return -1
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return -1
+	}
+	return resp.ContentLength
+}
 
+// open выполняет запрос с заголовком Range, начиная с указанного смещения
+func (sr *Reader) open(offset int64) error {
 	// Создаем запрос с заголовками для потокового чтения
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(sr.ctx, http.MethodGet, sr.url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+		return fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 
 	// Добавляем заголовки для оптимизации потокового чтения
-	req.Header.Set("Accept-Encoding", "identity")   // Отключаем сжатие для потока
-	req.Header.Set("Range", "bytes=0-")             // Указываем, что хотим читать с начала
-	req.Header.Set("Connection", "keep-alive")      // Поддерживаем соединение
-	req.Header.Set("User-Agent", "go-snatcher/1.0") // Идентифицируем клиент
+	req.Header.Set("Accept-Encoding", "identity")             // Отключаем сжатие для потока
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset)) // Указываем, с какой позиции читать
+	req.Header.Set("Connection", "keep-alive")                // Поддерживаем соединение
+	req.Header.Set("User-Agent", "go-snatcher/1.0")           // Идентифицируем клиент
 
-	resp, err := client.Do(req)
+	resp, err := sr.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+		return fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
 
 	// Проверяем статус ответа
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		resp.Body.Close()
-		return nil, fmt.Errorf("ошибка HTTP: %s", resp.Status)
+		return &statusError{status: resp.Status, code: resp.StatusCode}
 	}
 
-	return &Reader{
-		reader:     bufio.NewReaderSize(resp.Body, bufferSize),
-		resp:       resp,
-		bufferSize: bufferSize,
-	}, nil
+	// Сервер, игнорирующий Range, отдает файл с начала
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return errRangeUnsupported
+	}
+
+	// Если HEAD-запрос не дал размер, берем его из ответа
+	if sr.size < 0 {
+		sr.size = parseTotalSize(resp)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		sr.mediaType = contentType
+	}
+
+	if sr.reader == nil {
+		sr.reader = bufio.NewReaderSize(resp.Body, sr.bufferSize)
+	} else {
+		sr.reader.Reset(resp.Body)
+	}
+	sr.respMutex.Lock()
+	sr.resp = resp
+	sr.respMutex.Unlock()
+	sr.offset = offset
+
+	return nil
+}
+
+// parseTotalSize извлекает полный размер ресурса из ответа сервера
+func parseTotalSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusOK {
+		return resp.ContentLength
+	}
+
+	// Формат заголовка: "bytes 0-1023/4096"
+	contentRange := resp.Header.Get("Content-Range")
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return -1
+	}
+	total, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
+
+// Read реализует интерфейс io.Reader для потокового чтения. Обрыв
+// соединения не прерывает чтение: Read переподключается с текущей позиции
+// и возвращает ошибку, только если все попытки исчерпаны.
+func (sr *Reader) Read(p []byte) (int, error) {
+	if sr.closed {
+		return 0, ErrClosed
+	}
+	if sr.size >= 0 && sr.offset >= sr.size {
+		return 0, io.EOF
+	}
+
+	var err error
+	attempt := 0
+	for ; ; attempt++ {
+		if attempt > 0 {
+			sr.attempt.Store(int32(attempt))
+			if waitErr := sr.waitReconnect(attempt); waitErr != nil {
+				sr.attempt.Store(0)
+				return 0, waitErr
+			}
+		}
+
+		var n int
+		n, err = sr.readOnce(p)
+		if n > 0 || err == nil {
+			sr.attempt.Store(0)
+			if err != nil && sr.retryable(err) {
+				// Данные отдаем сейчас, а переподключимся при следующем чтении
+				sr.closeBody()
+				err = nil
+			}
+			return n, err
+		}
+
+		if !sr.retryable(err) || attempt >= sr.maxReconnects {
+			break
+		}
+		sr.closeBody()
+	}
+
+	sr.attempt.Store(0)
+	if attempt > 0 {
+		return 0, fmt.Errorf("не удалось восстановить соединение: %w", err)
+	}
+	return 0, err
+}
+
+// readOnce выполняет одно чтение, открывая соединение при необходимости
+func (sr *Reader) readOnce(p []byte) (int, error) {
+	// После перемотки или обрыва соединение открывается заново при чтении
+	if sr.resp == nil {
+		if err := sr.open(sr.offset); err != nil {
+			return 0, err
+		}
+	}
+
+	// Зависшее соединение закрываем по таймеру, чтобы чтение завершилось ошибкой
+	timer := time.AfterFunc(sr.readTimeout, sr.abortBody)
+	n, err := sr.reader.Read(p)
+	timer.Stop()
+
+	sr.offset += int64(n)
+	if err == io.EOF && sr.size >= 0 && sr.offset < sr.size {
+		err = errPrematureEOF
+	}
+	return n, err
 }
 
-// Read реализует интерфейс io.Reader для потокового чтения
-func (sr *Reader) Read(p []byte) (n int, err error) {
-	return sr.reader.Read(p)
+// retryable возвращает true, если после ошибки имеет смысл переподключиться
+func (sr *Reader) retryable(err error) bool {
+	if err == io.EOF || errors.Is(err, ErrClosed) || errors.Is(err, errRangeUnsupported) {
+		return false
+	}
+	if sr.ctx.Err() != nil || sr.cancelled() {
+		return false
+	}
+
+	// Ошибки клиента (кроме таймаута и лимита запросов) не исправятся повтором
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 ||
+			statusErr.code == http.StatusRequestTimeout ||
+			statusErr.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// waitReconnect ждет перед попыткой переподключения. Задержка удваивается
+// с каждой попыткой, но не превышает maxReconnectDelay.
+func (sr *Reader) waitReconnect(attempt int) error {
+	delay := sr.reconnectDelay << (attempt - 1)
+	if delay > maxReconnectDelay || delay <= 0 {
+		delay = maxReconnectDelay
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-sr.done:
+		return ErrClosed
+	case <-sr.ctx.Done():
+		return sr.ctx.Err()
+	}
+}
+
+// cancelled возвращает true, если был вызван Cancel
+func (sr *Reader) cancelled() bool {
+	select {
+	case <-sr.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReconnectAttempt возвращает номер текущей попытки переподключения
+// или 0, если соединение в порядке. Безопасен для вызова из любой горутины.
+func (sr *Reader) ReconnectAttempt() int {
+	return int(sr.attempt.Load())
+}
+
+// Cancel прерывает текущее чтение и ожидание переподключения. В отличие
+// от Close безопасен для вызова из любой горутины, в том числе во время Read.
+func (sr *Reader) Cancel() {
+	sr.cancelOnce.Do(func() {
+		close(sr.done)
+	})
+	sr.abortBody()
+}
+
+// abortBody закрывает тело текущего ответа, не трогая состояние ридера:
+// незавершенное чтение вернет ошибку
+func (sr *Reader) abortBody() {
+	sr.respMutex.Lock()
+	defer sr.respMutex.Unlock()
+	if sr.resp != nil {
+		sr.resp.Body.Close()
+	}
+}
+
+// Seek реализует интерфейс io.Seeker. Перемотка вперед в пределах
+// буфера выполняется без нового запроса, в остальных случаях текущее
+// соединение закрывается и переоткрывается при следующем чтении.
+func (sr *Reader) Seek(offset int64, whence int) (int64, error) {
+	if sr.closed {
+		return 0, ErrClosed
+	}
+
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = sr.offset + offset
+	case io.SeekEnd:
+		if sr.size < 0 {
+			return 0, fmt.Errorf("размер потока неизвестен")
+		}
+		target = sr.size + offset
+	default:
+		return 0, fmt.Errorf("неверное значение whence: %d", whence)
+	}
+
+	if target < 0 {
+		return 0, fmt.Errorf("отрицательная позиция: %d", target)
+	}
+
+	if target == sr.offset {
+		return target, nil
+	}
+
+	// Небольшую перемотку вперед выполняем за счет уже прочитанных данных
+	if sr.resp != nil && target > sr.offset && target-sr.offset <= int64(sr.reader.Buffered()) {
+		discarded, err := sr.reader.Discard(int(target - sr.offset))
+		sr.offset += int64(discarded)
+		return sr.offset, err
+	}
+
+	sr.closeBody()
+	sr.offset = target
+	return target, nil
+}
+
+// Peek возвращает следующие n байт потока, не сдвигая позицию чтения.
+// Если до конца потока осталось меньше n байт, возвращаются оставшиеся.
+func (sr *Reader) Peek(n int) ([]byte, error) {
+	if sr.closed {
+		return nil, ErrClosed
+	}
+	if sr.resp == nil {
+		if err := sr.open(sr.offset); err != nil {
+			return nil, err
+		}
+	}
+
+	timer := time.AfterFunc(sr.readTimeout, sr.abortBody)
+	data, err := sr.reader.Peek(n)
+	timer.Stop()
+
+	if err == io.EOF {
+		err = nil
+	}
+	return data, err
+}
+
+// ContentType возвращает заголовок Content-Type ответа сервера
+func (sr *Reader) ContentType() string {
+	return sr.mediaType
+}
+
+// Size возвращает полный размер потока в байтах или -1, если он неизвестен
+func (sr *Reader) Size() int64 {
+	return sr.size
 }
 
 // Close закрывает соединение
 func (sr *Reader) Close() error {
-	return sr.resp.Body.Close()
+	if sr.closed {
+		return nil
+	}
+	sr.closed = true
+	sr.cancelOnce.Do(func() {
+		close(sr.done)
+	})
+	return sr.closeBody()
+}
+
+// closeBody закрывает тело текущего ответа, если оно открыто
+func (sr *Reader) closeBody() error {
+	sr.respMutex.Lock()
+	defer sr.respMutex.Unlock()
+
+	if sr.resp == nil {
+		return nil
+	}
+	err := sr.resp.Body.Close()
+	sr.resp = nil
+	return err
 }
 
 // GetStreamStatus возвращает текстовое описание состояния потока
@@ -95,3 +471,8 @@ func GetStreamStatus(stuckCount int) string {
 		return "Возможная проблема с соединением"
 	}
 }
+
+// GetReconnectStatus возвращает текстовое описание попытки переподключения
+func GetReconnectStatus(attempt int) string {
+	return fmt.Sprintf("Переподключение (попытка %d из %d)...", attempt, DefaultMaxReconnects)
+}
@@ -0,0 +1,379 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer создает HTTP сервер, отдающий содержимое с поддержкой Range
+func newTestServer(t *testing.T, content []byte) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests++
+		}
+		http.ServeContent(w, r, "test.mp3", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestReaderReadAll(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+	server, _ := newTestServer(t, content)
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+
+	if reader.Size() != int64(len(content)) {
+		t.Errorf("Ожидался размер %d, получен %d", len(content), reader.Size())
+	}
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Ошибка чтения: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("Прочитанные данные не совпадают с исходными")
+	}
+}
+
+func TestReaderSeek(t *testing.T) {
+	content := make([]byte, 64*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	server, requests := newTestServer(t, content)
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+
+	tests := []struct {
+		name     string
+		offset   int64
+		whence   int
+		expected int64
+	}{
+		{"от начала", 40000, io.SeekStart, 40000},
+		{"назад от текущей", -30000, io.SeekCurrent, 10016},
+		{"от конца", -100, io.SeekEnd, int64(len(content)) - 100},
+	}
+
+	for _, test := range tests {
+		pos, err := reader.Seek(test.offset, test.whence)
+		if err != nil {
+			t.Fatalf("%s: ошибка перемотки: %v", test.name, err)
+		}
+		if pos != test.expected {
+			t.Fatalf("%s: ожидалась позиция %d, получена %d", test.name, test.expected, pos)
+		}
+
+		buf := make([]byte, 16)
+		n, err := io.ReadFull(reader, buf)
+		if err != nil {
+			t.Fatalf("%s: ошибка чтения: %v", test.name, err)
+		}
+		if !bytes.Equal(buf[:n], content[pos:pos+int64(n)]) {
+			t.Errorf("%s: данные после перемотки не совпадают", test.name)
+		}
+	}
+
+	// Каждая перемотка за пределы буфера требует нового запроса
+	if *requests != 4 {
+		t.Errorf("Ожидалось 4 GET-запроса, выполнено %d", *requests)
+	}
+
+	// Чтение с конца потока возвращает io.EOF
+	if _, err := reader.Seek(0, io.SeekEnd); err != nil {
+		t.Fatalf("Ошибка перемотки в конец: %v", err)
+	}
+	if _, err := reader.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Ожидался io.EOF, получено %v", err)
+	}
+}
+
+func TestReaderSeekWithinBuffer(t *testing.T) {
+	content := []byte(strings.Repeat("abcdefgh", 512))
+	server, requests := newTestServer(t, content)
+
+	reader, err := NewReader(context.Background(), server.URL, 4096)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("Ошибка чтения: %v", err)
+	}
+
+	// Перемотка вперед в пределах буфера не должна создавать новый запрос
+	if _, err := reader.Seek(100, io.SeekCurrent); err != nil {
+		t.Fatalf("Ошибка перемотки: %v", err)
+	}
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("Ошибка чтения: %v", err)
+	}
+	if !bytes.Equal(buf, content[108:116]) {
+		t.Error("Данные после перемотки не совпадают")
+	}
+	if *requests != 1 {
+		t.Errorf("Ожидался 1 GET-запрос, выполнено %d", *requests)
+	}
+}
+
+func TestReaderPeek(t *testing.T) {
+	content := []byte("fLaC" + strings.Repeat("x", 100))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/flac")
+		http.ServeContent(w, r, "mix", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+
+	if reader.ContentType() != "audio/flac" {
+		t.Errorf("Ожидался Content-Type audio/flac, получен %q", reader.ContentType())
+	}
+
+	header, err := reader.Peek(4)
+	if err != nil {
+		t.Fatalf("Ошибка чтения заголовка: %v", err)
+	}
+	if string(header) != "fLaC" {
+		t.Errorf("Ожидалась сигнатура fLaC, получено %q", header)
+	}
+
+	// Peek не сдвигает позицию чтения
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Ошибка чтения: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("Прочитанные данные не совпадают с исходными")
+	}
+
+	// У короткого остатка потока возвращается сколько есть
+	if _, err := reader.Seek(-2, io.SeekEnd); err != nil {
+		t.Fatalf("Ошибка перемотки: %v", err)
+	}
+	if header, err := reader.Peek(4); err != nil || len(header) != 2 {
+		t.Errorf("Ожидалось 2 байта без ошибки, получено %q, %v", header, err)
+	}
+}
+
+func TestReaderSeekInvalid(t *testing.T) {
+	server, _ := newTestServer(t, []byte("data"))
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+
+	if _, err := reader.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Ожидалась ошибка при перемотке на отрицательную позицию")
+	}
+
+	reader.Close()
+	if _, err := reader.Read(make([]byte, 1)); err != ErrClosed {
+		t.Errorf("Ожидалась ошибка ErrClosed, получено %v", err)
+	}
+}
+
+func TestReaderHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := NewReader(context.Background(), server.URL, 1024); err == nil {
+		t.Error("Ожидалась ошибка для ответа 404")
+	}
+}
+
+// abortWriter обрывает соединение после записи limit байт
+type abortWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *abortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		w.ResponseWriter.Write(p[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// newFlakyServer создает сервер, который обрывает первые drops GET-запросов
+// после отправки limit байт, а затем отдает содержимое нормально
+func newFlakyServer(t *testing.T, content []byte, drops, limit int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests++
+			if requests <= drops {
+				w = &abortWriter{ResponseWriter: w, limit: limit}
+			}
+		}
+		http.ServeContent(w, r, "test.mp3", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestReaderReconnect(t *testing.T) {
+	content := make([]byte, 200*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	server, requests := newFlakyServer(t, content, 2, 50*1024)
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+	reader.reconnectDelay = time.Millisecond
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Обрыв соединения не должен прерывать чтение: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("Данные после переподключения не совпадают с исходными")
+	}
+	if *requests != 3 {
+		t.Errorf("Ожидалось 3 GET-запроса (два обрыва), выполнено %d", *requests)
+	}
+	if reader.ReconnectAttempt() != 0 {
+		t.Error("После успешного чтения состояние переподключения должно сбрасываться")
+	}
+}
+
+func TestReaderReconnectStalled(t *testing.T) {
+	content := make([]byte, 100*1024)
+	release := make(chan struct{})
+	requests := 0
+
+	// Первый ответ зависает после части данных
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests++
+			if requests == 1 {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.Write(content[:10*1024])
+				w.(http.Flusher).Flush()
+				<-release
+				return
+			}
+		}
+		http.ServeContent(w, r, "test.mp3", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+	reader.reconnectDelay = time.Millisecond
+	reader.readTimeout = 50 * time.Millisecond
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Зависшее соединение должно переоткрываться: %v", err)
+	}
+	if len(got) != len(content) {
+		t.Errorf("Ожидалось %d байт, прочитано %d", len(content), len(got))
+	}
+}
+
+func TestReaderReconnectGivesUp(t *testing.T) {
+	content := make([]byte, 100*1024)
+	requests := 0
+
+	// После первого запроса сервер отвечает только ошибками
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests++
+			if requests > 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w = &abortWriter{ResponseWriter: w, limit: 1024}
+		}
+		http.ServeContent(w, r, "test.mp3", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+	reader.reconnectDelay = time.Millisecond
+	reader.maxReconnects = 3
+
+	if _, err := io.ReadAll(reader); err == nil {
+		t.Fatal("Ожидалась ошибка после исчерпания попыток")
+	}
+	if requests != 4 {
+		t.Errorf("Ожидалось 4 GET-запроса (1 + 3 попытки), выполнено %d", requests)
+	}
+}
+
+func TestReaderCancelDuringReconnect(t *testing.T) {
+	content := make([]byte, 100*1024)
+	server, _ := newFlakyServer(t, content, 100, 1024)
+
+	reader, err := NewReader(context.Background(), server.URL, 1024)
+	if err != nil {
+		t.Fatalf("Ошибка создания ридера: %v", err)
+	}
+	defer reader.Close()
+	reader.reconnectDelay = time.Hour
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(reader)
+		done <- err
+	}()
+
+	// Ждем, пока ридер начнет переподключаться
+	deadline := time.Now().Add(5 * time.Second)
+	for reader.ReconnectAttempt() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Ридер не перешел в состояние переподключения")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	reader.Cancel()
+	select {
+	case err := <-done:
+		if err != ErrClosed {
+			t.Errorf("Ожидалась ошибка ErrClosed, получено %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancel должен прерывать ожидание переподключения")
+	}
+}
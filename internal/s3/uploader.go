@@ -58,13 +58,18 @@ func NewUploader(config *Config) (*Uploader, error) {
 	}, nil
 }
 
-// UploadFile загружает файл в S3
-func (u *Uploader) UploadFile(ctx context.Context, reader io.Reader, key string) (string, error) {
-	_, err := u.s3Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+// UploadFile загружает файл в S3 с MIME-типом contentType. Если
+// contentType пустой, тип объекта определяет хранилище.
+func (u *Uploader) UploadFile(ctx context.Context, reader io.Reader, key, contentType string) (string, error) {
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(u.config.BucketName),
 		Key:    aws.String(key),
 		Body:   reader,
-	})
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err := u.s3Uploader.UploadWithContext(ctx, input)
 
 	if err != nil {
 		return "", fmt.Errorf("ошибка загрузки: %w", err)
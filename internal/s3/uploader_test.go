@@ -59,12 +59,16 @@ func NewTestUploader(config *Config, uploader S3UploaderInterface, client S3Clie
 }
 
 // UploadFile загружает файл в S3 (тестовая версия)
-func (u *TestUploader) UploadFile(ctx context.Context, reader io.Reader, key string) (string, error) {
-	_, err := u.s3Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+func (u *TestUploader) UploadFile(ctx context.Context, reader io.Reader, key, contentType string) (string, error) {
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(u.config.BucketName),
 		Key:    aws.String(key),
 		Body:   reader,
-	})
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err := u.s3Uploader.UploadWithContext(ctx, input)
 
 	if err != nil {
 		return "", fmt.Errorf("ошибка загрузки: %w", err)
@@ -110,6 +114,9 @@ func TestSuccessfulUpload(t *testing.T) {
 			if aws.StringValue(input.Key) != "test-file.mp3" {
 				t.Errorf("Ожидался key: test-file.mp3, получено: %s", aws.StringValue(input.Key))
 			}
+			if aws.StringValue(input.ContentType) != "audio/mpeg" {
+				t.Errorf("Ожидался ContentType: audio/mpeg, получено: %s", aws.StringValue(input.ContentType))
+			}
 
 			// Читаем содержимое для проверки
 			body, err := io.ReadAll(input.Body)
@@ -132,7 +139,7 @@ func TestSuccessfulUpload(t *testing.T) {
 	// Тестируем загрузку
 	ctx := context.Background()
 	reader := strings.NewReader("test content")
-	url, err := uploader.UploadFile(ctx, reader, "test-file.mp3")
+	url, err := uploader.UploadFile(ctx, reader, "test-file.mp3", "audio/mpeg")
 
 	if err != nil {
 		t.Errorf("Неожиданная ошибка при загрузке: %v", err)
@@ -166,7 +173,7 @@ func TestUploadErrorHandling(t *testing.T) {
 
 		ctx := context.Background()
 		reader := strings.NewReader("test content")
-		_, err := uploader.UploadFile(ctx, reader, "test-file.mp3")
+		_, err := uploader.UploadFile(ctx, reader, "test-file.mp3", "audio/mpeg")
 
 		if err == nil {
 			t.Error("Ожидалась ошибка при неверных учетных данных")
@@ -189,7 +196,7 @@ func TestUploadErrorHandling(t *testing.T) {
 
 		ctx := context.Background()
 		reader := strings.NewReader("test content")
-		_, err := uploader.UploadFile(ctx, reader, "test-file.mp3")
+		_, err := uploader.UploadFile(ctx, reader, "test-file.mp3", "audio/mpeg")
 
 		if err == nil {
 			t.Error("Ожидалась ошибка при сетевой проблеме")
@@ -212,7 +219,7 @@ func TestUploadErrorHandling(t *testing.T) {
 
 		ctx := context.Background()
 		reader := strings.NewReader("test content")
-		_, err := uploader.UploadFile(ctx, reader, "test-file.mp3")
+		_, err := uploader.UploadFile(ctx, reader, "test-file.mp3", "audio/mpeg")
 
 		if err == nil {
 			t.Error("Ожидалась ошибка при отсутствии доступа к bucket")
@@ -288,7 +295,7 @@ func TestS3ObjectKeyFormation(t *testing.T) {
 
 			ctx := context.Background()
 			reader := strings.NewReader("test content")
-			_, err := uploader.UploadFile(ctx, reader, tc.inputKey)
+			_, err := uploader.UploadFile(ctx, reader, tc.inputKey, "")
 
 			if err != nil {
 				t.Errorf("Ошибка при загрузке: %v", err)
@@ -427,7 +434,7 @@ func TestUploadFileWithContext(t *testing.T) {
 
 		ctx := context.Background()
 		reader := strings.NewReader("test content")
-		url, err := uploader.UploadFile(ctx, reader, "test-file.mp3")
+		url, err := uploader.UploadFile(ctx, reader, "test-file.mp3", "audio/mpeg")
 
 		if err != nil {
 			t.Errorf("Неожиданная ошибка при загрузке: %v", err)
@@ -7,22 +7,25 @@ import (
 
 // Manager управляет треками в приложении
 type Manager struct {
-	appData *data.AppData
+	store data.Store
 }
 
 // NewManager создает новый экземпляр Manager
-func NewManager(appData *data.AppData) *Manager {
+func NewManager(store data.Store) *Manager {
 	return &Manager{
-		appData: appData,
+		store: store,
 	}
 }
 
 // ListTracks возвращает список всех треков
-func (m *Manager) ListTracks() []data.TrackMetadata {
-	return m.appData.Tracks
+func (m *Manager) ListTracks() ([]data.TrackMetadata, error) {
+	return m.store.List()
 }
 
 // UpdateTrack обновляет существующий трек
 func (m *Manager) UpdateTrack(updatedTrack data.TrackMetadata) error {
-	return m.appData.UpdateTrack(updatedTrack)
+	if _, err := m.store.Get(updatedTrack.ID); err != nil {
+		return err
+	}
+	return m.store.Put(&updatedTrack)
 }
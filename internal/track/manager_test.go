@@ -26,7 +26,10 @@ func TestAddTrack(t *testing.T) {
 	appData.AddTrack(track)
 
 	// Проверяем, что трек был добавлен
-	tracks := manager.ListTracks()
+	tracks, err := manager.ListTracks()
+	if err != nil {
+		t.Fatalf("Ошибка получения списка треков: %v", err)
+	}
 	if len(tracks) != 1 {
 		t.Errorf("Ожидался 1 трек, получено %d", len(tracks))
 	}
@@ -63,8 +66,8 @@ func TestDeleteTrack(t *testing.T) {
 	appData.AddTrack(track2)
 
 	// Проверяем, что треки добавлены
-	if len(manager.ListTracks()) != 2 {
-		t.Errorf("Ожидалось 2 трека, получено %d", len(manager.ListTracks()))
+	if tracks, _ := manager.ListTracks(); len(tracks) != 2 {
+		t.Errorf("Ожидалось 2 трека, получено %d", len(tracks))
 	}
 
 	// Удаляем первый трек (ID = 1)
@@ -74,7 +77,10 @@ func TestDeleteTrack(t *testing.T) {
 	}
 
 	// Проверяем, что трек был удален
-	tracks := manager.ListTracks()
+	tracks, err := manager.ListTracks()
+	if err != nil {
+		t.Fatalf("Ошибка получения списка треков: %v", err)
+	}
 	if len(tracks) != 1 {
 		t.Errorf("Ожидался 1 трек после удаления, получено %d", len(tracks))
 	}
@@ -91,7 +97,10 @@ func TestGetAllTracks(t *testing.T) {
 	manager := NewManager(appData)
 
 	// Проверяем, что изначально список пуст
-	tracks := manager.ListTracks()
+	tracks, err := manager.ListTracks()
+	if err != nil {
+		t.Fatalf("Ошибка получения списка треков: %v", err)
+	}
 	if len(tracks) != 0 {
 		t.Errorf("Ожидался пустой список треков, получено %d", len(tracks))
 	}
@@ -115,7 +124,7 @@ func TestGetAllTracks(t *testing.T) {
 	appData.AddTrack(track3)
 
 	// Проверяем, что все треки получены
-	tracks = manager.ListTracks()
+	tracks, _ = manager.ListTracks()
 	if len(tracks) != 3 {
 		t.Errorf("Ожидалось 3 трека, получено %d", len(tracks))
 	}
@@ -2,7 +2,13 @@
 package app
 
 import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hazadus/go-snatcher/internal/cache"
 	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/player"
 	"github.com/hazadus/go-snatcher/internal/tui/editor"
@@ -10,6 +16,9 @@ import (
 	"github.com/hazadus/go-snatcher/internal/tui/tracklist"
 )
 
+// miniBarHeight - количество строк, которое занимает мини-плеер под списком треков
+const miniBarHeight = 2
+
 // ScreenType определяет тип текущего экрана
 type ScreenType int
 
@@ -25,38 +34,91 @@ const (
 
 // MainModel представляет главную модель TUI
 type MainModel struct {
-	appData        *data.AppData
+	appData        *data.AppData // Настройки плеера: громкость и позиции треков
+	store          data.Store    // Хранилище библиотеки треков
 	currentScreen  ScreenType
 	tracklistModel *tracklist.Model
 	playerModel    *tuiPlayer.Model
 	editorModel    *editor.Model
-	globalPlayer   *player.Player // Глобальный плеер для переиспользования
-	saveFunc       func() error   // Функция для сохранения данных
+	globalPlayer   *player.Player   // Глобальный плеер для переиспользования
+	saveFunc       func() error     // Функция для сохранения данных
+	retagFunc      editor.RetagFunc // Запись тегов в файл трека (nil, если недоступна)
+	windowSize     tea.WindowSizeMsg
+
+	// Позиции треков изменились и еще не сохранены. Плеер отмечает это и
+	// передает сигнал positionChanged из своих горутин, а данные
+	// сохраняются в горутине Bubble Tea.
+	unsavedPositions atomic.Bool
+	positionChanged  chan struct{}
 }
 
-// NewMainModel создает новую главную модель
-func NewMainModel(appData *data.AppData, saveFunc func() error) *MainModel {
+// NewMainModel создает новую главную модель. Треки берутся из store,
+// громкость и позиции воспроизведения хранятся в appData.
+func NewMainModel(appData *data.AppData, store data.Store, saveFunc func() error) *MainModel {
 	// Создаем модель списка треков
-	tracklistModel := tracklist.NewModel(appData)
+	tracklistModel := tracklist.NewModel(store)
 
-	// Создаем глобальный плеер один раз
-	globalPlayer := player.NewPlayer()
+	// Создаем глобальный плеер один раз с последней использованной громкостью
+	globalPlayer := player.NewPlayer(player.NewSpeakerOutput())
+	if appData.Volume != nil {
+		globalPlayer.SetVolume(*appData.Volume)
+	}
 
-	return &MainModel{
+	m := &MainModel{
 		appData:        appData,
+		store:          store,
 		currentScreen:  TracklistScreen,
 		tracklistModel: tracklistModel,
-		playerModel:    nil, // Будет создана при выборе трека
+		playerModel:    nil, // Будет создана при выборе трека и живет, пока идет воспроизведение
 		editorModel:    nil, // Будет создана при редактировании трека
 		globalPlayer:   globalPlayer,
 		saveFunc:       saveFunc,
+
+		positionChanged: make(chan struct{}, 1),
+	}
+
+	// Запоминаем позицию каждого трека, когда он перестает играть
+	globalPlayer.SetPositionHandler(m.rememberPosition)
+
+	return m
+}
+
+// SetCache подключает локальный кэш к плееру. В режиме offline список
+// треков показывает только треки из кэша.
+func (m *MainModel) SetCache(c *cache.Cache, offline bool) {
+	m.globalPlayer.SetCache(c, offline)
+	if offline {
+		m.tracklistModel.SetFilter("Треки в кэше (офлайн)", func(track data.TrackMetadata) bool {
+			return track.URL != "" && c.Has(track.URL)
+		})
 	}
 }
 
+// SetRetagFunc включает в редакторе запись тегов в файл трека
+func (m *MainModel) SetRetagFunc(retag editor.RetagFunc) {
+	m.retagFunc = retag
+}
+
+// SetCrossfade устанавливает длительность кроссфейда между треками очереди
+func (m *MainModel) SetCrossfade(duration time.Duration) {
+	m.globalPlayer.SetCrossfade(duration)
+}
+
 // Init инициализирует модель
 func (m *MainModel) Init() tea.Cmd {
-	// Инициализируем модель списка треков
-	return m.tracklistModel.Init()
+	// Инициализируем модель списка треков и ждем изменений позиций
+	return tea.Batch(m.tracklistModel.Init(), m.waitForPosition())
+}
+
+// positionChangedMsg сообщает, что позиция трека изменилась и ее нужно сохранить
+type positionChangedMsg struct{}
+
+// waitForPosition ждет сигнала об изменении позиции трека
+func (m *MainModel) waitForPosition() tea.Cmd {
+	return func() tea.Msg {
+		<-m.positionChanged
+		return positionChangedMsg{}
+	}
 }
 
 // Update обрабатывает сообщения
@@ -64,6 +126,12 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case positionChangedMsg:
+		if m.unsavedPositions.Swap(false) && m.saveFunc != nil {
+			_ = m.saveFunc() // Потеря позиции не критична, не прерываем воспроизведение
+		}
+		return m, m.waitForPosition()
+
 	case tea.KeyMsg:
 		// Глобальные горячие клавиши
 		switch msg.String() {
@@ -73,26 +141,71 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.globalPlayer.Stop()
 			}
 			return m, tea.Quit
+
+		case "p":
+			// Возвращаемся из списка к экрану играющего трека
+			if m.currentScreen == TracklistScreen && m.playerModel != nil && !m.tracklistModel.IsFiltering() {
+				m.currentScreen = PlayerScreen
+				return m, nil
+			}
 		}
 
 	case tracklist.TrackSelectedMsg:
 		// Переключаемся на экран плеера с выбранным треком
 		m.currentScreen = PlayerScreen
-		m.playerModel = tuiPlayer.NewModelWithPlayer(msg.Track, m.globalPlayer)
-		return m, m.playerModel.Init()
+		if m.playerModel != nil {
+			// Модель плеера уже слушает прогресс, переиспользуем ее
+			cmd = m.playerModel.PlayTrack(msg.Track)
+		} else {
+			m.playerModel = tuiPlayer.NewModelWithPlayer(msg.Track, m.globalPlayer)
+			cmd = tea.Batch(
+				m.playerModel.Init(),
+				m.resizePlayer(),
+			)
+		}
+		m.playerModel.OfferResume(m.resumePosition(msg.Track))
+		return m, cmd
+
+	case tracklist.TrackEnqueueMsg:
+		// Добавляем трек в очередь плеера, оставаясь в списке
+		queue := m.globalPlayer.Queue()
+		queue.Add(msg.Track)
+		return m, m.tracklistModel.ShowStatus(fmt.Sprintf(
+			"В очередь: %s - %s (треков в очереди: %d)", msg.Track.Artist, msg.Track.Title, queue.Len()))
 
 	case tracklist.TrackEditMsg:
 		// Переключаемся на экран редактирования с выбранным треком
 		m.currentScreen = EditorScreen
-		m.editorModel = editor.NewModel(m.appData, msg.Track, m.saveFunc)
+		m.editorModel = editor.NewModel(m.store, msg.Track, m.saveFunc)
+		m.editorModel.SetRetagFunc(m.retagFunc)
 		return m, m.editorModel.Init()
 
 	case tuiPlayer.GoBackMsg:
-		// Возвращаемся к списку треков
+		// Возвращаемся к списку треков; модель плеера остается жить,
+		// чтобы воспроизведение продолжалось и показывалось в мини-плеере
 		m.currentScreen = TracklistScreen
+		return m, nil
+
+	case tuiPlayer.PlaybackFinishedMsg:
+		// Очередь доиграна или воспроизведение остановлено
 		m.playerModel = nil
+		if m.currentScreen == PlayerScreen {
+			m.currentScreen = TracklistScreen
+		}
 		return m, nil
 
+	case tuiPlayer.TrackChangedMsg:
+		// Плеер перешел к другому треку очереди: предлагаем продолжить его
+		cmd = m.updatePlayer(msg)
+		if m.playerModel != nil {
+			m.playerModel.OfferResume(m.resumePosition(msg.Track))
+		}
+		return m, cmd
+
+	case tuiPlayer.ProgressMsg, tuiPlayer.PlaybackErrorMsg, tuiPlayer.ArtworkLoadedMsg, progress.FrameMsg:
+		// Сообщения плеера доставляем модели плеера на любом экране
+		return m, m.updatePlayer(msg)
+
 	case editor.GoBackMsg:
 		// Возвращаемся к списку треков из редактора
 		m.currentScreen = TracklistScreen
@@ -106,29 +219,24 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.WindowSizeMsg:
-		// Передаем размеры окна активной модели
-		switch m.currentScreen {
-		case TracklistScreen:
-			var tracklistCmd tea.Cmd
-			m.tracklistModel, tracklistCmd = m.tracklistModel.Update(msg)
-			return m, tracklistCmd
-		case PlayerScreen:
-			if m.playerModel != nil {
-				var playerCmd tea.Cmd
-				updatedModel, playerCmd := m.playerModel.Update(msg)
-				if playerModel, ok := updatedModel.(*tuiPlayer.Model); ok {
-					m.playerModel = playerModel
-				}
-				return m, playerCmd
-			}
-		case EditorScreen:
-			if m.editorModel != nil {
-				var editorCmd tea.Cmd
-				m.editorModel, editorCmd = m.editorModel.Update(msg)
-				return m, editorCmd
-			}
+		// Запоминаем размер окна и передаем его всем моделям, чтобы
+		// при переключении экранов они отображались корректно
+		m.windowSize = msg
+
+		var cmds []tea.Cmd
+		var tracklistCmd tea.Cmd
+		m.tracklistModel, tracklistCmd = m.tracklistModel.Update(tea.WindowSizeMsg{
+			Width:  msg.Width,
+			Height: msg.Height - miniBarHeight,
+		})
+		cmds = append(cmds, tracklistCmd, m.resizePlayer())
+
+		if m.editorModel != nil {
+			var editorCmd tea.Cmd
+			m.editorModel, editorCmd = m.editorModel.Update(msg)
+			cmds = append(cmds, editorCmd)
 		}
-		return m, nil
+		return m, tea.Batch(cmds...)
 	}
 
 	// Передаем сообщение активной модели
@@ -139,14 +247,7 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd = tracklistCmd
 
 	case PlayerScreen:
-		if m.playerModel != nil {
-			var playerCmd tea.Cmd
-			updatedModel, playerCmd := m.playerModel.Update(msg)
-			if playerModel, ok := updatedModel.(*tuiPlayer.Model); ok {
-				m.playerModel = playerModel
-			}
-			cmd = playerCmd
-		}
+		cmd = m.updatePlayer(msg)
 
 	case EditorScreen:
 		if m.editorModel != nil {
@@ -163,7 +264,11 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *MainModel) View() string {
 	switch m.currentScreen {
 	case TracklistScreen:
-		return m.tracklistModel.View()
+		view := m.tracklistModel.View()
+		if m.playerModel != nil {
+			view += "\n" + m.playerModel.MiniView()
+		}
+		return view
 
 	case PlayerScreen:
 		if m.playerModel != nil {
@@ -182,9 +287,59 @@ func (m *MainModel) View() string {
 	}
 }
 
-// Close закрывает ресурсы главной модели
+// updatePlayer передает сообщение модели плеера, если она существует
+func (m *MainModel) updatePlayer(msg tea.Msg) tea.Cmd {
+	if m.playerModel == nil {
+		return nil
+	}
+	updatedModel, cmd := m.playerModel.Update(msg)
+	if playerModel, ok := updatedModel.(*tuiPlayer.Model); ok {
+		m.playerModel = playerModel
+	}
+	return cmd
+}
+
+// resizePlayer передает модели плеера последний известный размер окна
+func (m *MainModel) resizePlayer() tea.Cmd {
+	if m.windowSize.Width == 0 {
+		return nil
+	}
+	return m.updatePlayer(m.windowSize)
+}
+
+// resumePosition возвращает сохраненную позицию трека для возобновления
+func (m *MainModel) resumePosition(track data.TrackMetadata) time.Duration {
+	return time.Duration(m.appData.Position(track.ID)) * time.Second
+}
+
+// rememberPosition запоминает позицию остановленного трека в памяти.
+// Вызывается плеером из его горутин под мьютексом плеера, поэтому данные
+// не сохраняются здесь, а только передается сигнал в Update.
+func (m *MainModel) rememberPosition(track data.TrackMetadata, position, total time.Duration) {
+	seconds := int(player.ResumePosition(position, total) / time.Second)
+	if !m.appData.SetPosition(track.ID, seconds) {
+		return
+	}
+	m.unsavedPositions.Store(true)
+	select {
+	case m.positionChanged <- struct{}{}:
+	default: // Сигнал уже ждет обработки
+	}
+}
+
+// Close закрывает ресурсы главной модели. Вызывается после завершения
+// программы Bubble Tea, поэтому сохраняет данные сам.
 func (m *MainModel) Close() {
-	if m.globalPlayer != nil {
-		m.globalPlayer.Close()
+	if m.globalPlayer == nil {
+		return
+	}
+
+	// Закрытие останавливает трек и запоминает его позицию
+	volume := m.globalPlayer.Volume()
+	m.globalPlayer.Close()
+
+	volumeChanged := m.appData.SetVolume(volume)
+	if (m.unsavedPositions.Swap(false) || volumeChanged) && m.saveFunc != nil {
+		_ = m.saveFunc() // Ошибка сохранения не критична при выходе
 	}
 }
@@ -0,0 +1,100 @@
+// Package artwork загружает обложки треков и рисует их в терминале
+// символами полублоков
+package artwork
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // Регистрируем декодер JPEG
+	_ "image/png"  // Регистрируем декодер PNG
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxSize ограничивает размер скачиваемой обложки
+const maxSize = 10 << 20
+
+// Fetch скачивает и декодирует обложку по URL
+func Fetch(ctx context.Context, url string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("User-Agent", "go-snatcher/1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("сервер вернул статус: %s", resp.Status)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования обложки: %w", err)
+	}
+	return img, nil
+}
+
+// Render рисует изображение шириной width символов. Каждый символ "▀"
+// передает два пикселя: верхний цветом текста, нижний цветом фона, поэтому
+// квадратная обложка занимает width/2 строк и выглядит квадратной.
+func Render(img image.Image, width int) string {
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Empty() {
+		return ""
+	}
+	height := width * bounds.Dy() / bounds.Dx()
+	height += height % 2 // Четное число пикселей по вертикали
+	if height == 0 {
+		height = 2
+	}
+
+	var b strings.Builder
+	for y := 0; y < height; y += 2 {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < width; x++ {
+			top := sample(img, x, y, width, height)
+			bottom := sample(img, x, y+1, width, height)
+			fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀",
+				top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+		}
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// sample возвращает средний цвет области исходного изображения, которая
+// соответствует пикселю (x, y) изображения размером width×height
+func sample(img image.Image, x, y, width, height int) color.RGBA {
+	bounds := img.Bounds()
+	x0 := bounds.Min.X + x*bounds.Dx()/width
+	x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+	y0 := bounds.Min.Y + y*bounds.Dy()/height
+	y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+
+	var r, g, bl, n uint64
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			cr, cg, cb, _ := img.At(px, py).RGBA()
+			r += uint64(cr)
+			g += uint64(cg)
+			bl += uint64(cb)
+			n++
+		}
+	}
+	return color.RGBA{
+		R: uint8(r / n >> 8),
+		G: uint8(g / n >> 8),
+		B: uint8(bl / n >> 8),
+		A: 0xff,
+	}
+}
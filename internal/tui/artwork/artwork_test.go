@@ -0,0 +1,85 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// twoColorImage создает квадратное изображение: верхняя половина красная,
+// нижняя синяя
+func twoColorImage(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if y >= size/2 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestRender(t *testing.T) {
+	rendered := Render(twoColorImage(64), 8)
+
+	lines := strings.Split(rendered, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Ожидалось 4 строки, получено %d", len(lines))
+	}
+	for i, line := range lines {
+		if count := strings.Count(line, "▀"); count != 8 {
+			t.Errorf("Строка %d: ожидалось 8 символов, получено %d", i, count)
+		}
+		if !strings.HasSuffix(line, "\x1b[0m") {
+			t.Errorf("Строка %d не сбрасывает цвета", i)
+		}
+	}
+
+	// Верхняя строка красная, нижняя синяя
+	if !strings.Contains(lines[0], "\x1b[38;2;255;0;0m\x1b[48;2;255;0;0m") {
+		t.Errorf("Верхняя строка должна быть красной: %q", lines[0])
+	}
+	if !strings.Contains(lines[3], "\x1b[38;2;0;0;255m\x1b[48;2;0;0;255m") {
+		t.Errorf("Нижняя строка должна быть синей: %q", lines[3])
+	}
+
+	if Render(twoColorImage(4), 0) != "" {
+		t.Error("Нулевая ширина должна давать пустую строку")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, twoColorImage(4)); err != nil {
+		t.Fatalf("Ошибка кодирования PNG: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cover.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	defer server.Close()
+
+	img, err := Fetch(context.Background(), server.URL+"/cover.png")
+	if err != nil {
+		t.Fatalf("Ошибка загрузки обложки: %v", err)
+	}
+	if img.Bounds().Dx() != 4 {
+		t.Errorf("Ожидалась ширина 4, получено %d", img.Bounds().Dx())
+	}
+
+	if _, err := Fetch(context.Background(), server.URL+"/missing.png"); err == nil {
+		t.Error("Ожидалась ошибка для отсутствующей обложки")
+	}
+}
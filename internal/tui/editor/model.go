@@ -2,6 +2,7 @@
 package editor
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -31,6 +32,18 @@ type TrackSavedMsg struct{}
 // GoBackMsg отправляется при отмене редактирования
 type GoBackMsg struct{}
 
+// RetagFunc записывает метаданные трека в теги его файла в хранилище
+type RetagFunc func(ctx context.Context, track data.TrackMetadata) error
+
+// retagDoneMsg отправляется по завершении записи тегов в файл
+type retagDoneMsg struct {
+	saved bool // Изменения прошли проверку и сохранены в библиотеке
+	err   error
+}
+
+// retagTimeout ограничивает запись тегов: файл скачивается и загружается целиком
+const retagTimeout = 10 * time.Minute
+
 // fieldType определяет тип поля для редактирования
 type fieldType int
 
@@ -38,6 +51,12 @@ const (
 	artistField fieldType = iota
 	titleField
 	albumField
+	yearField
+	genreField
+	trackNumberField
+	bpmField
+	keyField
+	commentField
 	lengthField
 	sourceURLField
 	numFields
@@ -52,12 +71,14 @@ type Model struct {
 	err           string
 	success       string
 	quitting      bool
+	retagging     bool         // Идет запись тегов в файл трека
 	saveFunc      func() error // Функция для сохранения данных в файл
+	retagFunc     RetagFunc    // Функция записи тегов в файл (nil, если недоступна)
 }
 
-// NewModel создает новую модель редактора трека
-func NewModel(appData *data.AppData, trackToEdit data.TrackMetadata, saveFunc func() error) *Model {
-	trackManager := track.NewManager(appData)
+// NewModel создает новую модель редактора трека из хранилища библиотеки
+func NewModel(store data.Store, trackToEdit data.TrackMetadata, saveFunc func() error) *Model {
+	trackManager := track.NewManager(store)
 
 	// Создаем поля ввода
 	inputs := make([]textinput.Model, numFields)
@@ -80,6 +101,31 @@ func NewModel(appData *data.AppData, trackToEdit data.TrackMetadata, saveFunc fu
 	inputs[albumField].Placeholder = "Введите название альбома"
 	inputs[albumField].SetValue(trackToEdit.Album)
 
+	// Необязательные теги: пустое числовое поле означает, что тег не указан
+	inputs[yearField] = textinput.New()
+	inputs[yearField].Placeholder = "Год выпуска"
+	inputs[yearField].SetValue(optionalInt(trackToEdit.Year))
+
+	inputs[genreField] = textinput.New()
+	inputs[genreField].Placeholder = "Жанр"
+	inputs[genreField].SetValue(trackToEdit.Genre)
+
+	inputs[trackNumberField] = textinput.New()
+	inputs[trackNumberField].Placeholder = "Номер трека в альбоме"
+	inputs[trackNumberField].SetValue(optionalInt(trackToEdit.TrackNumber))
+
+	inputs[bpmField] = textinput.New()
+	inputs[bpmField].Placeholder = "Темп в BPM"
+	inputs[bpmField].SetValue(optionalInt(trackToEdit.BPM))
+
+	inputs[keyField] = textinput.New()
+	inputs[keyField].Placeholder = "Тональность (например, Am или 8A)"
+	inputs[keyField].SetValue(trackToEdit.Key)
+
+	inputs[commentField] = textinput.New()
+	inputs[commentField].Placeholder = "Комментарий"
+	inputs[commentField].SetValue(trackToEdit.Comment)
+
 	// Поле Length (в секундах)
 	inputs[lengthField] = textinput.New()
 	inputs[lengthField].Placeholder = "Длительность в секундах"
@@ -99,6 +145,11 @@ func NewModel(appData *data.AppData, trackToEdit data.TrackMetadata, saveFunc fu
 	}
 }
 
+// SetRetagFunc включает запись тегов в файл трека по Ctrl+W
+func (m *Model) SetRetagFunc(retag RetagFunc) {
+	m.retagFunc = retag
+}
+
 // Init инициализирует модель
 func (m *Model) Init() tea.Cmd {
 	return textinput.Blink
@@ -107,8 +158,38 @@ func (m *Model) Init() tea.Cmd {
 // Update обрабатывает сообщения и обновляет модель
 func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case retagDoneMsg:
+		m.retagging = false
+		if !msg.saved {
+			// Ошибку проверки полей уже показывает m.err
+			m.success = ""
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = fmt.Sprintf("Метаданные сохранены, но теги не записаны в файл: %v", msg.err)
+			m.success = ""
+			return m, nil
+		}
+		m.err = ""
+		m.success = "Трек сохранен, теги записаны в файл!"
+		return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
+			return GoBackMsg{}
+		})
+
 	case tea.KeyMsg:
+		// Пока файл перезаписывается, редактор ждет завершения
+		if m.retagging {
+			return m, nil
+		}
+
 		switch msg.String() {
+		case "ctrl+w":
+			// Сохраняем изменения и записываем теги в файл
+			if m.retagFunc != nil {
+				return m, m.saveAndRetag()
+			}
+			return m, nil
+
 		case "ctrl+c", "esc":
 			// Отменяем редактирование
 			return m, func() tea.Msg {
@@ -180,60 +261,10 @@ func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 // saveTrack сохраняет изменения трека
 func (m *Model) saveTrack() tea.Cmd {
 	return func() tea.Msg {
-		// Валидируем и парсим поля
-		artist := strings.TrimSpace(m.inputs[artistField].Value())
-		title := strings.TrimSpace(m.inputs[titleField].Value())
-		album := strings.TrimSpace(m.inputs[albumField].Value())
-		lengthStr := strings.TrimSpace(m.inputs[lengthField].Value())
-		sourceURL := strings.TrimSpace(m.inputs[sourceURLField].Value())
-
-		// Проверяем обязательные поля
-		if artist == "" {
-			m.err = "Поле 'Исполнитель' не может быть пустым"
-			m.success = ""
-			return nil
-		}
-
-		if title == "" {
-			m.err = "Поле 'Название' не может быть пустым"
-			m.success = ""
-			return nil
-		}
-
-		// Парсим длительность
-		length, err := strconv.Atoi(lengthStr)
-		if err != nil || length < 0 {
-			m.err = "Длительность должна быть положительным числом"
-			m.success = ""
-			return nil
-		}
-
-		// Создаем обновленный трек
-		updatedTrack := m.originalTrack
-		updatedTrack.Artist = artist
-		updatedTrack.Title = title
-		updatedTrack.Album = album
-		updatedTrack.Length = length
-		updatedTrack.SourceURL = sourceURL
-
-		// Сохраняем изменения в памяти
-		err = m.trackManager.UpdateTrack(updatedTrack)
-		if err != nil {
-			m.err = fmt.Sprintf("Ошибка обновления трека: %v", err)
-			m.success = ""
+		if _, ok := m.applyChanges(); !ok {
 			return nil
 		}
 
-		// Сохраняем данные в файл
-		if m.saveFunc != nil {
-			err = m.saveFunc()
-			if err != nil {
-				m.err = fmt.Sprintf("Ошибка сохранения в файл: %v", err)
-				m.success = ""
-				return nil
-			}
-		}
-
 		m.err = ""
 		m.success = "Трек успешно сохранен!"
 
@@ -244,6 +275,111 @@ func (m *Model) saveTrack() tea.Cmd {
 	}
 }
 
+// saveAndRetag сохраняет изменения трека и записывает их в теги его файла
+func (m *Model) saveAndRetag() tea.Cmd {
+	m.retagging = true
+	m.err = ""
+	m.success = "Записываем теги в файл трека..."
+	retag := m.retagFunc
+
+	return func() tea.Msg {
+		updatedTrack, ok := m.applyChanges()
+		if !ok {
+			return retagDoneMsg{}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), retagTimeout)
+		defer cancel()
+		return retagDoneMsg{saved: true, err: retag(ctx, updatedTrack)}
+	}
+}
+
+// applyChanges проверяет поля, обновляет трек в библиотеке и сохраняет ее.
+// При ошибке заполняет m.err и возвращает false.
+func (m *Model) applyChanges() (data.TrackMetadata, bool) {
+	// Валидируем и парсим поля
+	artist := strings.TrimSpace(m.inputs[artistField].Value())
+	title := strings.TrimSpace(m.inputs[titleField].Value())
+	album := strings.TrimSpace(m.inputs[albumField].Value())
+	lengthStr := strings.TrimSpace(m.inputs[lengthField].Value())
+	sourceURL := strings.TrimSpace(m.inputs[sourceURLField].Value())
+
+	// Проверяем обязательные поля
+	if artist == "" {
+		m.err = "Поле 'Исполнитель' не может быть пустым"
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+
+	if title == "" {
+		m.err = "Поле 'Название' не может быть пустым"
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+
+	// Парсим длительность
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil || length < 0 {
+		m.err = "Длительность должна быть положительным числом"
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+
+	// Парсим необязательные числовые теги
+	year, ok := parseOptionalInt(m.inputs[yearField].Value())
+	if !ok {
+		m.err = "Год должен быть положительным числом"
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+	trackNumber, ok := parseOptionalInt(m.inputs[trackNumberField].Value())
+	if !ok {
+		m.err = "Номер трека должен быть положительным числом"
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+	bpm, ok := parseOptionalInt(m.inputs[bpmField].Value())
+	if !ok {
+		m.err = "Темп должен быть положительным числом"
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+
+	// Создаем обновленный трек
+	updatedTrack := m.originalTrack
+	updatedTrack.Artist = artist
+	updatedTrack.Title = title
+	updatedTrack.Album = album
+	updatedTrack.Year = year
+	updatedTrack.Genre = strings.TrimSpace(m.inputs[genreField].Value())
+	updatedTrack.TrackNumber = trackNumber
+	updatedTrack.BPM = bpm
+	updatedTrack.Key = strings.TrimSpace(m.inputs[keyField].Value())
+	updatedTrack.Comment = strings.TrimSpace(m.inputs[commentField].Value())
+	updatedTrack.Length = length
+	updatedTrack.SourceURL = sourceURL
+
+	// Сохраняем изменения в памяти
+	err = m.trackManager.UpdateTrack(updatedTrack)
+	if err != nil {
+		m.err = fmt.Sprintf("Ошибка обновления трека: %v", err)
+		m.success = ""
+		return data.TrackMetadata{}, false
+	}
+
+	// Сохраняем данные в файл
+	if m.saveFunc != nil {
+		err = m.saveFunc()
+		if err != nil {
+			m.err = fmt.Sprintf("Ошибка сохранения в файл: %v", err)
+			m.success = ""
+			return data.TrackMetadata{}, false
+		}
+	}
+
+	return updatedTrack, true
+}
+
 // View отображает модель
 func (m *Model) View() string {
 	if m.quitting {
@@ -257,7 +393,8 @@ func (m *Model) View() string {
 	b.WriteString("\n\n")
 
 	// Поля ввода
-	labels := []string{"Исполнитель:", "Название:", "Альбом:", "Длительность:", "URL источника:"}
+	labels := []string{"Исполнитель:", "Название:", "Альбом:", "Год:", "Жанр:", "Номер трека:",
+		"BPM:", "Тональность:", "Комментарий:", "Длительность:", "URL источника:"}
 
 	for i, input := range m.inputs {
 		b.WriteString(labelStyle.Render(labels[i]))
@@ -290,7 +427,33 @@ func (m *Model) View() string {
 	// Справка
 	b.WriteString(helpStyle.Render("Tab/Enter: следующее поле • Shift+Tab: предыдущее поле"))
 	b.WriteString("\n")
-	b.WriteString(footerStyle.Render("Ctrl+S: сохранить • Esc: отмена"))
+	footer := "Ctrl+S: сохранить • Esc: отмена"
+	if m.retagFunc != nil {
+		footer = "Ctrl+S: сохранить • Ctrl+W: сохранить и записать теги в файл • Esc: отмена"
+	}
+	b.WriteString(footerStyle.Render(footer))
 
 	return b.String()
 }
+
+// optionalInt форматирует необязательное число: ноль означает, что значение не указано
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// parseOptionalInt разбирает необязательное неотрицательное число;
+// пустая строка означает ноль
+func parseOptionalInt(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, true
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+	return number, true
+}
@@ -2,13 +2,17 @@
 package player
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/player"
+	"github.com/hazadus/go-snatcher/internal/player/streaming"
+	"github.com/hazadus/go-snatcher/internal/tui/artwork"
 	"github.com/hazadus/go-snatcher/internal/utils"
 )
 
@@ -34,6 +38,20 @@ var (
 	errorStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#ff0000")).
 			Bold(true)
+
+	miniBarStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#888888")).
+			PaddingLeft(2)
+
+	artworkStyle = lipgloss.NewStyle().
+			MarginRight(2)
+)
+
+const (
+	// artworkWidth - ширина обложки в символах
+	artworkWidth = 24
+	// artworkTimeout ограничивает время загрузки обложки
+	artworkTimeout = 10 * time.Second
 )
 
 // GoBackMsg отправляется для возврата к списку треков
@@ -44,6 +62,11 @@ type ProgressMsg struct {
 	Status player.Status
 }
 
+// TrackChangedMsg отправляется при переходе плеера к другому треку очереди
+type TrackChangedMsg struct {
+	Track data.TrackMetadata
+}
+
 // PlaybackFinishedMsg отправляется при завершении воспроизведения
 type PlaybackFinishedMsg struct{}
 
@@ -52,6 +75,12 @@ type PlaybackErrorMsg struct {
 	Error error
 }
 
+// ArtworkLoadedMsg содержит отрисованную обложку трека
+type ArtworkLoadedMsg struct {
+	URL  string // URL обложки, по которому сообщение сопоставляется с треком
+	View string
+}
+
 // Model представляет модель экрана воспроизведения
 type Model struct {
 	track       data.TrackMetadata
@@ -60,6 +89,9 @@ type Model struct {
 	status      player.Status
 	isPlaying   bool
 	error       error
+	notice      string        // Сообщение о неудачной операции (например, перемотке)
+	resumeTo    time.Duration // Сохраненная позиция, с которой предлагается продолжить трек
+	artwork     string        // Отрисованная обложка текущего трека
 	width       int
 	height      int
 }
@@ -72,7 +104,7 @@ func NewModel(track data.TrackMetadata) *Model {
 
 	return &Model{
 		track:       track,
-		player:      player.NewPlayer(),
+		player:      player.NewPlayer(player.NewSpeakerOutput()),
 		progressBar: prog,
 		isPlaying:   false,
 	}
@@ -98,6 +130,7 @@ func (m *Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.startPlayback(),
 		m.listenForProgress(),
+		m.loadArtwork(),
 	)
 }
 
@@ -114,17 +147,75 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "esc":
-			// Останавливаем плеер и возвращаемся к списку треков
-			m.player.Stop()
+			// Возвращаемся к списку треков, не прерывая воспроизведение
 			return m, func() tea.Msg {
 				return GoBackMsg{}
 			}
 
+		case "x":
+			// Останавливаем воспроизведение и возвращаемся к списку треков
+			m.player.Stop()
+			return m, func() tea.Msg {
+				return PlaybackFinishedMsg{}
+			}
+
 		case " ":
 			// Пауза/воспроизведение
 			m.player.Pause()
 			m.isPlaying = !m.isPlaying
 			return m, nil
+
+		case "left":
+			return m, m.skip(-player.SkipStep)
+
+		case "right":
+			return m, m.skip(player.SkipStep)
+
+		case "+", "=":
+			m.player.SetVolume(m.player.Volume() + player.VolumeStep)
+			return m, nil
+
+		case "-":
+			m.player.SetVolume(m.player.Volume() - player.VolumeStep)
+			return m, nil
+
+		case "m":
+			m.player.ToggleMute()
+			return m, nil
+
+		case "n":
+			return m, m.switchTrack(m.player.Next)
+
+		case "p":
+			return m, m.switchTrack(m.player.Previous)
+
+		case "s":
+			queue := m.player.Queue()
+			queue.SetShuffle(!queue.Shuffle(), time.Now().UnixNano())
+			return m, nil
+
+		case "r":
+			m.player.Queue().CycleRepeat()
+			return m, nil
+
+		case "f":
+			m.player.ToggleCrossfade()
+			return m, nil
+
+		case "[":
+			return m, m.skip(-player.LongSkipStep)
+
+		case "]":
+			return m, m.skip(player.LongSkipStep)
+
+		case "c":
+			return m, m.resume()
+
+		case "<":
+			return m, m.jumpChapter(m.player.PreviousChapter)
+
+		case ">":
+			return m, m.jumpChapter(m.player.NextChapter)
 		}
 
 	case ProgressMsg:
@@ -144,8 +235,28 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.listenForProgress(),
 		)
 
+	case TrackChangedMsg:
+		// Плеер перешел к другому треку очереди
+		m.track = msg.Track
+		m.status = player.Status{}
+		m.notice = ""
+		m.resumeTo = 0
+		m.artwork = ""
+		return m, tea.Batch(
+			m.progressBar.SetPercent(0),
+			m.listenForProgress(),
+			m.loadArtwork(),
+		)
+
+	case ArtworkLoadedMsg:
+		// Обложка могла загрузиться уже после перехода к другому треку
+		if msg.URL == m.track.ArtworkURL {
+			m.artwork = msg.View
+		}
+		return m, nil
+
 	case PlaybackFinishedMsg:
-		// Воспроизведение завершено, возвращаемся к списку
+		// Воспроизведение очереди завершено, возвращаемся к списку
 		m.isPlaying = false
 		return m, func() tea.Msg {
 			return GoBackMsg{}
@@ -182,12 +293,22 @@ func (m *Model) View() string {
 	title := titleStyle.Render("🎵 Воспроизведение")
 
 	// Информация о треке
-	trackInfo := trackInfoStyle.Render(fmt.Sprintf(
+	info := fmt.Sprintf(
 		"🎤 %s\n🎵 %s\n💿 %s",
 		m.track.Artist,
 		m.track.Title,
 		m.track.Album,
-	))
+	)
+	if tags := m.track.TagSummary(); tags != "" {
+		info += "\n🏷️  " + tags
+	}
+	if m.track.Comment != "" {
+		info += "\n💬 " + m.track.Comment
+	}
+	trackInfo := trackInfoStyle.Render(info)
+	if m.artwork != "" {
+		trackInfo = lipgloss.JoinHorizontal(lipgloss.Top, artworkStyle.Render(m.artwork), trackInfo)
+	}
 
 	// Статус воспроизведения
 	var statusIcon string
@@ -197,7 +318,11 @@ func (m *Model) View() string {
 		statusIcon = "⏸️"
 	}
 
-	statusText := statusStyle.Render(fmt.Sprintf("%s %s", statusIcon, formatStatus(m.isPlaying)))
+	statusLine := fmt.Sprintf("%s %s", statusIcon, formatStatus(m.isPlaying))
+	if m.status.Reconnecting() {
+		statusLine = "🔄 " + streaming.GetReconnectStatus(m.status.ReconnectAttempt)
+	}
+	statusText := statusStyle.Render(statusLine)
 
 	// Прогресс-бар
 	progressView := m.progressBar.View()
@@ -209,9 +334,39 @@ func (m *Model) View() string {
 		utils.FormatDuration(m.status.Total),
 	)
 
+	// Громкость берем напрямую из плеера, чтобы изменения были видны сразу
+	timeText += "\n" + formatVolume(m.player.Volume(), m.player.IsMuted())
+
+	// Состояние очереди
+	queue := m.player.Queue()
+	if queue.Len() > 0 {
+		shuffle := "выкл"
+		if queue.Shuffle() {
+			shuffle = "вкл"
+		}
+		timeText += fmt.Sprintf("\n📜 Очередь: %d/%d • 🔁 Повтор: %s • 🔀 Перемешивание: %s • 🎚️ Кроссфейд: %s",
+			queue.Position(), queue.Len(), queue.Repeat(), shuffle, formatCrossfade(m.player.Crossfade()))
+	} else if queue.Repeat() != player.RepeatOff {
+		timeText += fmt.Sprintf("\n🔁 Повтор: %s", queue.Repeat())
+	}
+
+	if chapter := m.chapterView(); chapter != "" {
+		timeText += "\n" + chapter
+	}
+
+	if m.resumeTo > 0 {
+		timeText += fmt.Sprintf("\n⏯️  Продолжить с %s? c: продолжить", utils.FormatDuration(m.resumeTo))
+	}
+
+	if m.notice != "" {
+		timeText += "\n" + errorStyle.Render(m.notice)
+	}
+
 	// Элементы управления
 	controls := controlsStyle.Render(
-		"Пробел: пауза/воспроизведение • q/esc: назад к списку",
+		"Пробел: пауза/воспроизведение • ←/→: ±10 с • [/]: ±1 мин • +/-: громкость • m: без звука\n" +
+			"n/p: следующий/предыдущий • </>: предыдущая/следующая глава • s: перемешивание • r: повтор • f: кроссфейд\n" +
+			"c: продолжить с сохраненной позиции • x: стоп • q/esc: к списку (музыка продолжит играть)",
 	)
 
 	return fmt.Sprintf(
@@ -225,6 +380,62 @@ func (m *Model) View() string {
 	)
 }
 
+// PlayTrack запускает другой трек в уже работающей модели. Прослушивание
+// прогресса продолжается прежней командой, поэтому второе не запускается.
+func (m *Model) PlayTrack(track data.TrackMetadata) tea.Cmd {
+	m.track = track
+	m.status = player.Status{}
+	m.error = nil
+	m.notice = ""
+	m.resumeTo = 0
+	m.artwork = ""
+	return tea.Batch(
+		m.progressBar.SetPercent(0),
+		m.startPlayback(),
+		m.loadArtwork(),
+	)
+}
+
+// OfferResume предлагает продолжить текущий трек с сохраненной позиции.
+// Нулевая позиция убирает предложение.
+func (m *Model) OfferResume(position time.Duration) {
+	m.resumeTo = position
+}
+
+// MiniView отображает компактную строку "сейчас играет" для других экранов
+func (m *Model) MiniView() string {
+	if m.error != nil {
+		return miniBarStyle.Render(errorStyle.Render("❌ " + m.error.Error()))
+	}
+
+	statusIcon := "⏸️"
+	if m.status.Reconnecting() {
+		statusIcon = "🔄"
+	} else if m.isPlaying {
+		statusIcon = "▶️"
+	}
+
+	var percent float64
+	if m.status.Total > 0 {
+		percent = float64(m.status.Current) / float64(m.status.Total)
+	}
+
+	// Копия прогресс-бара с меньшей шириной, чтобы не менять основной
+	bar := m.progressBar
+	bar.Width = 20
+
+	return miniBarStyle.Render(fmt.Sprintf(
+		"%s %s - %s  %s %s / %s  %s  • p: плеер",
+		statusIcon,
+		utils.TruncateString(m.track.Artist, 20),
+		utils.TruncateString(m.track.Title, 40),
+		bar.ViewAs(percent),
+		utils.FormatDuration(m.status.Current),
+		utils.FormatDuration(m.status.Total),
+		formatVolume(m.player.Volume(), m.player.IsMuted()),
+	))
+}
+
 // Close очищает ресурсы модели
 func (m *Model) Close() error {
 	if m.player != nil {
@@ -245,6 +456,100 @@ func (m *Model) startPlayback() tea.Cmd {
 	}
 }
 
+// skip перематывает трек на delta и сразу обновляет отображаемую позицию
+func (m *Model) skip(delta time.Duration) tea.Cmd {
+	if err := m.player.Skip(delta); err != nil {
+		m.notice = err.Error()
+		return nil
+	}
+	m.notice = ""
+	return m.showPosition(m.status.Current + delta)
+}
+
+// resume перематывает трек на сохраненную позицию из предложения
+func (m *Model) resume() tea.Cmd {
+	if m.resumeTo == 0 {
+		return nil
+	}
+	position := m.resumeTo
+	m.resumeTo = 0
+
+	if err := m.player.Seek(position); err != nil {
+		m.notice = err.Error()
+		return nil
+	}
+	m.notice = ""
+	return m.showPosition(position)
+}
+
+// showPosition сразу показывает новую позицию после перемотки, не дожидаясь
+// следующего обновления от плеера
+func (m *Model) showPosition(position time.Duration) tea.Cmd {
+	if position < 0 {
+		position = 0
+	}
+	if m.status.Total > 0 && position > m.status.Total {
+		position = m.status.Total
+	}
+	m.status.Current = position
+
+	if m.status.Total > 0 {
+		return m.progressBar.SetPercent(float64(position) / float64(m.status.Total))
+	}
+	return nil
+}
+
+// jumpChapter переходит к соседней главе микса с помощью переданной функции
+// и сразу обновляет отображаемую позицию
+func (m *Model) jumpChapter(jump func() (data.Chapter, error)) tea.Cmd {
+	chapter, err := jump()
+	if err != nil {
+		m.notice = err.Error()
+		return nil
+	}
+	m.notice = ""
+	return m.showPosition(time.Duration(chapter.Start) * time.Second)
+}
+
+// chapterView возвращает строку с текущей главой микса или пустую строку,
+// если у трека нет глав
+func (m *Model) chapterView() string {
+	index := m.track.ChapterAt(m.status.Current)
+	if index < 0 {
+		return ""
+	}
+	return fmt.Sprintf("📑 Глава %d/%d: %s", index+1, len(m.track.Chapters), m.track.Chapters[index])
+}
+
+// switchTrack переходит к другому треку очереди с помощью переданной функции
+func (m *Model) switchTrack(move func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := move(); err != nil {
+			m.notice = err.Error()
+		}
+		return nil
+	}
+}
+
+// loadArtwork загружает и отрисовывает обложку текущего трека. Если
+// обложки нет или ее не удалось загрузить, экран остается без нее.
+func (m *Model) loadArtwork() tea.Cmd {
+	url := m.track.ArtworkURL
+	if url == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), artworkTimeout)
+		defer cancel()
+
+		img, err := artwork.Fetch(ctx, url)
+		if err != nil {
+			return nil
+		}
+		return ArtworkLoadedMsg{URL: url, View: artwork.Render(img, artworkWidth)}
+	}
+}
+
 // listenForProgress слушает обновления прогресса от плеера
 func (m *Model) listenForProgress() tea.Cmd {
 	return func() tea.Msg {
@@ -255,6 +560,12 @@ func (m *Model) listenForProgress() tea.Cmd {
 			}
 			return ProgressMsg{Status: status}
 
+		case track, ok := <-m.player.TrackChanged():
+			if !ok {
+				return PlaybackFinishedMsg{}
+			}
+			return TrackChangedMsg{Track: track}
+
 		case _, ok := <-m.player.Done():
 			if !ok {
 				return PlaybackFinishedMsg{}
@@ -273,6 +584,20 @@ func formatStatus(isPlaying bool) string {
 	return "Пауза"
 }
 
+func formatVolume(level int, muted bool) string {
+	if muted {
+		return fmt.Sprintf("🔇 %d%% (звук выключен)", level)
+	}
+	return fmt.Sprintf("🔊 %d%%", level)
+}
+
+func formatCrossfade(duration time.Duration) string {
+	if duration <= 0 {
+		return "выкл"
+	}
+	return fmt.Sprintf("%d с", int(duration/time.Second))
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -1,11 +1,13 @@
 package player
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/hazadus/go-snatcher/internal/data"
+	"github.com/hazadus/go-snatcher/internal/player"
 	"github.com/hazadus/go-snatcher/internal/utils"
 )
 
@@ -114,3 +116,156 @@ func TestKeyHandling(t *testing.T) {
 		t.Error("Expected command to be returned for 'q' key")
 	}
 }
+
+func TestSkipKeysWithoutPlayback(t *testing.T) {
+	track := data.TrackMetadata{
+		ID:     1,
+		Artist: "Test Artist",
+		Title:  "Test Title",
+	}
+
+	model := NewModel(track)
+
+	// Без активного воспроизведения перемотка показывает сообщение, но не меняет позицию
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRight},
+		{Type: tea.KeyLeft},
+		{Type: tea.KeyRunes, Runes: []rune{']'}},
+		{Type: tea.KeyRunes, Runes: []rune{'['}},
+	}
+
+	for _, key := range keys {
+		updatedModel, _ := model.Update(key)
+		playerModel := updatedModel.(*Model)
+
+		if playerModel.notice == "" {
+			t.Errorf("Expected notice after %q without playback", key.String())
+		}
+
+		if playerModel.status.Current != 0 {
+			t.Errorf("Expected position to stay 0 after %q, got %v", key.String(), playerModel.status.Current)
+		}
+	}
+}
+
+func TestOfferResume(t *testing.T) {
+	track := data.TrackMetadata{
+		ID:     1,
+		Artist: "Test Artist",
+		Title:  "Test Title",
+	}
+
+	model := NewModel(track)
+	model.OfferResume(47*time.Minute + 12*time.Second)
+
+	if view := model.View(); !strings.Contains(view, "Продолжить с 00:47:12?") {
+		t.Errorf("Expected resume offer in view, got:\n%s", view)
+	}
+
+	// Без активного воспроизведения перемотка не удается, но предложение снимается
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	playerModel := updatedModel.(*Model)
+
+	if playerModel.resumeTo != 0 {
+		t.Error("Expected resume offer to be cleared after pressing c")
+	}
+	if playerModel.notice == "" {
+		t.Error("Expected notice when resuming without playback")
+	}
+}
+
+func TestCrossfadeToggle(t *testing.T) {
+	model := NewModel(data.TrackMetadata{ID: 1, Artist: "Test Artist", Title: "Test Title"})
+	defer model.Close()
+	model.player.SetCrossfade(8 * time.Second)
+	model.player.Queue().Add(model.track)
+
+	if !strings.Contains(model.View(), "Кроссфейд: 8 с") {
+		t.Error("Expected crossfade duration in the player view")
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if model.player.Crossfade() != 0 || !strings.Contains(model.View(), "Кроссфейд: выкл") {
+		t.Error("Expected 'f' to turn crossfade off")
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if model.player.Crossfade() != 8*time.Second {
+		t.Errorf("Expected 'f' to restore crossfade of 8s, got %v", model.player.Crossfade())
+	}
+}
+
+func TestViewShowsTags(t *testing.T) {
+	model := NewModel(data.TrackMetadata{
+		ID:      1,
+		Artist:  "Test Artist",
+		Title:   "Test Title",
+		Year:    2021,
+		Genre:   "Techno",
+		BPM:     132,
+		Key:     "Am",
+		Comment: "Live at the club",
+	})
+	defer model.Close()
+
+	view := model.View()
+	for _, expected := range []string{"2021 • Techno • 132 BPM • Am", "Live at the club"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected view to contain %q, got:\n%s", expected, view)
+		}
+	}
+}
+
+func TestArtworkLoaded(t *testing.T) {
+	model := NewModel(data.TrackMetadata{
+		ID:         1,
+		Title:      "Test Title",
+		ArtworkURL: "https://example.com/test.cover.jpg",
+	})
+	defer model.Close()
+
+	// Artwork of a track that is no longer playing is ignored
+	model.Update(ArtworkLoadedMsg{URL: "https://example.com/other.cover.jpg", View: "OLD"})
+	if strings.Contains(model.View(), "OLD") {
+		t.Error("Expected stale artwork to be ignored")
+	}
+
+	model.Update(ArtworkLoadedMsg{URL: "https://example.com/test.cover.jpg", View: "COVER"})
+	if !strings.Contains(model.View(), "COVER") {
+		t.Errorf("Expected view to contain artwork, got:\n%s", model.View())
+	}
+
+	// Switching tracks drops the previous artwork
+	model.Update(TrackChangedMsg{Track: data.TrackMetadata{ID: 2, Title: "Next"}})
+	if strings.Contains(model.View(), "COVER") {
+		t.Error("Expected artwork to be cleared on track change")
+	}
+
+	// Tracks without artwork do not fetch anything
+	if cmd := model.loadArtwork(); cmd != nil {
+		t.Error("Expected no artwork command for a track without artwork")
+	}
+}
+
+func TestViewShowsCurrentChapter(t *testing.T) {
+	model := NewModel(data.TrackMetadata{
+		ID:    1,
+		Title: "Mix",
+		Chapters: []data.Chapter{
+			{Start: 0, Artist: "First Artist", Title: "Opening"},
+			{Start: 300, Artist: "Second Artist", Title: "Peak"},
+		},
+	})
+	defer model.Close()
+
+	model.Update(ProgressMsg{Status: player.Status{Current: 6 * time.Minute, Total: time.Hour}})
+	if view := model.View(); !strings.Contains(view, "Глава 2/2: Second Artist - Peak") {
+		t.Errorf("Expected current chapter in the player view, got:\n%s", view)
+	}
+
+	// Without playback the chapter keys report an error instead of seeking
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'>'}})
+	if model.notice == "" {
+		t.Error("Expected a notice when jumping to a chapter without playback")
+	}
+}
@@ -21,6 +21,7 @@ var (
 	paginationStyle   = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
 	helpStyle         = list.DefaultStyles().HelpStyle.PaddingLeft(4).PaddingBottom(1)
 	quitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 2, 4)
+	errorStyle        = lipgloss.NewStyle().PaddingLeft(4).Foreground(lipgloss.Color("196"))
 )
 
 // TrackSelectedMsg отправляется при выборе трека для воспроизведения
@@ -33,13 +34,19 @@ type TrackEditMsg struct {
 	Track data.TrackMetadata
 }
 
+// TrackEnqueueMsg отправляется при добавлении трека в очередь воспроизведения
+type TrackEnqueueMsg struct {
+	Track data.TrackMetadata
+}
+
 // trackItem реализует интерфейс list.Item для трека
 type trackItem struct {
 	track data.TrackMetadata
 }
 
 func (i trackItem) FilterValue() string {
-	return fmt.Sprintf("%s %s", i.track.Artist, i.track.Title)
+	// По ID и UID трек можно найти, как и по исполнителю и названию
+	return fmt.Sprintf("%s %s #%d %s", i.track.Artist, i.track.Title, i.track.ID, i.track.UID)
 }
 
 // trackItemDelegate реализует отображение элементов списка
@@ -76,22 +83,19 @@ func (d trackItemDelegate) Render(w io.Writer, m list.Model, index int, listItem
 type Model struct {
 	list         list.Model
 	trackManager *track.Manager
+	filter       func(data.TrackMetadata) bool // Отбор треков для показа (nil - все треки)
+	loadErr      error                         // Ошибка чтения треков из хранилища
 	quitting     bool
 }
 
-// NewModel создает новую модель списка треков
-func NewModel(appData *data.AppData) *Model {
-	trackManager := track.NewManager(appData)
-	tracks := trackManager.ListTracks()
-
-	// Преобразуем треки в элементы списка
-	items := make([]list.Item, len(tracks))
-	for i, t := range tracks {
-		items[i] = trackItem{track: t}
+// NewModel создает новую модель списка треков из хранилища библиотеки
+func NewModel(store data.Store) *Model {
+	m := &Model{
+		trackManager: track.NewManager(store),
 	}
 
 	// Создаем список
-	l := list.New(items, trackItemDelegate{}, 0, 0)
+	l := list.New(m.items(), trackItemDelegate{}, 0, 0)
 	l.Title = "Треки"
 	l.SetShowStatusBar(false)
 	l.SetShowTitle(true) // Убеждаемся, что заголовок отображается
@@ -100,10 +104,30 @@ func NewModel(appData *data.AppData) *Model {
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
 
-	return &Model{
-		list:         l,
-		trackManager: trackManager,
+	m.list = l
+	return m
+}
+
+// items преобразует треки в элементы списка с учетом отбора
+func (m *Model) items() []list.Item {
+	tracks, err := m.trackManager.ListTracks()
+	m.loadErr = err
+
+	items := make([]list.Item, 0, len(tracks))
+	for _, t := range tracks {
+		if m.filter == nil || m.filter(t) {
+			items = append(items, trackItem{track: t})
+		}
 	}
+	return items
+}
+
+// SetFilter ограничивает список треками, для которых filter возвращает true,
+// и меняет заголовок списка
+func (m *Model) SetFilter(title string, filter func(data.TrackMetadata) bool) {
+	m.filter = filter
+	m.list.Title = title
+	m.RefreshData()
 }
 
 // Init инициализирует модель
@@ -113,17 +137,8 @@ func (m *Model) Init() tea.Cmd {
 
 // RefreshData обновляет данные модели без пересоздания
 func (m *Model) RefreshData() {
-	// Получаем актуальные треки
-	tracks := m.trackManager.ListTracks()
-
-	// Преобразуем треки в элементы списка
-	items := make([]list.Item, len(tracks))
-	for i, t := range tracks {
-		items[i] = trackItem{track: t}
-	}
-
-	// Обновляем элементы в существующем списке
-	m.list.SetItems(items)
+	// Обновляем элементы в существующем списке актуальными треками
+	m.list.SetItems(m.items())
 }
 
 // Update обрабатывает сообщения и обновляет модель
@@ -152,6 +167,14 @@ func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 				}
 			}
 
+		case "a":
+			// Добавление выбранного трека в очередь
+			if item, ok := m.list.SelectedItem().(trackItem); ok {
+				return m, func() tea.Msg {
+					return TrackEnqueueMsg{Track: item.track}
+				}
+			}
+
 		case "e":
 			// Редактирование выбранного трека
 			selectedItem := m.list.SelectedItem()
@@ -172,6 +195,16 @@ func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
 	return m, cmd
 }
 
+// IsFiltering возвращает true, если пользователь вводит строку поиска
+func (m *Model) IsFiltering() bool {
+	return m.list.FilterState() == list.Filtering
+}
+
+// ShowStatus показывает короткое сообщение в строке заголовка списка
+func (m *Model) ShowStatus(message string) tea.Cmd {
+	return m.list.NewStatusMessage(message)
+}
+
 // View отображает модель
 func (m *Model) View() string {
 	if m.quitting {
@@ -179,7 +212,10 @@ func (m *Model) View() string {
 	}
 
 	view := m.list.View()
+	if m.loadErr != nil {
+		view += "\n" + errorStyle.Render(fmt.Sprintf("Ошибка чтения библиотеки: %v", m.loadErr))
+	}
 	// Добавляем дополнительную справку
-	extraHelp := helpStyle.Render("Enter: воспроизвести • e: редактировать • q: выход")
+	extraHelp := helpStyle.Render("Enter: воспроизвести • a: в очередь • e: редактировать • q: выход")
 	return view + "\n" + extraHelp
 }
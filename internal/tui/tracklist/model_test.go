@@ -3,6 +3,7 @@ package tracklist
 import (
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/hazadus/go-snatcher/internal/data"
 )
 
@@ -48,3 +49,27 @@ func TestNewModel(t *testing.T) {
 		t.Fatalf("Expected 2 items, got %d", len(model.list.Items()))
 	}
 }
+
+func TestEnqueueKey(t *testing.T) {
+	appData := &data.AppData{
+		Tracks: []data.TrackMetadata{
+			{ID: 7, Artist: "Test Artist", Title: "Test Track"},
+		},
+	}
+
+	model := NewModel(appData)
+
+	// Нажатие 'a' добавляет выбранный трек в очередь
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	if cmd == nil {
+		t.Fatal("Expected command for 'a' key")
+	}
+
+	msg, ok := cmd().(TrackEnqueueMsg)
+	if !ok {
+		t.Fatalf("Expected TrackEnqueueMsg, got %T", cmd())
+	}
+	if msg.Track.ID != 7 {
+		t.Errorf("Expected track ID 7, got %d", msg.Track.ID)
+	}
+}
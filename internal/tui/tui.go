@@ -2,29 +2,62 @@
 package tui
 
 import (
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hazadus/go-snatcher/internal/cache"
 	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/tui/app"
+	"github.com/hazadus/go-snatcher/internal/tui/editor"
 )
 
 // App представляет основное TUI приложение
 type App struct {
 	appData  *data.AppData
-	saveFunc func() error // Функция для сохранения данных
+	store    data.Store       // Хранилище библиотеки треков
+	saveFunc func() error     // Функция для сохранения данных
+	cache    *cache.Cache     // Локальный кэш треков (nil, если не используется)
+	offline  bool             // Показывать и воспроизводить только треки из кэша
+	retag    editor.RetagFunc // Запись тегов в файл трека из редактора
+
+	crossfade time.Duration // Длительность кроссфейда между треками
 }
 
 // NewApp создает новый экземпляр TUI приложения
-func NewApp(appData *data.AppData, saveFunc func() error) *App {
+func NewApp(appData *data.AppData, store data.Store, saveFunc func() error) *App {
 	return &App{
 		appData:  appData,
+		store:    store,
 		saveFunc: saveFunc,
 	}
 }
 
+// SetCache подключает локальный кэш треков. В режиме offline доступны
+// только треки из кэша.
+func (tuiApp *App) SetCache(c *cache.Cache, offline bool) {
+	tuiApp.cache = c
+	tuiApp.offline = offline
+}
+
+// SetRetagFunc включает в редакторе запись тегов в файл трека
+func (tuiApp *App) SetRetagFunc(retag editor.RetagFunc) {
+	tuiApp.retag = retag
+}
+
+// SetCrossfade устанавливает длительность кроссфейда между треками очереди
+func (tuiApp *App) SetCrossfade(duration time.Duration) {
+	tuiApp.crossfade = duration
+}
+
 // Run запускает TUI приложение
 func (tuiApp *App) Run() error {
 	// Создаем модель для Bubble Tea
-	model := app.NewMainModel(tuiApp.appData, tuiApp.saveFunc)
+	model := app.NewMainModel(tuiApp.appData, tuiApp.store, tuiApp.saveFunc)
+	if tuiApp.cache != nil {
+		model.SetCache(tuiApp.cache, tuiApp.offline)
+	}
+	model.SetCrossfade(tuiApp.crossfade)
+	model.SetRetagFunc(tuiApp.retag)
 
 	// Создаем программу Bubble Tea
 	p := tea.NewProgram(model, tea.WithAltScreen())
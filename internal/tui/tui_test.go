@@ -2,6 +2,7 @@
 package tui
 
 import (
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -30,7 +31,7 @@ func TestMainModelRouting(t *testing.T) {
 	}
 
 	// Создаем главную модель
-	model := app.NewMainModel(testData, saveFunc)
+	model := app.NewMainModel(testData, testData, saveFunc)
 
 	// Проверяем начальное состояние
 	// Поскольку поля модели теперь приватные, проверяем через поведение
@@ -91,7 +92,7 @@ func TestMainModelView(t *testing.T) {
 		return nil
 	}
 
-	model := app.NewMainModel(testData, saveFunc)
+	model := app.NewMainModel(testData, testData, saveFunc)
 
 	// Тестируем отображение списка треков
 	view := model.View()
@@ -117,3 +118,46 @@ func TestMainModelView(t *testing.T) {
 		t.Error("Expected different view for player screen compared to tracklist")
 	}
 }
+
+func TestMainModelMiniPlayer(t *testing.T) {
+	testData := &data.AppData{
+		Tracks: []data.TrackMetadata{
+			{
+				ID:     1,
+				Title:  "Test Track",
+				Artist: "Test Artist",
+			},
+		},
+	}
+
+	model := app.NewMainModel(testData, testData, func() error { return nil })
+
+	if strings.Contains(model.View(), "p: плеер") {
+		t.Error("Mini player should not be shown before playback starts")
+	}
+
+	updatedModel, _ := model.Update(tracklist.TrackSelectedMsg{Track: testData.Tracks[0]})
+	model = updatedModel.(*app.MainModel)
+	playerView := model.View()
+
+	// После возврата к списку воспроизведение продолжается в мини-плеере
+	updatedModel, _ = model.Update(player.GoBackMsg{})
+	model = updatedModel.(*app.MainModel)
+	if view := model.View(); !strings.Contains(view, "p: плеер") {
+		t.Errorf("Expected mini player under tracklist, got:\n%s", view)
+	}
+
+	// Клавиша p возвращает к экрану плеера
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	model = updatedModel.(*app.MainModel)
+	if view := model.View(); view != playerView {
+		t.Error("Expected player screen after pressing p")
+	}
+
+	// После завершения воспроизведения мини-плеер исчезает
+	updatedModel, _ = model.Update(player.PlaybackFinishedMsg{})
+	model = updatedModel.(*app.MainModel)
+	if strings.Contains(model.View(), "p: плеер") {
+		t.Error("Mini player should be hidden after playback finished")
+	}
+}
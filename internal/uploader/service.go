@@ -2,44 +2,76 @@
 package uploader
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/hazadus/go-snatcher/internal/audio"
 	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/metadata"
 	"github.com/hazadus/go-snatcher/internal/s3"
 )
 
+// S3Uploader загружает файлы в хранилище с указанным MIME-типом
+// (реализуется s3.Uploader)
+type S3Uploader interface {
+	UploadFile(ctx context.Context, reader io.Reader, key, contentType string) (string, error)
+}
+
+// MetadataExtractor извлекает метаданные из аудиофайлов (реализуется
+// metadata.Extractor)
+type MetadataExtractor interface {
+	ExtractFromFile(filePath string) metadata.TrackMetadata
+	GetFileInfo(filePath string) (*metadata.FileInfo, error)
+}
+
+var (
+	_ S3Uploader        = (*s3.Uploader)(nil)
+	_ MetadataExtractor = (*metadata.Extractor)(nil)
+)
+
 // Service управляет процессом загрузки файлов
 type Service struct {
-	s3Uploader        *s3.Uploader
-	metadataExtractor *metadata.Extractor
-	appData           *data.AppData
+	s3Uploader        S3Uploader
+	metadataExtractor MetadataExtractor
+	store             data.Store
 }
 
-// NewService создает новый сервис загрузки
-func NewService(s3Uploader *s3.Uploader, appData *data.AppData) *Service {
+// NewService создает новый сервис загрузки, который извлекает метаданные
+// файлов через metadataExtractor, загружает их через s3Uploader и добавляет
+// треки в хранилище библиотеки store
+func NewService(s3Uploader S3Uploader, metadataExtractor MetadataExtractor, store data.Store) *Service {
 	return &Service{
 		s3Uploader:        s3Uploader,
-		metadataExtractor: metadata.NewExtractor(),
-		appData:           appData,
+		metadataExtractor: metadataExtractor,
+		store:             store,
 	}
 }
 
 // UploadResult содержит результат загрузки
 type UploadResult struct {
-	URL      string
-	Metadata metadata.TrackMetadata
-	FileInfo *metadata.FileInfo
+	URL        string
+	ArtworkURL string // URL обложки (пустой, если обложки нет или ее не удалось загрузить)
+	// Ошибка загрузки обложки. Она не прерывает загрузку: трек добавляется
+	// без обложки.
+	ArtworkErr error
+	SHA256     string // SHA-256 загруженного файла (hex)
+	Metadata   metadata.TrackMetadata
+	FileInfo   *metadata.FileInfo
 }
 
-// UploadFile загружает файл с метаданными
-func (s *Service) UploadFile(ctx context.Context, filePath string, progressCallback func(int64)) (*UploadResult, error) {
+// UploadFile загружает файл с метаданными. sum - SHA-256 файла (hex), если
+// он уже посчитан (например, при проверке на дубликаты); если sum пустой,
+// хеш считается во время загрузки.
+func (s *Service) UploadFile(ctx context.Context, filePath, sum string, progressCallback func(int64)) (*UploadResult, error) {
 	// Проверяем существование файла
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("файл не найден: %s", filePath)
@@ -61,46 +93,144 @@ func (s *Service) UploadFile(ctx context.Context, filePath string, progressCallb
 	}
 	defer file.Close()
 
-	// Создаем reader с отслеживанием прогресса
-	var reader io.Reader = file
-	if progressCallback != nil {
-		reader = &ProgressReader{
-			Reader:     file,
-			Size:       fileInfo.Size,
-			OnProgress: progressCallback,
-		}
+	// Создаем reader с отслеживанием прогресса. Если хеш еще не посчитан,
+	// reader заодно считает его по загружаемым данным, чтобы не читать файл
+	// еще раз.
+	reader := &ProgressReader{
+		Reader:     file,
+		Size:       fileInfo.Size,
+		OnProgress: progressCallback,
+	}
+	if sum == "" {
+		reader.Hash = sha256.New()
 	}
 
-	// Формируем ключ для S3
+	// Формируем ключ для S3, сохраняя исходное расширение файла
 	fileName := getFileNameWithoutExt(filePath)
-	s3Key := fileName + ".mp3"
+	s3Key := fileName + fileExt(filePath, fileInfo.Format)
 
 	// Загружаем файл с контекстом
-	url, err := s.s3Uploader.UploadFile(ctx, reader, s3Key)
+	url, err := s.s3Uploader.UploadFile(ctx, reader, s3Key, fileInfo.Format.ContentType())
 	if err != nil {
 		return nil, fmt.Errorf("ошибка загрузки в S3: %w", err)
 	}
 
-	return &UploadResult{
+	if reader.Hash != nil {
+		sum = hex.EncodeToString(reader.Hash.Sum(nil))
+	}
+
+	result := &UploadResult{
 		URL:      url,
+		SHA256:   sum,
 		Metadata: trackMetadata,
 		FileInfo: fileInfo,
+	}
+
+	// Обложку загружаем рядом с аудиофайлом
+	if artwork := trackMetadata.Artwork; artwork != nil {
+		key := ArtworkKey(s3Key, artwork.FileExt())
+		result.ArtworkURL, result.ArtworkErr = s.s3Uploader.UploadFile(ctx, bytes.NewReader(artwork.Data), key, artwork.MIMEType)
+	}
+
+	return result, nil
+}
+
+// UploadedResult составляет результат загрузки для файла, который уже был
+// загружен в S3 ранее (например, при повторном запуске прерванной
+// операции): метаданные снова извлекаются из локального файла, а URL берутся
+// из предыдущей загрузки
+func (s *Service) UploadedResult(filePath, url, artworkURL string) (*UploadResult, error) {
+	fileInfo, err := s.metadataExtractor.GetFileInfo(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения информации о файле: %w", err)
+	}
+	trackMetadata := s.metadataExtractor.ExtractFromFile(filePath)
+	if artworkURL == "" {
+		// Обложка в прошлый раз не загрузилась
+		trackMetadata.Artwork = nil
+	}
+	sum, err := HashFile(filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		URL:        url,
+		ArtworkURL: artworkURL,
+		SHA256:     sum,
+		Metadata:   trackMetadata,
+		FileInfo:   fileInfo,
 	}, nil
 }
 
+// LinkResult составляет результат загрузки для файла, который уже загружен
+// в S3 как трек track: теги берутся из локального файла, а URL, хеш и
+// сведения о загруженном файле - из трека, поэтому новая запись библиотеки
+// ссылается на тот же файл в S3
+func (s *Service) LinkResult(filePath string, track data.TrackMetadata) *UploadResult {
+	trackMetadata := s.metadataExtractor.ExtractFromFile(filePath)
+	trackMetadata.Artwork = nil
+	if track.ArtworkURL != "" {
+		trackMetadata.Artwork = &metadata.Artwork{MIMEType: track.Artwork}
+	}
+	if trackMetadata.SourceURL == "" {
+		trackMetadata.SourceURL = track.SourceURL
+	}
+	return &UploadResult{
+		URL:        track.URL,
+		ArtworkURL: track.ArtworkURL,
+		SHA256:     track.SHA256,
+		Metadata:   trackMetadata,
+		FileInfo: &metadata.FileInfo{
+			Size:     track.FileSize,
+			Duration: time.Duration(track.Length) * time.Second,
+			Format:   audio.Format(track.Format),
+		},
+	}
+}
+
 // UpdateApplicationData обновляет данные приложения с информацией о треке
 func (s *Service) UpdateApplicationData(result *UploadResult) error {
 	track := data.TrackMetadata{
-		Artist:   result.Metadata.Artist,
-		Title:    result.Metadata.Title,
-		Album:    result.Metadata.Album,
-		Length:   int(result.FileInfo.Duration.Seconds()),
-		FileSize: result.FileInfo.Size,
-		URL:      result.URL,
+		Artist:      result.Metadata.Artist,
+		Title:       result.Metadata.Title,
+		Album:       result.Metadata.Album,
+		Year:        result.Metadata.Year,
+		Genre:       result.Metadata.Genre,
+		Comment:     result.Metadata.Comment,
+		TrackNumber: result.Metadata.TrackNumber,
+		BPM:         result.Metadata.BPM,
+		Key:         result.Metadata.Key,
+		Length:      int(result.FileInfo.Duration.Seconds()),
+		FileSize:    result.FileInfo.Size,
+		SHA256:      result.SHA256,
+		URL:         result.URL,
+		SourceURL:   result.Metadata.SourceURL,
+		UploadDate:  result.Metadata.UploadDate,
+		Format:      string(result.FileInfo.Format),
+		Chapters:    LibraryChapters(result.Metadata.Chapters),
+	}
+	if result.Metadata.Artwork != nil && result.ArtworkErr == nil {
+		track.Artwork = result.Metadata.Artwork.MIMEType
+		track.ArtworkURL = result.ArtworkURL
 	}
 
-	s.appData.AddTrack(track)
-	return nil
+	return s.store.Put(&track)
+}
+
+// LibraryChapters переводит главы из метаданных файла в формат библиотеки
+func LibraryChapters(chapters []metadata.Chapter) []data.Chapter {
+	if len(chapters) == 0 {
+		return nil
+	}
+	result := make([]data.Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		result = append(result, data.Chapter{
+			Start:  int(chapter.Start / time.Second),
+			Artist: chapter.Artist,
+			Title:  chapter.Title,
+		})
+	}
+	return result
 }
 
 // ProgressReader структура для отслеживания прогресса чтения
@@ -108,24 +238,69 @@ type ProgressReader struct {
 	io.Reader
 	Size       int64
 	OnProgress func(int64)
+	Hash       hash.Hash // Если задан, в него записываются все прочитанные данные
 	bytesRead  int64
 }
 
 func (pr *ProgressReader) Read(p []byte) (n int, err error) {
 	n, err = pr.Reader.Read(p)
 	pr.bytesRead += int64(n)
+	if pr.Hash != nil {
+		pr.Hash.Write(p[:n])
+	}
 	if pr.OnProgress != nil {
 		pr.OnProgress(pr.bytesRead)
 	}
 	return n, err
 }
 
+// HashReader читает r до конца и возвращает SHA-256 прочитанных данных в
+// виде hex-строки. onProgress, если задан, получает число прочитанных байт.
+func HashReader(r io.Reader, onProgress func(int64)) (string, error) {
+	reader := &ProgressReader{Reader: r, OnProgress: onProgress, Hash: sha256.New()}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return "", fmt.Errorf("ошибка вычисления хеша: %w", err)
+	}
+	return hex.EncodeToString(reader.Hash.Sum(nil)), nil
+}
+
+// HashFile возвращает SHA-256 содержимого файла в виде hex-строки
+func HashFile(filePath string, onProgress func(int64)) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	defer file.Close()
+	return HashReader(file, onProgress)
+}
+
 // getFileNameWithoutExt возвращает имя файла без расширения
 func getFileNameWithoutExt(filePath string) string {
 	fileName := filepath.Base(filePath)
 	return strings.TrimSuffix(fileName, filepath.Ext(fileName))
 }
 
+// ArtworkKey возвращает ключ обложки в S3 для аудиофайла с ключом audioKey:
+// имя аудиофайла с суффиксом ".cover" и расширением изображения
+func ArtworkKey(audioKey, ext string) string {
+	return strings.TrimSuffix(audioKey, filepath.Ext(audioKey)) + ".cover." + ext
+}
+
+// fileExt возвращает расширение файла в нижнем регистре, если оно
+// соответствует формату, иначе стандартное расширение формата. Если
+// формат не определен, известное расширение сохраняется как есть.
+func fileExt(filePath string, format audio.Format) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	extFormat, known := audio.FromExt(ext)
+	switch {
+	case known && (format == "" || extFormat == format):
+		return ext
+	case format == "":
+		return audio.FormatMP3.Ext()
+	}
+	return format.Ext()
+}
+
 // FormatFileSize форматирует размер файла в читаемом виде
 func FormatFileSize(bytes int64) string {
 	const unit = 1024
@@ -2,35 +2,30 @@ package uploader
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hazadus/go-snatcher/internal/audio"
 	"github.com/hazadus/go-snatcher/internal/data"
 	"github.com/hazadus/go-snatcher/internal/metadata"
 )
 
-// S3UploaderInterface интерфейс для S3 uploader
-type S3UploaderInterface interface {
-	UploadFile(ctx context.Context, reader interface{}, key string) (string, error)
-}
-
-// MetadataExtractorInterface интерфейс для извлечения метаданных
-type MetadataExtractorInterface interface {
-	ExtractFromFile(filePath string) metadata.TrackMetadata
-	GetFileInfo(filePath string) (*metadata.FileInfo, error)
-}
-
 // MockS3Uploader мок для S3 uploader
 type MockS3Uploader struct {
-	uploadFunc func(ctx context.Context, reader interface{}, key string) (string, error)
+	uploadFunc func(ctx context.Context, reader io.Reader, key, contentType string) (string, error)
 }
 
-func (m *MockS3Uploader) UploadFile(ctx context.Context, reader interface{}, key string) (string, error) {
-	return m.uploadFunc(ctx, reader, key)
+func (m *MockS3Uploader) UploadFile(ctx context.Context, reader io.Reader, key, contentType string) (string, error) {
+	return m.uploadFunc(ctx, reader, key, contentType)
 }
 
 // MockMetadataExtractor мок для извлечения метаданных
@@ -47,87 +42,6 @@ func (m *MockMetadataExtractor) GetFileInfo(filePath string) (*metadata.FileInfo
 	return m.getFileInfoFunc(filePath)
 }
 
-// TestService тестовая версия Service для тестирования
-type TestService struct {
-	s3Uploader        S3UploaderInterface
-	metadataExtractor MetadataExtractorInterface
-	appData           *data.AppData
-}
-
-// NewTestService создает тестовый сервис
-func NewTestService(s3Uploader S3UploaderInterface, metadataExtractor MetadataExtractorInterface, appData *data.AppData) *TestService {
-	return &TestService{
-		s3Uploader:        s3Uploader,
-		metadataExtractor: metadataExtractor,
-		appData:           appData,
-	}
-}
-
-// UploadFile загружает файл с метаданными (тестовая версия)
-func (s *TestService) UploadFile(ctx context.Context, filePath string, progressCallback func(int64)) (*UploadResult, error) {
-	// Проверяем существование файла
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("файл не найден: %s", filePath)
-	}
-
-	// Получаем информацию о файле
-	fileInfo, err := s.metadataExtractor.GetFileInfo(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка получения информации о файле: %w", err)
-	}
-
-	// Извлекаем метаданные
-	trackMetadata := s.metadataExtractor.ExtractFromFile(filePath)
-
-	// Открываем файл для загрузки
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
-	}
-	defer file.Close()
-
-	// Создаем reader с отслеживанием прогресса
-	var reader interface{} = file
-	if progressCallback != nil {
-		reader = &ProgressReader{
-			Reader:     file,
-			Size:       fileInfo.Size,
-			OnProgress: progressCallback,
-		}
-	}
-
-	// Формируем ключ для S3
-	fileName := getFileNameWithoutExt(filePath)
-	s3Key := fileName + ".mp3"
-
-	// Загружаем файл с контекстом
-	url, err := s.s3Uploader.UploadFile(ctx, reader, s3Key)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка загрузки в S3: %w", err)
-	}
-
-	return &UploadResult{
-		URL:      url,
-		Metadata: trackMetadata,
-		FileInfo: fileInfo,
-	}, nil
-}
-
-// UpdateApplicationData обновляет данные приложения с информацией о треке (тестовая версия)
-func (s *TestService) UpdateApplicationData(result *UploadResult) error {
-	track := data.TrackMetadata{
-		Artist:   result.Metadata.Artist,
-		Title:    result.Metadata.Title,
-		Album:    result.Metadata.Album,
-		Length:   int(result.FileInfo.Duration.Seconds()),
-		FileSize: result.FileInfo.Size,
-		URL:      result.URL,
-	}
-
-	s.appData.AddTrack(track)
-	return nil
-}
-
 // TestSuccessfulUpload тестирует успешную загрузку файла
 func TestSuccessfulUpload(t *testing.T) {
 	// Создаем временный тестовый файл
@@ -143,11 +57,15 @@ func TestSuccessfulUpload(t *testing.T) {
 
 	// Создаем мок S3 uploader
 	mockS3Uploader := &MockS3Uploader{
-		uploadFunc: func(ctx context.Context, reader interface{}, key string) (string, error) {
+		uploadFunc: func(ctx context.Context, reader io.Reader, key, _ string) (string, error) {
 			// Проверяем, что ключ формируется правильно
 			if key != "test-song.mp3" {
 				t.Errorf("Ожидался ключ: test-song.mp3, получено: %s", key)
 			}
+			// Хеш считается по мере чтения файла загрузчиком
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				t.Errorf("Ошибка чтения файла: %v", err)
+			}
 			return "https://s3.amazonaws.com/test-bucket/test-song.mp3", nil
 		},
 	}
@@ -171,11 +89,11 @@ func TestSuccessfulUpload(t *testing.T) {
 
 	// Создаем тестовый сервис
 	appData := data.NewAppData()
-	service := NewTestService(mockS3Uploader, mockMetadataExtractor, appData)
+	service := NewService(mockS3Uploader, mockMetadataExtractor, appData)
 
 	// Тестируем загрузку
 	ctx := context.Background()
-	result, err := service.UploadFile(ctx, testFilePath, nil)
+	result, err := service.UploadFile(ctx, testFilePath, "", nil)
 
 	if err != nil {
 		t.Errorf("Неожиданная ошибка при загрузке: %v", err)
@@ -198,6 +116,55 @@ func TestSuccessfulUpload(t *testing.T) {
 	if result.FileInfo.Size != 1024 {
 		t.Errorf("Ожидался Size: 1024, получено: %d", result.FileInfo.Size)
 	}
+
+	sum := sha256.Sum256([]byte(testContent))
+	if result.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Неверный хеш загруженного файла: %s", result.SHA256)
+	}
+	if fileSum, err := HashFile(testFilePath, nil); err != nil || fileSum != result.SHA256 {
+		t.Errorf("Хеш файла не совпадает с хешем загрузки: %s, %v", fileSum, err)
+	}
+}
+
+// TestUploadWithKnownHash проверяет, что уже посчитанный хеш файла
+// используется как есть и файл при загрузке повторно не хешируется
+func TestUploadWithKnownHash(t *testing.T) {
+	testFilePath := filepath.Join(t.TempDir(), "test-song.mp3")
+	testContent := "test audio content"
+	if err := os.WriteFile(testFilePath, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Ошибка создания тестового файла: %v", err)
+	}
+	sum := sha256.Sum256([]byte(testContent))
+	knownSum := hex.EncodeToString(sum[:])
+
+	mockS3Uploader := &MockS3Uploader{
+		uploadFunc: func(ctx context.Context, reader io.Reader, key, _ string) (string, error) {
+			if progressReader, ok := reader.(*ProgressReader); !ok || progressReader.Hash != nil {
+				t.Error("Файл с известным хешем не должен хешироваться при загрузке")
+			}
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				t.Errorf("Ошибка чтения файла: %v", err)
+			}
+			return "https://s3.amazonaws.com/test-bucket/test-song.mp3", nil
+		},
+	}
+	mockMetadataExtractor := &MockMetadataExtractor{
+		extractFunc: func(_ string) metadata.TrackMetadata {
+			return metadata.TrackMetadata{}
+		},
+		getFileInfoFunc: func(_ string) (*metadata.FileInfo, error) {
+			return &metadata.FileInfo{Size: int64(len(testContent))}, nil
+		},
+	}
+
+	service := NewService(mockS3Uploader, mockMetadataExtractor, data.NewAppData())
+	result, err := service.UploadFile(context.Background(), testFilePath, knownSum, nil)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при загрузке: %v", err)
+	}
+	if result.SHA256 != knownSum {
+		t.Errorf("Ожидался хеш %s, получено: %s", knownSum, result.SHA256)
+	}
 }
 
 // TestUploadErrorHandling тестирует обработку ошибок при загрузке
@@ -216,7 +183,7 @@ func TestUploadErrorHandling(t *testing.T) {
 	// Тест 1: Ошибка S3 загрузки
 	t.Run("S3UploadError", func(t *testing.T) {
 		mockS3Uploader := &MockS3Uploader{
-			uploadFunc: func(ctx context.Context, reader interface{}, key string) (string, error) {
+			uploadFunc: func(ctx context.Context, reader io.Reader, key, _ string) (string, error) {
 				return "", fmt.Errorf("S3 upload failed")
 			},
 		}
@@ -231,10 +198,10 @@ func TestUploadErrorHandling(t *testing.T) {
 		}
 
 		appData := data.NewAppData()
-		service := NewTestService(mockS3Uploader, mockMetadataExtractor, appData)
+		service := NewService(mockS3Uploader, mockMetadataExtractor, appData)
 
 		ctx := context.Background()
-		_, err := service.UploadFile(ctx, testFilePath, nil)
+		_, err := service.UploadFile(ctx, testFilePath, "", nil)
 
 		if err == nil {
 			t.Error("Ожидалась ошибка при загрузке в S3")
@@ -248,7 +215,7 @@ func TestUploadErrorHandling(t *testing.T) {
 	// Тест 2: Ошибка получения информации о файле
 	t.Run("FileInfoError", func(t *testing.T) {
 		mockS3Uploader := &MockS3Uploader{
-			uploadFunc: func(ctx context.Context, reader interface{}, key string) (string, error) {
+			uploadFunc: func(ctx context.Context, reader io.Reader, key, _ string) (string, error) {
 				return "https://s3.amazonaws.com/test-bucket/test-song.mp3", nil
 			},
 		}
@@ -263,10 +230,10 @@ func TestUploadErrorHandling(t *testing.T) {
 		}
 
 		appData := data.NewAppData()
-		service := NewTestService(mockS3Uploader, mockMetadataExtractor, appData)
+		service := NewService(mockS3Uploader, mockMetadataExtractor, appData)
 
 		ctx := context.Background()
-		_, err := service.UploadFile(ctx, testFilePath, nil)
+		_, err := service.UploadFile(ctx, testFilePath, "", nil)
 
 		if err == nil {
 			t.Error("Ожидалась ошибка при получении информации о файле")
@@ -280,7 +247,7 @@ func TestUploadErrorHandling(t *testing.T) {
 	// Тест 3: Файл не существует
 	t.Run("FileNotExists", func(t *testing.T) {
 		mockS3Uploader := &MockS3Uploader{
-			uploadFunc: func(ctx context.Context, reader interface{}, key string) (string, error) {
+			uploadFunc: func(ctx context.Context, reader io.Reader, key, _ string) (string, error) {
 				return "https://s3.amazonaws.com/test-bucket/test-song.mp3", nil
 			},
 		}
@@ -295,10 +262,10 @@ func TestUploadErrorHandling(t *testing.T) {
 		}
 
 		appData := data.NewAppData()
-		service := NewTestService(mockS3Uploader, mockMetadataExtractor, appData)
+		service := NewService(mockS3Uploader, mockMetadataExtractor, appData)
 
 		ctx := context.Background()
-		_, err := service.UploadFile(ctx, "/non/existent/file.mp3", nil)
+		_, err := service.UploadFile(ctx, "/non/existent/file.mp3", "", nil)
 
 		if err == nil {
 			t.Error("Ожидалась ошибка при несуществующем файле")
@@ -326,39 +293,66 @@ func TestS3ObjectKeyFormation(t *testing.T) {
 	testCases := []struct {
 		name        string
 		fileName    string
+		format      audio.Format
 		expectedKey string
 		description string
 	}{
 		{
 			name:        "SimpleFileName",
 			fileName:    "song.mp3",
+			format:      audio.FormatMP3,
 			expectedKey: "song.mp3",
 			description: "Простое имя файла без пути",
 		},
 		{
 			name:        "FileNameWithPath",
 			fileName:    "music/artist/song.mp3",
+			format:      audio.FormatMP3,
 			expectedKey: "song.mp3",
 			description: "Имя файла с путем",
 		},
 		{
 			name:        "FileNameWithSpecialChars",
 			fileName:    "song (remix) [2024].mp3",
+			format:      audio.FormatMP3,
 			expectedKey: "song (remix) [2024].mp3",
 			description: "Имя файла со специальными символами",
 		},
 		{
 			name:        "FileNameWithSpaces",
 			fileName:    "my song title.mp3",
+			format:      audio.FormatMP3,
 			expectedKey: "my song title.mp3",
 			description: "Имя файла с пробелами",
 		},
 		{
 			name:        "FileNameWithUnicode",
 			fileName:    "песня.mp3",
+			format:      audio.FormatMP3,
 			expectedKey: "песня.mp3",
 			description: "Имя файла с Unicode символами",
 		},
+		{
+			name:        "FLACFile",
+			fileName:    "mix.FLAC",
+			format:      audio.FormatFLAC,
+			expectedKey: "mix.flac",
+			description: "Расширение FLAC сохраняется в нижнем регистре",
+		},
+		{
+			name:        "OggFile",
+			fileName:    "set.oga",
+			format:      audio.FormatOgg,
+			expectedKey: "set.oga",
+			description: "Исходное расширение Ogg сохраняется",
+		},
+		{
+			name:        "MislabeledFile",
+			fileName:    "live.mp3",
+			format:      audio.FormatWAV,
+			expectedKey: "live.wav",
+			description: "Расширение, не совпадающее с форматом, заменяется",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -382,7 +376,7 @@ func TestS3ObjectKeyFormation(t *testing.T) {
 
 			var receivedKey string
 			mockS3Uploader := &MockS3Uploader{
-				uploadFunc: func(ctx context.Context, reader interface{}, key string) (string, error) {
+				uploadFunc: func(ctx context.Context, reader io.Reader, key, _ string) (string, error) {
 					receivedKey = key
 					return "https://s3.amazonaws.com/test-bucket/" + key, nil
 				},
@@ -393,15 +387,15 @@ func TestS3ObjectKeyFormation(t *testing.T) {
 					return metadata.TrackMetadata{}
 				},
 				getFileInfoFunc: func(_ string) (*metadata.FileInfo, error) {
-					return &metadata.FileInfo{}, nil
+					return &metadata.FileInfo{Format: tc.format}, nil
 				},
 			}
 
 			appData := data.NewAppData()
-			service := NewTestService(mockS3Uploader, mockMetadataExtractor, appData)
+			service := NewService(mockS3Uploader, mockMetadataExtractor, appData)
 
 			ctx := context.Background()
-			_, err = service.UploadFile(ctx, testFilePath, nil)
+			_, err = service.UploadFile(ctx, testFilePath, "", nil)
 
 			if err != nil {
 				t.Errorf("Ошибка при загрузке: %v", err)
@@ -418,11 +412,16 @@ func TestS3ObjectKeyFormation(t *testing.T) {
 func TestUpdateApplicationData(t *testing.T) {
 	// Создаем тестовый результат загрузки
 	result := &UploadResult{
-		URL: "https://s3.amazonaws.com/test-bucket/test-song.mp3",
+		URL:        "https://s3.amazonaws.com/test-bucket/test-song.mp3",
+		ArtworkURL: "https://s3.amazonaws.com/test-bucket/test-song.cover.jpg",
 		Metadata: metadata.TrackMetadata{
-			Artist: "Test Artist",
-			Title:  "Test Song",
-			Album:  "Test Album",
+			Artist:  "Test Artist",
+			Title:   "Test Song",
+			Album:   "Test Album",
+			Year:    2021,
+			BPM:     124,
+			Key:     "8A",
+			Artwork: &metadata.Artwork{MIMEType: "image/jpeg", Ext: "jpg", Data: []byte{0xff, 0xd8}},
 		},
 		FileInfo: &metadata.FileInfo{
 			Size:     1024,
@@ -432,7 +431,7 @@ func TestUpdateApplicationData(t *testing.T) {
 
 	// Создаем тестовый сервис
 	appData := data.NewAppData()
-	service := NewTestService(&MockS3Uploader{}, &MockMetadataExtractor{}, appData)
+	service := NewService(&MockS3Uploader{}, &MockMetadataExtractor{}, appData)
 
 	// Тестируем обновление данных
 	err := service.UpdateApplicationData(result)
@@ -440,8 +439,108 @@ func TestUpdateApplicationData(t *testing.T) {
 		t.Errorf("Неожиданная ошибка при обновлении данных: %v", err)
 	}
 
-	// Примечание: метод GetTracks может не существовать, поэтому пропускаем эту проверку
-	// Проверяем только, что обновление данных не вызывает ошибку
+	if len(appData.Tracks) != 1 {
+		t.Fatalf("Ожидался 1 трек, получено %d", len(appData.Tracks))
+	}
+	added := appData.Tracks[0]
+	if added.Year != 2021 || added.BPM != 124 || added.Key != "8A" || added.Artwork != "image/jpeg" {
+		t.Errorf("Теги не перенесены в библиотеку: %+v", added)
+	}
+	if added.ArtworkURL != result.ArtworkURL {
+		t.Errorf("Ожидался URL обложки %s, получено %s", result.ArtworkURL, added.ArtworkURL)
+	}
+}
+
+// TestUpdateApplicationDataCarriesSource проверяет, что сведения об источнике
+// и главы переносятся в библиотеку
+func TestUpdateApplicationDataCarriesSource(t *testing.T) {
+	appData := data.NewAppData()
+	service := NewService(nil, metadata.NewExtractor(), appData)
+
+	err := service.UpdateApplicationData(&UploadResult{
+		URL: "https://s3.amazonaws.com/test-bucket/mix.mp3",
+		Metadata: metadata.TrackMetadata{
+			Artist:     "Channel",
+			Title:      "Mix",
+			SourceURL:  "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			UploadDate: "2019-05-17",
+			Chapters:   []metadata.Chapter{{Start: 90 * time.Second, Artist: "A", Title: "One"}},
+		},
+		FileInfo: &metadata.FileInfo{Size: 1024, Duration: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка обновления данных: %v", err)
+	}
+
+	added := appData.Tracks[0]
+	if added.SourceURL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || added.UploadDate != "2019-05-17" {
+		t.Errorf("Сведения об источнике не перенесены: %+v", added)
+	}
+	if len(added.Chapters) != 1 || added.Chapters[0] != (data.Chapter{Start: 90, Artist: "A", Title: "One"}) {
+		t.Errorf("Главы не перенесены: %+v", added.Chapters)
+	}
+}
+
+// TestArtworkUpload тестирует загрузку обложки рядом с аудиофайлом
+func TestArtworkUpload(t *testing.T) {
+	testFilePath := filepath.Join(t.TempDir(), "song.flac")
+	if err := os.WriteFile(testFilePath, []byte("test audio content"), 0644); err != nil {
+		t.Fatalf("Ошибка создания тестового файла: %v", err)
+	}
+
+	var keys, contentTypes []string
+	mockS3Uploader := &MockS3Uploader{
+		uploadFunc: func(_ context.Context, _ io.Reader, key, contentType string) (string, error) {
+			keys = append(keys, key)
+			contentTypes = append(contentTypes, contentType)
+			if strings.Contains(key, ".cover.") {
+				return "", errors.New("access denied")
+			}
+			return "https://s3.amazonaws.com/test-bucket/" + key, nil
+		},
+	}
+	mockMetadataExtractor := &MockMetadataExtractor{
+		extractFunc: func(_ string) metadata.TrackMetadata {
+			return metadata.TrackMetadata{
+				Title:   "Song",
+				Artwork: &metadata.Artwork{MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+			}
+		},
+		getFileInfoFunc: func(_ string) (*metadata.FileInfo, error) {
+			return &metadata.FileInfo{Size: 18, Format: audio.FormatFLAC}, nil
+		},
+	}
+
+	appData := data.NewAppData()
+	service := NewService(mockS3Uploader, mockMetadataExtractor, appData)
+	result, err := service.UploadFile(context.Background(), testFilePath, "", nil)
+	if err != nil {
+		t.Fatalf("Ошибка обложки не должна прерывать загрузку: %v", err)
+	}
+
+	if len(keys) != 2 || keys[1] != "song.cover.png" {
+		t.Errorf("Ожидались ключи [song.flac song.cover.png], получено %v", keys)
+	}
+	if !slices.Equal(contentTypes, []string{"audio/flac", "image/png"}) {
+		t.Errorf("Ожидались типы [audio/flac image/png], получено %v", contentTypes)
+	}
+	if result.ArtworkErr == nil || result.ArtworkURL != "" {
+		t.Errorf("Ожидалась ошибка загрузки обложки, получено %q, %v", result.ArtworkURL, result.ArtworkErr)
+	}
+
+	// Трек без загруженной обложки не ссылается на нее
+	if err := service.UpdateApplicationData(result); err != nil {
+		t.Fatalf("Ошибка обновления данных: %v", err)
+	}
+	if added := appData.Tracks[0]; added.Artwork != "" || added.ArtworkURL != "" {
+		t.Errorf("Обложка не должна сохраняться: %+v", added)
+	}
+}
+
+func TestArtworkKey(t *testing.T) {
+	if key := ArtworkKey("Mix 01.mp3", "jpg"); key != "Mix 01.cover.jpg" {
+		t.Errorf("Неверный ключ обложки: %s", key)
+	}
 }
 
 // TestProgressReader тестирует отслеживание прогресса чтения